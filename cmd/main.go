@@ -1,13 +1,107 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"vulnWeb/pkg/config"
 	"vulnWeb/pkg/endpoints"
+	"vulnWeb/pkg/sandbox"
+)
+
+// Таймауты сервера: медленный клиент не держит соединение бесконечно
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 30 * time.Second
+	writeTimeout      = 60 * time.Second
+	idleTimeout       = 120 * time.Second
+	shutdownTimeout   = 10 * time.Second
 )
 
 func main() {
-	endpoints := endpoints.New("localhost:9999", http.NewServeMux())
-	endpoints.FillEndpoints()
-	log.Fatal(endpoints.ListenAndServe())
+	// Если бинарник запущен как процесс песочницы команд, управление сюда не вернется
+	sandbox.Init()
+
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	level, _ := cfg.Level()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	// log.Printf обработчиков тоже идет через slog (уровень info)
+	slog.SetDefault(logger)
+
+	endpoints := endpoints.New(endpoints.Options{
+		DataDir:      cfg.DataDir,
+		FlagSecret:   cfg.FlagSecret,
+		TrainerToken: cfg.TrainerToken,
+	}, http.NewServeMux())
+	if err := endpoints.FillEndpoints(); err != nil {
+		log.Fatal(err)
+	}
+	if err := endpoints.SetMode(cfg.Mode); err != nil {
+		log.Fatal(err)
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           logRequests(endpoints.Handler()),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		scheme := "http"
+		if cfg.TLSEnabled() {
+			scheme = "https"
+		}
+		slog.Info("server started", "url", scheme+"://"+cfg.Addr, "data_dir", cfg.DataDir, "mode", cfg.Mode)
+		if cfg.TLSEnabled() {
+			errc <- srv.ListenAndServeTLS(cfg.TLS.Cert, cfg.TLS.Key)
+		} else {
+			errc <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errc:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	// Новые соединения не принимаются, начатые запросы дорабатывают
+	slog.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("shutdown", "error", err)
+		os.Exit(1)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("server", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("server stopped")
+}
+
+// Запросы в лог на уровне debug
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		slog.Debug("request", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr, "duration", time.Since(start))
+	})
 }
@@ -0,0 +1,160 @@
+// Настройки сервера. Значения берутся по возрастанию приоритета: значения по умолчанию,
+// файл конфигурации (JSON), переменные окружения VULNWEB_*, флаги командной строки
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Переменная окружения с путем к файлу конфигурации (вместо флага -config)
+const configEnv = "VULNWEB_CONFIG"
+
+// Короткий токен преподавателя легко подобрать
+const minTrainerToken = 8
+
+type Config struct {
+	Addr     string `json:"addr"`      // Адрес, на котором слушает сервер
+	DataDir  string `json:"data_dir"`  // Каталог для прогресса, команд, секрета флагов и event.json
+	Mode     string `json:"mode"`      // Начальный режим эндпоинтов: vulnerable или secure
+	LogLevel string `json:"log_level"` // debug, info, warn или error
+	TLS      TLS    `json:"tls"`
+	// Секрет для флагов; пустой - секрет из каталога данных (генерируется при первом запуске)
+	FlagSecret string `json:"flag_secret"`
+	// Токен преподавателя для смены режима; пустой - генерируется при старте
+	TrainerToken string `json:"trainer_token"`
+}
+
+// Сертификат и ключ в PEM; если оба пусты, сервер работает по HTTP
+type TLS struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+}
+
+func Default() Config {
+	return Config{
+		Addr:     "localhost:9999",
+		DataDir:  "data",
+		Mode:     "vulnerable",
+		LogLevel: "info",
+	}
+}
+
+// Параметр конфигурации: имя флага, переменная окружения и поле Config
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	field func(*Config) *string
+}
+
+var settings = []setting{
+	{"addr", "VULNWEB_ADDR", "адрес для входящих соединений", func(c *Config) *string { return &c.Addr }},
+	{"data-dir", "VULNWEB_DATA_DIR", "каталог с данными учащихся", func(c *Config) *string { return &c.DataDir }},
+	{"mode", "VULNWEB_MODE", "начальный режим эндпоинтов: vulnerable или secure", func(c *Config) *string { return &c.Mode }},
+	{"log-level", "VULNWEB_LOG_LEVEL", "уровень логирования: debug, info, warn или error", func(c *Config) *string { return &c.LogLevel }},
+	{"tls-cert", "VULNWEB_TLS_CERT", "сертификат TLS (PEM)", func(c *Config) *string { return &c.TLS.Cert }},
+	{"tls-key", "VULNWEB_TLS_KEY", "закрытый ключ TLS (PEM)", func(c *Config) *string { return &c.TLS.Key }},
+	{"flag-secret", "VULNWEB_FLAG_SECRET", "секрет для флагов", func(c *Config) *string { return &c.FlagSecret }},
+	{"trainer-token", "VULNWEB_TRAINER_TOKEN", "токен преподавателя для смены режима", func(c *Config) *string { return &c.TrainerToken }},
+}
+
+// Собрать конфигурацию из файла, окружения и аргументов командной строки
+func Load(args []string) (Config, error) {
+	fs := flag.NewFlagSet("vulnweb", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv(configEnv), "файл конфигурации в формате JSON (переменная "+configEnv+")")
+	values := make([]*string, len(settings))
+	for i, s := range settings {
+		values[i] = fs.String(s.flag, "", s.usage+" (переменная "+s.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	cfg := Default()
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return Config{}, err
+		}
+	}
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok {
+			*s.field(&cfg) = value
+		}
+	}
+	// Флаг важнее окружения, только если он задан явно
+	fs.Visit(func(f *flag.Flag) {
+		for i, s := range settings {
+			if s.flag == f.Name {
+				*s.field(&cfg) = *values[i]
+			}
+		}
+	})
+
+	if err := cfg.validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c Config) validate() error {
+	var errs []error
+	if c.Addr == "" {
+		errs = append(errs, fmt.Errorf("addr is required"))
+	}
+	if c.DataDir == "" {
+		errs = append(errs, fmt.Errorf("data_dir is required"))
+	}
+	if c.Mode != "vulnerable" && c.Mode != "secure" {
+		errs = append(errs, fmt.Errorf("mode must be vulnerable or secure, got %q", c.Mode))
+	}
+	if _, err := c.Level(); err != nil {
+		errs = append(errs, err)
+	}
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		errs = append(errs, fmt.Errorf("tls: cert and key must be set together"))
+	}
+	if c.FlagSecret != "" && strings.TrimSpace(c.FlagSecret) != c.FlagSecret {
+		errs = append(errs, fmt.Errorf("flag_secret must not start or end with whitespace"))
+	}
+	if c.TrainerToken != "" && len(c.TrainerToken) < minTrainerToken {
+		errs = append(errs, fmt.Errorf("trainer_token must be at least %d characters", minTrainerToken))
+	}
+	return errors.Join(errs...)
+}
+
+// Включен ли TLS
+func (c Config) TLSEnabled() bool {
+	return c.TLS.Cert != ""
+}
+
+// Уровень логирования для slog
+func (c Config) Level() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return 0, fmt.Errorf("log_level must be debug, info, warn or error, got %q", c.LogLevel)
+	}
+	return level, nil
+}
@@ -0,0 +1,267 @@
+package endpoints
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A01:2025 - Broken Access Control
+// Исправленные версии эндпоинтов (режим secure)
+
+// Исправление 1: профиль отдается только его владельцу
+func apiV1UsersIDSecure(w http.ResponseWriter, r *http.Request) {
+	userID := strings.TrimPrefix(r.URL.Path, "/api/v1/users/")
+
+	// ИСПРАВЛЕНИЕ: ID из URL сверяется с пользователем сессии
+	if userID != labUser.ID {
+		sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": "Access denied",
+		})
+		return
+	}
+	user, ok := learnerDomain(w, r).User(labUser.ID)
+	if !ok {
+		sendJSONStatus(w, http.StatusNotFound, map[string]interface{}{
+			"status":  "error",
+			"message": "User not found",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"data":   user.profile(),
+	})
+}
+
+// Исправление 2: права администратора берутся из сессии, а не из параметра
+func apiV1AdminUsersSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: параметр is_admin игнорируется, роль берется из серверной сессии
+	if sessionRole(r) != "admin" {
+		sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": "Access denied",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"data":   []map[string]string{},
+	})
+}
+
+// Исправление 3: редирект только на страницы этого же сайта
+func apiV1AuthLoginRedirectSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		// ИСПРАВЛЕНИЕ: внешние и protocol-relative адреса заменяются на страницу по умолчанию
+		redirect := r.FormValue("redirect")
+		if !localRedirect(redirect) {
+			redirect = "/dashboard"
+		}
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	apiV1AuthLoginRedirect(w, r)
+}
+
+// Локальный путь: начинается с одного слеша, без схемы, хоста и обратных слешей
+func localRedirect(target string) bool {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.Contains(target, `\`) {
+		return false
+	}
+	u, err := url.Parse(target)
+	return err == nil && u.Scheme == "" && u.Host == ""
+}
+
+// Исправление 4: подпись JWT проверяется
+func apiV1AuthVerifyJWTSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: токен принимается только с верной подписью и не истекший
+	token, err := parseJWT(requestJWT(r))
+	if err == nil {
+		err = verifyJWT(token)
+	}
+	if err != nil {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid token",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"user":   fmt.Sprint(token.Claims["name"]),
+		"role":   fmt.Sprint(token.Claims["role"]),
+	})
+}
+
+// Алгоритм определяется ключом, а не заголовком токена: hs1 - только HS256 с сильным
+// секретом, rs1 - только RS256. Неизвестный kid, none и токен без exp отклоняются
+func verifyJWT(token *jwtToken) error {
+	switch {
+	case token.Header.Kid == jwtHMACKid && token.Header.Alg == "HS256":
+		if err := token.VerifyHMAC(serverKey("jwt")); err != nil {
+			return err
+		}
+	case token.Header.Kid == jwtRSAKid && token.Header.Alg == "RS256":
+		if err := token.VerifyRSA(&jwtRSAKey().PublicKey); err != nil {
+			return err
+		}
+	default:
+		return errors.New("unexpected key or algorithm")
+	}
+	return token.CheckExpiry()
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Исправление 5: только файлы из каталога пользователя
+func apiV1FilesSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: имя - локальный путь внутри каталога, абсолютные пути и ".." отклоняются
+	name, ok := resolveSafe(webFilesDir, r.URL.Query().Get("file"))
+	if !ok {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	content, err := readServerFile(w, r, name)
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	w.Write(content)
+}
+
+// Исправление 6: заголовки X-Admin и X-User-Role не дают прав
+func apiV1AdminConfigSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: роль берется из серверной сессии
+	if sessionRole(r) != "admin" {
+		sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": "Admin access required",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"config": map[string]string{},
+	})
+}
+
+// Исправление 7: CORS только для своего домена
+func apiV1UserProfileSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: Origin не отражается; чужой домен не получает разрешения CORS
+	w.Header().Add("Vary", "Origin")
+	if origin := r.Header.Get("Origin"); origin != "" && sameOrigin(r, origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	d := learnerDomain(w, r)
+	user, _ := d.User(labUser.ID)
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"user": map[string]string{
+			"id":    user.ID,
+			"email": user.Email,
+		},
+		"orders": orderList(d.Orders(labUser.ID)),
+	})
+}
+
+// Переводы каждого учащегося выполняются по очереди
+var transferLocks = newKeyedMutex()
+
+// Исправление 8: переводы одного пользователя выполняются последовательно
+func apiV1PaymentTransferRaceSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1PaymentTransferRace(w, r)
+		return
+	}
+	amount, err := strconv.Atoi(r.FormValue("amount"))
+	if err != nil || amount <= 0 {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Amount must be a positive number",
+		})
+		return
+	}
+	learner := learnerID(w, r)
+
+	// ИСПРАВЛЕНИЕ: переводы учащегося выполняются по очереди, а проверка баланса
+	// и списание - одна атомарная операция
+	unlock := transferLocks.Lock(learner)
+	time.Sleep(transferProcessing) // Имитация обработки перевода
+	balance, err := learnerDomain(w, r).TransferFunds(labUser.ID, r.FormValue("to_user"), amount)
+	unlock()
+	switch {
+	case errors.Is(err, errInsufficientFunds):
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Insufficient funds",
+			"balance": balance,
+		})
+		return
+	case err != nil:
+		sendJSONStatus(w, http.StatusNotFound, map[string]interface{}{
+			"status":  "error",
+			"message": "Account not found",
+		})
+		return
+	}
+
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Transferred %d to user %s", amount, r.FormValue("to_user")),
+		"balance": balance,
+	})
+}
+
+// Исправление 9: админ панель проверяет роль
+func apiV1AdminDashboardSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: знания URL недостаточно, нужна роль администратора
+	if sessionRole(r) != "admin" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(renderPage("Access Denied", `
+		<div class="card">
+			<h2>403 Forbidden</h2>
+			<p>Administrator role required</p>
+		</div>
+	`)))
+		return
+	}
+	d := learnerDomain(w, r)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(renderPage("Admin Dashboard", fmt.Sprintf(`
+		<div class="card">
+			<h2>Admin Dashboard</h2>
+			<p>Total Users: %d</p>
+			<p>Active Sessions: %d</p>
+			<p>Orders: %d</p>
+		</div>
+	`, len(d.Users()), d.SessionCount(), len(d.Orders(""))))))
+}
+
+// Исправление 10: отладочных параметров обхода авторизации нет
+func apiV1UserSettingsSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: bypass_auth и debug игнорируются, настройки отдаются владельцу сессии
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"settings": map[string]string{
+			"email":       labUser.Email,
+			"2fa_enabled": "true",
+			"api_key":     "sk_live_****2345",
+		},
+	})
+}
@@ -0,0 +1,119 @@
+package endpoints
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// A02:2025 - Security Misconfiguration
+// Исправленные версии эндпоинтов (режим secure)
+
+// Исправление 1: служебные файлы не отдаются веб-сервером
+func apiV1ConfigEnvSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: .env лежит вне каталога, который обслуживает веб-сервер
+	http.NotFound(w, r)
+}
+
+// Исправление 2: ошибки без отладочной информации
+func apiV1UsersSearchDebugSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: отладочный режим выключен, клиент получает только короткое сообщение
+	if r.URL.Query().Get("q") == "" {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Query parameter q is required",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"results": []string{"user1", "user2"},
+	})
+}
+
+// Исправление 3: метрики доступны только сборщику с токеном
+func apiV1MetricsSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: Bearer токен сборщика метрик, в метриках нет секретов
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	expected := hex.EncodeToString(serverKey("metrics"))
+	if !hmac.Equal([]byte(token), []byte(expected)) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	w.Write([]byte(`# HELP http_requests_total Total HTTP requests
+# TYPE http_requests_total counter
+http_requests_total{method="GET",status="200"} 123456
+http_requests_total{method="POST",status="200"} 45678`))
+}
+
+// Исправление 4: каталог .git не публикуется
+func apiV1GitConfigSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: при деплое копируется только собранное приложение, без .git
+	http.NotFound(w, r)
+}
+
+// Исправление 5: CORS только для своего домена
+func apiV1ApiDataSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: вместо * разрешаем только собственный Origin
+	w.Header().Add("Vary", "Origin")
+	if origin := r.Header.Get("Origin"); origin != "" && sameOrigin(r, origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", "GET")
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"data": map[string]string{
+			"user_id": "123",
+		},
+	})
+}
+
+// Исправление 6: версии технологий не раскрываются
+func apiV1HealthSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: нет заголовков Server, X-Powered-By и отладочных токенов
+	sendJSON(w, map[string]interface{}{
+		"status": "healthy",
+	})
+}
+
+// Исправление 7: cookie сессии с флагами безопасности
+func apiV1AuthSessionSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: случайный ID в cookie с HttpOnly, Secure (по HTTPS) и SameSite
+	if _, err := secureSessions.Create(w, r); err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{
+			"status":  "error",
+			"message": "Internal server error",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+	})
+}
+
+// Исправление 8: резервные копии не доступны через веб
+func apiV1BackupSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: бэкапы хранятся вне веб-каталога, API их не отдает
+	http.NotFound(w, r)
+}
+
+// Исправление 9: логи только для администраторов
+func apiV1LogsSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: доступ к логам проверяется по роли из сессии
+	if sessionRole(r) != "admin" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	w.Write([]byte("2024-01-15 10:30:15 [INFO] User login: a***@company.com\n"))
+}
+
+// Исправление 10: конфигурация БД не отдается через API
+func apiV1ConfigDatabaseSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: учетные данные БД хранятся в менеджере секретов, эндпоинт закрыт
+	sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+		"status":  "error",
+		"message": "Forbidden",
+	})
+}
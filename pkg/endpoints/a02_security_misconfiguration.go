@@ -61,15 +61,15 @@ func a02SecurityMisconfiguration(w http.ResponseWriter, r *http.Request) {
 		<button>Показать конфигурацию БД</button>
 	</form>
 	`
-	
+
 	vuln := r.URL.Query().Get("vuln")
-	
+
 	// Уязвимость 1: Открытый .env файл
 	if r.URL.Path == "/.env" {
 		w.Write([]byte("DB_PASSWORD=secret123\nAPI_KEY=key12345\nADMIN_TOKEN=admin_token"))
 		return
 	}
-	
+
 	// Уязвимость 2: Отладочная информация
 	if vuln == "2" {
 		id := r.URL.Query().Get("id")
@@ -77,13 +77,13 @@ func a02SecurityMisconfiguration(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Ошибка получения данных для ID=%s\nStack trace:\nmain.go:42\nendpoints.go:15\nSQL: SELECT * FROM users WHERE id=%s", id, id)))
 		return
 	}
-	
+
 	// Уязвимость 3: Открытый Git репозиторий
 	if r.URL.Path == "/.git/config" {
 		w.Write([]byte("[core]\n\trepositoryformatversion = 0\n\tfilemode = true\n\tbare = false\n[remote \"origin\"]\n\turl = https://github.com/company/secret-repo.git"))
 		return
 	}
-	
+
 	// Уязвимость 4: Слабая конфигурация CORS
 	if vuln == "4" {
 		// Разрешаем всем доменам
@@ -92,13 +92,13 @@ func a02SecurityMisconfiguration(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("CORS разрешен для всех доменов"))
 		return
 	}
-	
+
 	// Уязвимость 5: Открытые директории
 	if r.URL.Path == "/backup/" {
 		w.Write([]byte("backup_2024.db\nbackup_2023.db\npasswords.txt\nconfig.old"))
 		return
 	}
-	
+
 	// Уязвимость 6: Версия в заголовках
 	if vuln == "6" {
 		w.Header().Set("Server", "Go/1.24.9")
@@ -107,7 +107,7 @@ func a02SecurityMisconfiguration(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Заголовки отправлены"))
 		return
 	}
-	
+
 	// Уязвимость 7: Небезопасные настройки сессий
 	if vuln == "7" {
 		// Сессия без HttpOnly и Secure флагов
@@ -115,27 +115,27 @@ func a02SecurityMisconfiguration(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Сессия создана (небезопасно)"))
 		return
 	}
-	
+
 	// Уязвимость 8: Неправильная настройка HTTPS
 	if vuln == "8" {
 		// Нет HSTS заголовка
 		w.Write([]byte("HTTPS не настроен правильно. Нет HSTS заголовка."))
 		return
 	}
-	
+
 	// Уязвимость 9: Открытый доступ к метрикам
 	if r.URL.Path == "/metrics" {
 		w.Write([]byte("http_requests_total 12345\nhttp_errors_total 12\nactive_connections 5\nmemory_usage_bytes 1048576"))
 		return
 	}
-	
+
 	// Уязвимость 10: Небезопасная конфигурация базы данных
 	if vuln == "10" {
 		// Показываем конфигурацию БД
 		w.Write([]byte("DB_HOST=localhost\nDB_PORT=5432\nDB_USER=admin\nDB_NAME=production\nDB_SSL_MODE=disable"))
 		return
 	}
-	
+
 	// Показываем файлы системы (опасно!)
 	if r.URL.Query().Get("file") != "" {
 		file := r.URL.Query().Get("file")
@@ -143,8 +143,7 @@ func a02SecurityMisconfiguration(w http.ResponseWriter, r *http.Request) {
 		w.Write(content)
 		return
 	}
-	
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
 }
-
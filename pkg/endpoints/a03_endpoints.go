@@ -1,9 +1,13 @@
 package endpoints
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"os/exec"
+	"strings"
 )
 
 // A03:2025 - Software Supply Chain Failures
@@ -13,16 +17,17 @@ import (
 func apiV1PackagesInstall(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		packageName := r.FormValue("package")
-		
+
 		// УЯЗВИМОСТЬ: Устанавливаем пакет без проверки подписи и целостности
 		sendJSON(w, map[string]interface{}{
 			"status":  "success",
 			"message": fmt.Sprintf("Package %s installed without signature verification", packageName),
 			"version": "latest",
+			"flag":    labFlag(w, r, "a03_1"),
 		})
 		return
 	}
-	
+
 	html := renderPage("Install Package", `
 		<div class="card">
 			<h2>Install NPM Package</h2>
@@ -42,13 +47,17 @@ func apiV1PackagesInstall(w http.ResponseWriter, r *http.Request) {
 // Уязвимость 2: Загрузка зависимостей из небезопасных источников
 func apiV1DependenciesUpdate(w http.ResponseWriter, r *http.Request) {
 	url := r.URL.Query().Get("url")
-	
+
 	// УЯЗВИМОСТЬ: Загружаем зависимости с любого URL
 	if url != "" {
-		sendJSON(w, map[string]interface{}{
+		response := map[string]interface{}{
 			"status":  "success",
 			"message": fmt.Sprintf("Dependency loaded from %s without verification", url),
-		})
+		}
+		if !strings.Contains(url, "localhost") {
+			response["flag"] = labFlag(w, r, "a03_2")
+		}
+		sendJSON(w, response)
 	} else {
 		sendJSON(w, map[string]interface{}{
 			"status":  "error",
@@ -61,16 +70,21 @@ func apiV1DependenciesUpdate(w http.ResponseWriter, r *http.Request) {
 func apiV1Build(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		script := r.FormValue("script")
-		
+
 		// УЯЗВИМОСТЬ: Выполняем произвольную команду
-		out, _ := exec.Command("sh", "-c", script).Output()
+		// Флаг лежит в окружении сборки: его можно получить только выполнив свою команду
+		res, err := runSandboxed(r, []string{"LAB_FLAG=" + labFlag(w, r, "a03_3")}, "sh", "-c", script)
+		if err != nil {
+			sandboxFailed(w, err)
+			return
+		}
 		sendJSON(w, map[string]interface{}{
-			"status":  "success",
-			"output":  string(out),
+			"status": "success",
+			"output": sandboxOutput(res),
 		})
 		return
 	}
-	
+
 	html := renderPage("Build Project", `
 		<div class="card">
 			<h2>Run Build Script</h2>
@@ -90,13 +104,29 @@ func apiV1Build(w http.ResponseWriter, r *http.Request) {
 // Уязвимость 4: Отсутствие проверки checksum при обновлении
 func apiV1Update(w http.ResponseWriter, r *http.Request) {
 	version := r.URL.Query().Get("version")
-	
+
+	archive, ok := mirrorArchive(version)
+	if !ok {
+		sendJSONStatus(w, http.StatusNotFound, map[string]interface{}{
+			"status":  "error",
+			"message": fmt.Sprintf("Version %q not found on the update mirror", version),
+		})
+		return
+	}
+
 	// УЯЗВИМОСТЬ: Обновление без проверки checksum
-	sendJSON(w, map[string]interface{}{
+	sum := sha256.Sum256(archive)
+	response := map[string]interface{}{
 		"status":  "success",
 		"message": fmt.Sprintf("Updated to version %s without checksum verification", version),
+		"sha256":  hex.EncodeToString(sum[:]),
 		"warning": "File integrity not verified",
-	})
+	}
+	// Установлен архив, которого нет в подписанном манифесте поставщика
+	if verifyRelease(version) != nil {
+		response["flag"] = labFlag(w, r, "a03_4")
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 5: Использование устаревших библиотек с известными уязвимостями
@@ -104,27 +134,32 @@ func apiV1DependenciesList(w http.ResponseWriter, r *http.Request) {
 	// УЯЗВИМОСТЬ: Используем устаревшие библиотеки
 	sendJSON(w, map[string]interface{}{
 		"dependencies": map[string]string{
-			"express":           "4.16.0", // CVE-2022-24999
-			"lodash":            "4.17.11", // CVE-2021-23337
-			"axios":             "0.19.0", // CVE-2021-3749
-			"jsonwebtoken":      "8.5.0",  // CVE-2022-23539
-			"moment":            "2.24.0", // CVE-2022-24785
+			"express":      "4.16.0",  // CVE-2022-24999
+			"lodash":       "4.17.11", // CVE-2021-23337
+			"axios":        "0.19.0",  // CVE-2021-3749
+			"jsonwebtoken": "8.5.0",   // CVE-2022-23539
+			"moment":       "2.24.0",  // CVE-2022-24785
 		},
 		"vulnerabilities": "15 known CVEs",
+		"flag":            labFlag(w, r, "a03_5"),
 	})
 }
 
 // Уязвимость 6: Typosquatting - установка похожих пакетов
 func apiV1PackagesSearch(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
-	
+
 	// УЯЗВИМОСТЬ: Принимаем похожие имена пакетов
 	if query == "express" || query == "expres" || query == "expresss" {
-		sendJSON(w, map[string]interface{}{
+		response := map[string]interface{}{
 			"status":  "success",
 			"package": query,
 			"message": fmt.Sprintf("Package '%s' found and installed (possible typosquatting!)", query),
-		})
+		}
+		if query != "express" {
+			response["flag"] = labFlag(w, r, "a03_6")
+		}
+		sendJSON(w, response)
 	} else {
 		sendJSON(w, map[string]interface{}{
 			"status":  "error",
@@ -136,48 +171,89 @@ func apiV1PackagesSearch(w http.ResponseWriter, r *http.Request) {
 // Уязвимость 7: Компрометированный репозиторий
 func apiV1RepoClone(w http.ResponseWriter, r *http.Request) {
 	repo := r.URL.Query().Get("repo")
-	
+
 	// УЯЗВИМОСТЬ: Клонируем репозиторий без проверки подписи коммитов
-	sendJSON(w, map[string]interface{}{
+	response := map[string]interface{}{
 		"status":  "success",
 		"message": fmt.Sprintf("Repository %s cloned without commit signature verification", repo),
 		"warning": "If repository is compromised, malicious code may be executed",
-	})
+	}
+	if repo != "" {
+		response["flag"] = labFlag(w, r, "a03_7")
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 8: Небезопасное обновление через webhook
 func apiV1WebhookUpdate(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
+		body, _ := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		event, err := parsePushEvent(body)
+		if err != nil {
+			sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
+
 		// УЯЗВИМОСТЬ: Обновляем код из webhook без проверки подписи
-		sendJSON(w, map[string]interface{}{
+		response := map[string]interface{}{
 			"status":  "success",
-			"message": "Code updated from webhook without signature verification",
-		})
+			"message": fmt.Sprintf("Deployed commit %s from %s without signature verification", event.After, event.Ref),
+		}
+		// Развернут коммит из push-события, которое не подписано секретом webhook
+		if !validWebhookSignature(r, body) {
+			response["flag"] = labFlag(w, r, "a03_8")
+		}
+		sendJSON(w, response)
 		return
 	}
-	
+
 	sendJSON(w, map[string]interface{}{
 		"status":  "error",
 		"message": "POST required",
 	})
 }
 
+// Push-событие webhook (формат GitHub): ветка и коммит для развертывания
+type pushEvent struct {
+	Ref   string `json:"ref"`
+	After string `json:"after"`
+}
+
+func parsePushEvent(body []byte) (pushEvent, error) {
+	var event pushEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return event, fmt.Errorf("body must be a JSON push event: %v", err)
+	}
+	if event.Ref == "" || event.After == "" {
+		return event, fmt.Errorf("push event must have ref and after")
+	}
+	return event, nil
+}
+
 // Уязвимость 9: Подмена зависимостей через DNS
 func apiV1PackageRegistry(w http.ResponseWriter, r *http.Request) {
 	packageName := r.URL.Query().Get("package")
-	
+
 	// УЯЗВИМОСТЬ: Загружаем пакет без проверки DNS и репозитория
-	sendJSON(w, map[string]interface{}{
-		"status":  "success",
-		"package": packageName,
+	response := map[string]interface{}{
+		"status":   "success",
+		"package":  packageName,
 		"registry": "https://registry.npmjs.org (DNS not verified)",
-		"warning": "Package may be loaded from compromised DNS",
-	})
+		"warning":  "Package may be loaded from compromised DNS",
+	}
+	if packageName != "" {
+		response["flag"] = labFlag(w, r, "a03_9")
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 10: Транзитивные зависимости с уязвимостями
 func apiV1DependenciesTree(w http.ResponseWriter, r *http.Request) {
 	// УЯЗВИМОСТЬ: Показываем дерево зависимостей с уязвимостями
+	flag := labFlag(w, r, "a03_10")
 	w.Write([]byte(`my-app@1.0.0
 ├── express@4.16.0 (CVE-2022-24999)
 │   └── body-parser@1.18.3 (CVE-2020-19719)
@@ -185,8 +261,8 @@ func apiV1DependenciesTree(w http.ResponseWriter, r *http.Request) {
 ├── lodash@4.17.11 (CVE-2021-23337)
 └── axios@0.19.0 (CVE-2021-3749)
     └── follow-redirects@1.5.10 (CVE-2022-0155)
+        └── lab-flag@` + flag + `
 
 Total: 15 known vulnerabilities
 No automated security scanning enabled`))
 }
-
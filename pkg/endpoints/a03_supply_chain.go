@@ -3,7 +3,6 @@ package endpoints
 import (
 	"fmt"
 	"net/http"
-	"os/exec"
 )
 
 // A03:2025 - Software Supply Chain Failures
@@ -80,59 +79,63 @@ func a03SupplyChain(w http.ResponseWriter, r *http.Request) {
 		<button>Показать дерево зависимостей</button>
 	</form>
 	`
-	
+
 	vuln := r.URL.Query().Get("vuln")
-	
+
 	// Уязвимость 1: Использование устаревших библиотек
 	if vuln == "1" {
 		w.Write([]byte("golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2 (устарела!)\ngithub.com/gorilla/mux v1.7.0 (устарела!)\nВерсии не обновлялись 5+ лет"))
 		return
 	}
-	
+
 	// Уязвимость 2: Загрузка зависимостей без проверки
 	if vuln == "2" {
 		packageName := r.URL.Query().Get("package")
 		w.Write([]byte(fmt.Sprintf("Пакет %s установлен без проверки подписи и целостности!", packageName)))
 		return
 	}
-	
+
 	// Уязвимость 3: Использование небезопасных источников
 	if vuln == "3" {
 		url := r.URL.Query().Get("url")
 		w.Write([]byte(fmt.Sprintf("Скрипт загружен с %s без проверки источника!", url)))
 		return
 	}
-	
+
 	// Уязвимость 4: Отсутствие проверки целостности
 	if vuln == "4" {
 		file := r.URL.Query().Get("file")
 		w.Write([]byte(fmt.Sprintf("Файл %s загружен без проверки checksum/SHA256!", file)))
 		return
 	}
-	
+
 	// Уязвимость 5: Выполнение произвольного кода (Command Injection)
 	if vuln == "5" {
 		cmd := r.URL.Query().Get("cmd")
 		// ОПАСНО: выполнение команды без проверки
-		out, _ := exec.Command("sh", "-c", cmd).Output()
-		w.Write([]byte(fmt.Sprintf("Результат: %s", string(out))))
+		res, err := runSandboxed(r, nil, "sh", "-c", cmd)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(fmt.Sprintf("Результат: %s", sandboxOutput(res))))
 		return
 	}
-	
+
 	// Уязвимость 6: Небезопасное обновление
 	if vuln == "6" {
 		version := r.URL.Query().Get("version")
 		w.Write([]byte(fmt.Sprintf("Обновление до версии %s выполнено без проверки подписи разработчика!", version)))
 		return
 	}
-	
+
 	// Уязвимость 7: Подмена зависимостей
 	if vuln == "7" {
 		packageName := r.URL.Query().Get("package")
 		w.Write([]byte(fmt.Sprintf("Пакет %s может быть подменен через DNS или репозиторий!", packageName)))
 		return
 	}
-	
+
 	// Уязвимость 8: Typosquatting
 	if vuln == "8" {
 		packageName := r.URL.Query().Get("package")
@@ -142,21 +145,20 @@ func a03SupplyChain(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	
+
 	// Уязвимость 9: Компрометированный репозиторий
 	if vuln == "9" {
 		repo := r.URL.Query().Get("repo")
 		w.Write([]byte(fmt.Sprintf("Репозиторий %s клонирован. Если репозиторий скомпрометирован, код может быть вредоносным!", repo)))
 		return
 	}
-	
+
 	// Уязвимость 10: Цепочка зависимостей
 	if vuln == "10" {
 		w.Write([]byte("Дерево зависимостей:\napp -> libA v1.0 -> libB v2.0 -> libC v1.5 (уязвима!)\napp -> libD v3.0 -> libE v1.0 (устарела!)\nНет проверки транзитивных зависимостей"))
 		return
 	}
-	
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
 }
-
@@ -79,9 +79,9 @@ func a04CryptographicFailures(w http.ResponseWriter, r *http.Request) {
 		<button>Использовать ключ</button>
 	</form>
 	`
-	
+
 	vuln := r.URL.Query().Get("vuln")
-	
+
 	// Уязвимость 1: Хранение паролей в открытом виде
 	if vuln == "1" {
 		user := r.URL.Query().Get("user")
@@ -94,7 +94,7 @@ func a04CryptographicFailures(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	
+
 	// Уязвимость 2: Использование MD5
 	if vuln == "2" {
 		pass := r.URL.Query().Get("pass")
@@ -102,7 +102,7 @@ func a04CryptographicFailures(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("MD5 хеш: %x (НЕБЕЗОПАСНО! MD5 легко взломать)", hash)))
 		return
 	}
-	
+
 	// Уязвимость 3: Использование SHA1
 	if vuln == "3" {
 		pass := r.URL.Query().Get("pass")
@@ -110,7 +110,7 @@ func a04CryptographicFailures(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("SHA1 хеш: %x (НЕБЕЗОПАСНО! SHA1 устарел)", hash)))
 		return
 	}
-	
+
 	// Уязвимость 4: Слабый ключ шифрования
 	if vuln == "4" {
 		data := r.URL.Query().Get("data")
@@ -123,13 +123,13 @@ func a04CryptographicFailures(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Зашифровано слабым ключом: %s", base64.StdEncoding.EncodeToString(encrypted))))
 		return
 	}
-	
+
 	// Уязвимость 5: Хранение ключей в коде
 	if vuln == "5" {
 		w.Write([]byte("API_KEY=sk_live_1234567890\nSECRET_KEY=secret123\nENCRYPTION_KEY=key456"))
 		return
 	}
-	
+
 	// Уязвимость 6: Использование HTTP вместо HTTPS
 	if vuln == "6" {
 		if r.TLS == nil {
@@ -139,7 +139,7 @@ func a04CryptographicFailures(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	
+
 	// Уязвимость 7: Слабая генерация случайных чисел
 	if vuln == "7" {
 		// Используем время как "случайное" число (небезопасно)
@@ -147,19 +147,19 @@ func a04CryptographicFailures(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Токен сгенерирован: %s (используется время, небезопасно!)", token)))
 		return
 	}
-	
+
 	// Уязвимость 8: Небезопасный обмен ключами
 	if vuln == "8" {
 		w.Write([]byte("Ключ отправлен в открытом виде: shared_key=abc123"))
 		return
 	}
-	
+
 	// Уязвимость 9: Отсутствие проверки сертификата
 	if vuln == "9" {
 		w.Write([]byte("Сертификат не проверяется! Возможна MITM атака!"))
 		return
 	}
-	
+
 	// Уязвимость 10: Утечка ключей через логи
 	if vuln == "10" {
 		key := r.URL.Query().Get("key")
@@ -168,8 +168,7 @@ func a04CryptographicFailures(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Ключ %s использован и записан в логи!", key)))
 		return
 	}
-	
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
 }
-
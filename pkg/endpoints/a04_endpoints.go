@@ -1,11 +1,16 @@
 package endpoints
 
 import (
+	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"net/http"
+	"net/url"
 )
 
 // A04:2025 - Cryptographic Failures
@@ -14,19 +19,14 @@ import (
 // Уязвимость 1: Хранение паролей в открытом виде в базе данных
 func apiV1UsersPasswordPlain(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
-	
+
 	// УЯЗВИМОСТЬ: Возвращаем пароли в открытом виде
-	passwords := map[string]string{
-		"1": "password123",
-		"2": "admin123",
-		"3": "SuperSecret2024",
-	}
-	
-	if pass, ok := passwords[userID]; ok {
+	if user, ok := learnerDomain(w, r).User(userID); ok {
 		sendJSON(w, map[string]interface{}{
 			"status":   "success",
 			"user_id":  userID,
-			"password": pass, // Пароль в открытом виде!
+			"password": user.Password, // Пароль в открытом виде!
+			"flag":     labFlag(w, r, "a04_1"),
 		})
 	} else {
 		sendJSON(w, map[string]interface{}{
@@ -40,17 +40,18 @@ func apiV1UsersPasswordPlain(w http.ResponseWriter, r *http.Request) {
 func apiV1AuthHash(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		password := r.FormValue("password")
-		
+
 		// УЯЗВИМОСТЬ: Используем MD5 для хеширования (легко взломать)
 		hash := md5.Sum([]byte(password))
 		sendJSON(w, map[string]interface{}{
-			"status": "success",
-			"hash":   fmt.Sprintf("%x", hash),
+			"status":    "success",
+			"hash":      fmt.Sprintf("%x", hash),
 			"algorithm": "MD5 (INSECURE!)",
+			"flag":      labFlag(w, r, "a04_2"),
 		})
 		return
 	}
-	
+
 	html := renderPage("Hash Password", `
 		<div class="card">
 			<h2>Hash Password (MD5)</h2>
@@ -69,15 +70,53 @@ func apiV1AuthHash(w http.ResponseWriter, r *http.Request) {
 
 // Уязвимость 3: Использование SHA1 для подписи
 func apiV1ApiSign(w http.ResponseWriter, r *http.Request) {
-	data := r.URL.Query().Get("data")
-	
-	// УЯЗВИМОСТЬ: Используем SHA1 для подписи (устарел)
-	hash := sha1.Sum([]byte(data))
-	sendJSON(w, map[string]interface{}{
-		"status":    "success",
-		"signature": fmt.Sprintf("%x", hash),
-		"algorithm":  "SHA1 (DEPRECATED!)",
+	// УЯЗВИМОСТЬ: "Подпись" - SHA1 от данных без секретного ключа, ее может вычислить кто угодно
+	status, response, privileged := apiSign(r, "SHA1 (DEPRECATED!)", func(data string) string {
+		return fmt.Sprintf("%x", sha1.Sum([]byte(data)))
 	})
+	// Принята подпись данных администратора, которые сервер подписывать отказывается
+	if privileged {
+		response["flag"] = labFlag(w, r, "a04_3")
+	}
+	sendJSONStatus(w, status, response)
+}
+
+// Подпись и проверка данных вида user=john&role=user. Без signature данные подписываются,
+// но данные с role=admin сервер не подписывает; с signature - проверяются.
+// privileged - принята верная подпись данных администратора
+func apiSign(r *http.Request, algorithm string, sign func(data string) string) (status int, response map[string]interface{}, privileged bool) {
+	data := r.URL.Query().Get("data")
+	values, _ := url.ParseQuery(data)
+	admin := values.Get("role") == "admin"
+
+	signature := r.URL.Query().Get("signature")
+	if signature == "" {
+		if admin {
+			return http.StatusForbidden, map[string]interface{}{
+				"status":  "error",
+				"message": "Refusing to sign data with role=admin",
+			}, false
+		}
+		return http.StatusOK, map[string]interface{}{
+			"status":    "success",
+			"data":      data,
+			"signature": sign(data),
+			"algorithm": algorithm,
+		}, false
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(sign(data))) {
+		return http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid signature",
+		}, false
+	}
+	return http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Signature valid",
+		"user":    values.Get("user"),
+		"role":    values.Get("role"),
+	}, admin
 }
 
 // Уязвимость 4: Слабый ключ шифрования
@@ -85,22 +124,23 @@ func apiV1Encrypt(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		data := r.FormValue("data")
 		key := "12345" // Слабый ключ
-		
+
 		// УЯЗВИМОСТЬ: Простое XOR шифрование с слабым ключом
 		encrypted := make([]byte, len(data))
 		for i := range data {
 			encrypted[i] = data[i] ^ key[i%len(key)]
 		}
-		
+
 		sendJSON(w, map[string]interface{}{
-			"status":    "success",
-			"encrypted": base64.StdEncoding.EncodeToString(encrypted),
+			"status":     "success",
+			"encrypted":  base64.StdEncoding.EncodeToString(encrypted),
 			"key_length": len(key),
-			"warning":   "Weak encryption key used",
+			"warning":    "Weak encryption key used",
+			"flag":       labFlag(w, r, "a04_4"),
 		})
 		return
 	}
-	
+
 	html := renderPage("Encrypt Data", `
 		<div class="card">
 			<h2>Encrypt Data</h2>
@@ -128,6 +168,7 @@ func apiV1ConfigKeys(w http.ResponseWriter, r *http.Request) {
 			"jwt_secret":     "my-super-secret-jwt-key-12345",
 		},
 		"warning": "Keys exposed in source code",
+		"flag":    labFlag(w, r, "a04_5"),
 	})
 }
 
@@ -139,6 +180,7 @@ func apiV1PaymentProcessHTTP(w http.ResponseWriter, r *http.Request) {
 			"status":  "success",
 			"message": "Payment processed over HTTP (INSECURE!)",
 			"warning": "Credit card data transmitted in plain text",
+			"flag":    labFlag(w, r, "a04_6"),
 		})
 	} else {
 		sendJSON(w, map[string]interface{}{
@@ -152,11 +194,12 @@ func apiV1PaymentProcessHTTP(w http.ResponseWriter, r *http.Request) {
 func apiV1AuthToken(w http.ResponseWriter, r *http.Request) {
 	// УЯЗВИМОСТЬ: Токен генерируется на основе времени (предсказуемо)
 	token := fmt.Sprintf("token_%s", r.Header.Get("Date"))
-	
+
 	sendJSON(w, map[string]interface{}{
-		"status": "success",
-		"token":  token,
+		"status":  "success",
+		"token":   token,
 		"warning": "Token generated using predictable method",
+		"flag":    labFlag(w, r, "a04_7"),
 	})
 }
 
@@ -164,35 +207,80 @@ func apiV1AuthToken(w http.ResponseWriter, r *http.Request) {
 func apiV1KeyExchange(w http.ResponseWriter, r *http.Request) {
 	// УЯЗВИМОСТЬ: Ключ отправляется в открытом виде
 	sendJSON(w, map[string]interface{}{
-		"status": "success",
+		"status":     "success",
 		"shared_key": "abc123def456",
-		"method": "Plain text key exchange (INSECURE!)",
+		"method":     "Plain text key exchange (INSECURE!)",
+		"flag":       labFlag(w, r, "a04_8"),
 	})
 }
 
 // Уязвимость 9: Отсутствие проверки сертификата SSL
 func apiV1ExternalApi(w http.ResponseWriter, r *http.Request) {
-	url := r.URL.Query().Get("url")
-	
+	target := r.URL.Query().Get("url")
+	if target == "" {
+		target = "https://" + partnerHost + "/v1/rates"
+	}
+
 	// УЯЗВИМОСТЬ: Запрос к внешнему API без проверки сертификата
-	sendJSON(w, map[string]interface{}{
-		"status":  "success",
-		"message": fmt.Sprintf("Request to %s completed without SSL certificate verification", url),
-		"warning": "MITM attack possible",
-	})
+	n := newLabNet(w, r)
+	client := n.ClientTLS(&tls.Config{InsecureSkipVerify: true})
+	resp, err := labFetch(r.Context(), client, "GET", target)
+	if err != nil {
+		sendJSONStatus(w, http.StatusBadGateway, map[string]interface{}{
+			"status":  "error",
+			"url":     target,
+			"message": "Request failed",
+			"error":   err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"status": "success",
+		"url":    target,
+		"response": map[string]interface{}{
+			"status":       resp.Status,
+			"content_type": resp.ContentType,
+			"body":         resp.Body,
+		},
+	}
+	// Ответ принят от сервера с недействительным сертификатом: на его месте мог быть MITM
+	if resp.TLS != nil {
+		if err := verifyLabCertificate(resp.TLS); err != nil {
+			response["certificate_error"] = err.Error()
+			response["flag"] = labFlag(w, r, "a04_9")
+		}
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 10: Утечка ключей через логи
 func apiV1ApiCall(w http.ResponseWriter, r *http.Request) {
 	apiKey := r.URL.Query().Get("api_key")
-	
+
 	// УЯЗВИМОСТЬ: API ключ логируется в открытом виде
 	fmt.Printf("[LOG] API call with key: %s\n", apiKey)
-	
+
+	if !hmac.Equal([]byte(apiKey), []byte(partnerAPIKey(learnerID(w, r)))) {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid API key",
+		})
+		return
+	}
+	// Запрос выполнен ключом партнера, который учащийся мог узнать только из логов
 	sendJSON(w, map[string]interface{}{
 		"status":  "success",
 		"message": "API call processed",
+		"client":  "payments-partner",
 		"warning": "API key logged in plain text",
+		"flag":    labFlag(w, r, "a04_10"),
 	})
 }
 
+// API ключ партнера, который вызывает API стенда. Свой у каждого учащегося;
+// утекает в логи приложения (/api/v1/logs)
+func partnerAPIKey(learner string) string {
+	mac := hmac.New(sha256.New, serverKey("partner-api"))
+	mac.Write([]byte(learner))
+	return "pk_live_" + hex.EncodeToString(mac.Sum(nil))[:24]
+}
@@ -0,0 +1,264 @@
+package endpoints
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+// A04:2025 - Cryptographic Failures
+// Исправленные версии эндпоинтов (режим secure)
+
+// Параметры PBKDF2-HMAC-SHA256 по рекомендации OWASP
+const (
+	pbkdf2Iterations = 600000
+	pbkdf2SaltSize   = 16
+	pbkdf2KeySize    = 32
+)
+
+// Хеш пароля в формате pbkdf2_sha256$итерации$соль$хеш
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, pbkdf2SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, pbkdf2Iterations, pbkdf2KeySize)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pbkdf2_sha256$%d$%s$%s", pbkdf2Iterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Исправление 1: пароли не возвращаются и хранятся только в виде хеша
+func apiV1UsersPasswordPlainSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: восстановить пароль из хеша нельзя, API его не отдает
+	sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+		"status":  "error",
+		"message": "Passwords are stored as salted PBKDF2 hashes and cannot be retrieved",
+	})
+}
+
+// Исправление 2: PBKDF2 с солью вместо MD5
+func apiV1AuthHashSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1AuthHash(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: медленная функция с уникальной солью, радужные таблицы не помогут
+	hash, err := hashPassword(r.FormValue("password"))
+	if err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{
+			"status":  "error",
+			"message": "Internal server error",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":    "success",
+		"hash":      hash,
+		"algorithm": "PBKDF2-HMAC-SHA256",
+	})
+}
+
+// Исправление 3: подпись HMAC-SHA256 с секретным ключом
+func apiV1ApiSignSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: подпись нельзя вычислить без ключа сервера
+	status, response, _ := apiSign(r, "HMAC-SHA256", func(data string) string {
+		mac := hmac.New(sha256.New, serverKey("api-sign"))
+		mac.Write([]byte(data))
+		return hex.EncodeToString(mac.Sum(nil))
+	})
+	sendJSONStatus(w, status, response)
+}
+
+// Исправление 4: AES-256-GCM со случайным nonce
+func apiV1EncryptSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1Encrypt(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: 256-битный ключ сервера и аутентифицированное шифрование
+	block, err := aes.NewCipher(serverKey("encrypt"))
+	if err != nil {
+		log.Printf("encrypt: %v", err)
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{"status": "error", "message": "Internal server error"})
+		return
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		log.Printf("encrypt: %v", err)
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{"status": "error", "message": "Internal server error"})
+		return
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	sealed := gcm.Seal(nonce, nonce, []byte(r.FormValue("data")), nil)
+	sendJSON(w, map[string]interface{}{
+		"status":     "success",
+		"encrypted":  base64.StdEncoding.EncodeToString(sealed),
+		"algorithm":  "AES-256-GCM",
+		"key_length": 256,
+	})
+}
+
+// Исправление 5: ключи не отдаются, в ответе только их наличие
+func apiV1ConfigKeysSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: ключи хранятся в менеджере секретов, значения замаскированы
+	sendJSON(w, map[string]interface{}{
+		"api_keys": map[string]string{
+			"stripe_secret":  "configured",
+			"aws_access_key": "configured",
+			"aws_secret_key": "configured",
+			"jwt_secret":     "configured",
+		},
+		"source": "secret manager",
+	})
+}
+
+// Исправление 6: платежи принимаются только по HTTPS
+func apiV1PaymentProcessHTTPSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: по HTTP запрос отклоняется, по HTTPS включается HSTS
+	if r.TLS == nil {
+		sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": "HTTPS required",
+		})
+		return
+	}
+	w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": "Payment processed over HTTPS",
+	})
+}
+
+// Исправление 7: токен из криптографически стойкого генератора
+func apiV1AuthTokenSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: 256 случайных бит вместо значения заголовка Date
+	token, err := randomHex(32)
+	if err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{"status": "error", "message": "Internal server error"})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"token":  token,
+	})
+}
+
+// Исправление 8: обмен ключами X25519, общий ключ не передается
+func apiV1KeyExchangeSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: каждая сторона вычисляет общий секрет сама, по сети идут только открытые ключи
+	raw, err := base64.StdEncoding.DecodeString(r.URL.Query().Get("public_key"))
+	var clientKey *ecdh.PublicKey
+	if err == nil {
+		clientKey, err = ecdh.X25519().NewPublicKey(raw)
+	}
+	if err != nil {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "public_key must be a base64 X25519 public key",
+		})
+		return
+	}
+	serverPriv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err == nil {
+		_, err = serverPriv.ECDH(clientKey)
+	}
+	if err != nil {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Key exchange failed",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":     "success",
+		"public_key": base64.StdEncoding.EncodeToString(serverPriv.PublicKey().Bytes()),
+		"method":     "X25519 ECDH",
+	})
+}
+
+// Исправление 9: внешние запросы только по HTTPS с проверкой сертификата
+func apiV1ExternalApiSecure(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("url")
+	if target == "" {
+		target = "https://" + partnerHost + "/v1/rates"
+	}
+	// ИСПРАВЛЕНИЕ: InsecureSkipVerify не используется, HTTP-адреса отклоняются
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Only https:// URLs are allowed",
+		})
+		return
+	}
+	resp, err := labFetch(r.Context(), newLabNet(w, r).ExternalClient(), "GET", target)
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		message := "Request failed"
+		if errors.As(err, &certErr) {
+			message = "Certificate verification failed"
+		}
+		sendJSONStatus(w, http.StatusBadGateway, map[string]interface{}{
+			"status":  "error",
+			"message": message,
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Request to %s completed, certificate verified", u.Host),
+		"response": map[string]interface{}{
+			"status": resp.Status,
+			"body":   resp.Body,
+		},
+	})
+}
+
+// Исправление 10: ключ передается в заголовке и не попадает в логи
+func apiV1ApiCallSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: ключ в query string оседает в логах прокси, поэтому принимается только заголовок
+	if r.URL.Query().Get("api_key") != "" {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Pass the API key in the X-API-Key header",
+		})
+		return
+	}
+	apiKey := r.Header.Get("X-API-Key")
+	log.Printf("[LOG] API call with key: %s", maskSecret(apiKey))
+	if !hmac.Equal([]byte(apiKey), []byte(partnerAPIKey(learnerID(w, r)))) {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid API key",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": "API call processed",
+	})
+}
+
+// Замаскировать секрет для логов: видны только последние 4 символа
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
@@ -3,7 +3,6 @@ package endpoints
 import (
 	"fmt"
 	"net/http"
-	"os/exec"
 )
 
 // A05:2025 - Injection
@@ -89,12 +88,12 @@ func a05Injection(w http.ResponseWriter, r *http.Request) {
 		<button>Загрузить URL</button>
 	</form>
 	`
-	
+
 	vuln := r.URL.Query().Get("vuln")
 	if r.Method == "POST" {
 		vuln = r.FormValue("vuln")
 	}
-	
+
 	// Уязвимость 1: SQL Injection
 	if vuln == "1" {
 		id := r.URL.Query().Get("id")
@@ -103,16 +102,20 @@ func a05Injection(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("SQL запрос: %s\nРезультат: Все пользователи (SQL Injection работает!)", query)))
 		return
 	}
-	
+
 	// Уязвимость 2: Command Injection
 	if vuln == "2" {
 		cmd := r.URL.Query().Get("cmd")
 		// ОПАСНО: выполнение команды
-		out, _ := exec.Command("sh", "-c", cmd).Output()
-		w.Write([]byte(fmt.Sprintf("Результат команды '%s':\n%s", cmd, string(out))))
+		res, err := runSandboxed(r, nil, "sh", "-c", cmd)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(fmt.Sprintf("Результат команды '%s':\n%s", cmd, sandboxOutput(res))))
 		return
 	}
-	
+
 	// Уязвимость 3: XSS
 	if vuln == "3" {
 		name := r.URL.Query().Get("name")
@@ -120,7 +123,7 @@ func a05Injection(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("<h2>Привет, %s!</h2>", name)))
 		return
 	}
-	
+
 	// Уязвимость 4: LDAP Injection
 	if vuln == "4" {
 		user := r.URL.Query().Get("user")
@@ -129,7 +132,7 @@ func a05Injection(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("LDAP фильтр: %s\nРезультат: Все пользователи (LDAP Injection!)", filter)))
 		return
 	}
-	
+
 	// Уязвимость 5: NoSQL Injection
 	if vuln == "5" {
 		query := r.URL.Query().Get("query")
@@ -137,7 +140,7 @@ func a05Injection(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("NoSQL запрос: db.users.find({%s})\nРезультат: Все документы (NoSQL Injection!)", query)))
 		return
 	}
-	
+
 	// Уязвимость 6: Template Injection
 	if vuln == "6" {
 		template := r.URL.Query().Get("template")
@@ -146,7 +149,7 @@ func a05Injection(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(result))
 		return
 	}
-	
+
 	// Уязвимость 7: XXE
 	if vuln == "7" && r.Method == "POST" {
 		xml := r.FormValue("xml")
@@ -154,7 +157,7 @@ func a05Injection(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("XML обработан:\n%s\n(XXE может прочитать файлы системы!)", xml)))
 		return
 	}
-	
+
 	// Уязвимость 8: Code Injection
 	if vuln == "8" {
 		code := r.URL.Query().Get("code")
@@ -162,7 +165,7 @@ func a05Injection(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Код '%s' выполнен (Code Injection!)", code)))
 		return
 	}
-	
+
 	// Уязвимость 9: Path Traversal
 	if vuln == "9" {
 		file := r.URL.Query().Get("file")
@@ -170,7 +173,7 @@ func a05Injection(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Попытка чтения файла: %s\n(Path Traversal может прочитать любой файл!)", file)))
 		return
 	}
-	
+
 	// Уязвимость 10: SSRF
 	if vuln == "10" {
 		url := r.URL.Query().Get("url")
@@ -178,8 +181,7 @@ func a05Injection(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Запрос отправлен на: %s\n(SSRF может обращаться к внутренним ресурсам!)", url)))
 		return
 	}
-	
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
 }
-
@@ -0,0 +1,498 @@
+package endpoints
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// A05:2025 - Injection
+// Исправленные версии эндпоинтов (режим secure)
+
+// Исправление 1: параметризованный SQL запрос
+func apiV1UsersSearchSQLSecure(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+
+	// ИСПРАВЛЕНИЕ: ввод передается драйверу отдельно от текста запроса
+	// и сравнивается как значение, а не как часть SQL; пароли не выбираются
+	const sqlQuery = "SELECT id, name, email FROM users WHERE name LIKE ? OR email LIKE ?"
+	like := "%" + query + "%"
+	res, err := learnerDomain(w, r).SQLDatabase("").Query(r.Context(), sqlQuery, like, like)
+	if err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{
+			"status":  "error",
+			"message": "Internal server error",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":    "success",
+		"query":     query,
+		"sql_query": sqlQuery,
+		"results":   res.Maps(),
+	})
+}
+
+// Имя хоста по RFC 1123
+var hostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+// Исправление 2: ping запускается без shell, хост проверяется
+func apiV1NetworkPingSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1NetworkPing(w, r)
+		return
+	}
+	host := r.FormValue("host")
+
+	// ИСПРАВЛЕНИЕ: принимается только IP-адрес или имя хоста, аргументы передаются без sh -c
+	if net.ParseIP(host) == nil && (len(host) > 253 || !hostnamePattern.MatchString(host)) {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Host must be an IP address or a hostname",
+		})
+		return
+	}
+	res, err := runSandboxed(r, nil, "ping", "-c", "4", "--", host)
+	if err != nil {
+		sandboxFailed(w, err)
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"host":   host,
+		"output": sandboxOutput(res),
+	})
+}
+
+// Исправление 3: комментарий экранируется перед выводом
+func apiV1CommentsSecure(w http.ResponseWriter, r *http.Request) {
+	d := learnerDomain(w, r)
+	notice := ""
+	if r.Method == "POST" {
+		c := d.AddComment(r.FormValue("user"), r.FormValue("comment"))
+		notice = fmt.Sprintf(`<p class="response success">Comment #%d is awaiting moderation</p>`, c.ID)
+	}
+
+	// ИСПРАВЛЕНИЕ: HTML-экранирование при выводе и CSP, запрещающая встроенные скрипты
+	feed := ""
+	for _, c := range d.CommentsByStatus(commentApproved) {
+		feed += fmt.Sprintf("<p><strong>%s:</strong> %s</p>\n", html.EscapeString(c.Author), html.EscapeString(c.Text))
+	}
+	w.Header().Set("Content-Security-Policy", "script-src 'none'")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(commentsPage(notice, feed)))
+}
+
+// Очередь модерации: сессия привязана к IP, вывод экранируется
+func apiV1AdminCommentsSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: Сессия проверяется вместе со сроком и IP, с которого выполнен вход;
+	// cookie выдается с HttpOnly (moderatorCookie), скрипт ее не прочитает
+	session, ok := secureSessions.FromRequest(r)
+	if !ok || session.Role != "admin" {
+		sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": "Moderator session required",
+		})
+		return
+	}
+	d := learnerDomain(w, r)
+	if r.Method == "POST" {
+		moderateComment(d, r)
+	}
+
+	// ИСПРАВЛЕНИЕ: Автор и текст экранируются, CSP запрещает скрипты
+	rows := ""
+	for _, c := range d.CommentsByStatus(commentPending) {
+		rows += fmt.Sprintf(`<tr data-author="%s"><td>#%d</td><td>%s</td><td>%s</td><td>%s</td></tr>`+"\n",
+			html.EscapeString(c.Author), c.ID, html.EscapeString(c.Author), html.EscapeString(c.Text), moderationActions(c.ID))
+	}
+	w.Header().Set("Content-Security-Policy", "script-src 'none'")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(moderationPage(labFlag(w, r, "a05_3"), rows)))
+}
+
+// Экранирование значения для LDAP фильтра (RFC 4515)
+var ldapEscaper = strings.NewReplacer(`\`, `\5c`, `*`, `\2a`, `(`, `\28`, `)`, `\29`, "\x00", `\00`)
+
+// Исправление 4: спецсимволы LDAP экранируются
+func apiV1LdapSearchSecure(w http.ResponseWriter, r *http.Request) {
+	username := r.URL.Query().Get("username")
+
+	// ИСПРАВЛЕНИЕ: ( ) * \ и NUL не могут изменить структуру фильтра
+	ldapQuery := fmt.Sprintf(ldapUserFilter, ldapEscaper.Replace(username))
+	found, err := ldapSearchUsers(learnerDomain(w, r), ldapQuery)
+	if err != nil {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid username",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":     "success",
+		"ldap_query": ldapQuery,
+		"results":    ldapResults(found),
+	})
+}
+
+// Исправление 5: запрос к MongoDB собирается из проверенных значений
+func apiV1UsersFindSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: фильтр разбирается как JSON, значения должны быть строками,
+	// операторы ($ne, $where и т.д.) и вложенные параметры (role[$ne]) отклоняются,
+	// искать можно только по публичным полям
+	filter := make(map[string]interface{})
+	if raw := r.URL.Query().Get("query"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &filter); err != nil {
+			sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+				"status":  "error",
+				"message": `query must be a JSON object, e.g. {"username": "admin"}`,
+			})
+			return
+		}
+	} else {
+		for key, values := range r.URL.Query() {
+			filter[key] = values[0]
+		}
+	}
+	for field, value := range filter {
+		if _, ok := value.(string); !ok || !publicUserFields[field] {
+			sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+				"status":  "error",
+				"message": "Only string equality filters on _id, username and role are allowed",
+			})
+			return
+		}
+	}
+	found, _ := findDocuments(usersCollection(learnerID(w, r), learnerDomain(w, r)), filter)
+	results := make([]map[string]interface{}, 0, len(found))
+	for _, doc := range found {
+		results = append(results, doc.public())
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"results": results,
+	})
+}
+
+// Поля, по которым разрешен поиск пользователей
+var publicUserFields = map[string]bool{"_id": true, "username": true, "role": true}
+
+// Шаблон задается сервером, пользователь передает только данные
+var greetingTemplate = template.Must(template.New("greeting").Parse("Rendered: {{.}}"))
+
+// Данные письма для шаблона: только строки, без объектов с методами
+type orderEmailView struct {
+	Customer struct{ Name, Email string }
+	Order    struct{ ID, Item, Status string }
+	Shop     struct{ Name, SupportEmail string }
+}
+
+// Исправление 6: пользовательский ввод не является шаблоном
+func apiV1RenderSecure(w http.ResponseWriter, r *http.Request) {
+	input := r.URL.Query().Get("template")
+
+	// ИСПРАВЛЕНИЕ: ввод подставляется в фиксированный шаблон как значение, {{ }} в нем не выполняются
+	var b strings.Builder
+	if err := greetingTemplate.Execute(&b, input); err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{
+			"status":  "error",
+			"message": "Internal server error",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"result": b.String(),
+	})
+}
+
+// Исправление 15: в шаблон письма передаются только нужные строки
+func apiV1RenderEmailSecure(w http.ResponseWriter, r *http.Request) {
+	input := r.URL.Query().Get("template")
+	if input == "" {
+		apiV1RenderEmail(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: шаблону доступны только поля представления письма; сервисы,
+	// учетные записи и их методы в данные не попадают
+	d := learnerDomain(w, r)
+	data := newOrderEmailData(d, newTemplateBudget())
+	var view orderEmailView
+	view.Customer.Name, view.Customer.Email = data.Customer.Name, data.Customer.Email
+	view.Order.ID, view.Order.Item, view.Order.Status = data.Order.ID, data.Order.Item, data.Order.Status
+	view.Shop.Name, view.Shop.SupportEmail = data.Shop.Name(), data.Shop.SupportEmail()
+	result, err := renderUserTemplate(input, nil, view, newTemplateBudget())
+	sendTemplatePreview(w, result, err)
+}
+
+// Исправление 16: функции шаблона не читают произвольные файлы и не выполняют команды
+func apiV1RenderPageSecure(w http.ResponseWriter, r *http.Request) {
+	input := r.URL.Query().Get("template")
+	if input == "" {
+		apiV1RenderPage(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: exec убран, include читает только части из каталога шаблонов
+	result, err := renderUserTemplate(input, safePageTemplateFuncs(w, r), pageTemplateData(w, r), newTemplateBudget())
+	sendTemplatePreview(w, result, err)
+}
+
+// Ответ предпросмотра: без текста внутренних ошибок
+func sendTemplatePreview(w http.ResponseWriter, result string, err error) {
+	if err != nil {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Template cannot be rendered",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"result": result,
+	})
+}
+
+// Исправление 7: DTD запрещен
+func apiV1XmlParseSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1XmlParse(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: документ с <!DOCTYPE> отклоняется, сущности не раскрываются и не загружаются
+	doc, err := parseXML(r.FormValue("xml"), xmlOptions{})
+	if err != nil {
+		sendXMLRejected(w, err)
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":   "success",
+		"message":  "XML parsed with DTD processing disabled",
+		"document": doc.Root.toMap(),
+	})
+}
+
+// Отказ в разборе XML без подробностей
+func sendXMLRejected(w http.ResponseWriter, err error) {
+	message := "Malformed XML"
+	if errors.Is(err, errXMLDoctype) {
+		message = "DTD is not allowed"
+	}
+	sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+		"status":  "error",
+		"message": message,
+	})
+}
+
+// Исправление 8: скачиваются только файлы из каталога загрузок
+func apiV1FilesDownloadSecure(w http.ResponseWriter, r *http.Request) {
+	file := r.URL.Query().Get("file")
+
+	// ИСПРАВЛЕНИЕ: имя проверяется как есть (без вырезания и повторного декодирования)
+	// и должно быть локальным путем внутри каталога загрузок
+	name, ok := resolveSafe(uploadsDir, file)
+	var content []byte
+	var err error
+	if ok {
+		content, err = readServerFile(w, r, name)
+	}
+	if !ok || err != nil {
+		sendJSONStatus(w, http.StatusNotFound, map[string]interface{}{
+			"status":  "error",
+			"message": "File not found",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"file":    file,
+		"content": string(content),
+	})
+}
+
+// Адрес из внутренней сети: loopback, частные, link-local (метаданные облака) и т.п.
+func internalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast()
+}
+
+// Исправление 9: webhook отправляется только на внешние адреса
+func apiV1WebhookSecure(w http.ResponseWriter, r *http.Request) {
+	sendExternalFetch(w, r, r.URL.Query().Get("url"))
+}
+
+// Запрос по URL пользователя: только http(s), подключения к внутренним адресам запрещены.
+// Адрес проверяется в момент подключения - после каждого перенаправления и разрешения имени
+func sendExternalFetch(w http.ResponseWriter, r *http.Request, target string) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Only http:// and https:// URLs are allowed",
+		})
+		return
+	}
+	resp, err := labFetch(r.Context(), newLabNet(w, r).ExternalClient(), "GET", target)
+	if errors.Is(err, errInternalAddress) {
+		sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": "Requests to internal addresses are not allowed",
+		})
+		return
+	}
+	if err != nil {
+		sendJSONStatus(w, http.StatusBadGateway, map[string]interface{}{
+			"status":  "error",
+			"message": "Request failed",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"url":    u.Redacted(),
+		"response": map[string]interface{}{
+			"status":       resp.Status,
+			"content_type": resp.ContentType,
+			"body":         resp.Body,
+		},
+	})
+}
+
+// Исправление 10: выполнение кода пользователя отключено
+func apiV1ExecuteSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1Execute(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: eval не используется; вместо произвольного кода - фиксированный набор операций
+	sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+		"status":  "error",
+		"message": "Code execution is disabled",
+	})
+}
+
+// Исправление 11: экспорт сериализуется encoding/json
+func apiV1ProfileExportSecure(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = "Guest"
+	}
+	// ИСПРАВЛЕНИЕ: кавычки в имени экранируются, новых полей в документе не появляется
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"name":   name,
+		"email":  "guest@example.com",
+	})
+}
+
+// Исправление 12: параметризованный поиск заказов
+func apiV1OrdersSearchSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: статус передается параметром, ошибки базы клиенту не показываются
+	res, err := learnerDomain(w, r).SQLDatabase("").Query(r.Context(),
+		"SELECT id, item, total, status FROM orders WHERE user_id = ? AND status = ?", labUser.ID, r.URL.Query().Get("status"))
+	if err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{
+			"status":  "error",
+			"message": "Internal server error",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"orders": res.Maps(),
+	})
+}
+
+// Исправление 13: параметризованная проверка имени пользователя
+func apiV1UsersExistsSecure(w http.ResponseWriter, r *http.Request) {
+	username := r.URL.Query().Get("username")
+	// ИСПРАВЛЕНИЕ: имя сравнивается как значение; ответ зависит только от того, есть ли такой пользователь
+	res, err := learnerDomain(w, r).SQLDatabase("").Query(r.Context(), "SELECT id FROM users WHERE username = ?", username)
+	sendJSON(w, map[string]interface{}{
+		"status":   "success",
+		"username": username,
+		"exists":   err == nil && len(res.Rows) > 0,
+	})
+}
+
+// Исправление 14: параметризованный запрос статуса заказа
+func apiV1OrdersTrackSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: ID передается параметром, SLEEP в нем - просто строка
+	learnerDomain(w, r).SQLDatabase("").Query(r.Context(), "SELECT status FROM orders WHERE id = ?", r.URL.Query().Get("id"))
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": "Tracking request accepted, updates will be sent by email",
+	})
+}
+
+// Исправление 17: DTD запрещен и при импорте
+func apiV1XmlImportSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1XmlImport(w, r)
+		return
+	}
+	doc, err := parseXML(r.FormValue("xml"), xmlOptions{})
+	if err != nil {
+		sendXMLRejected(w, err)
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Imported %d items", len(doc.Root.find("item"))),
+	})
+}
+
+// Исправление 18: адрес проверяется при подключении, а не по строке URL
+func apiV1LinkPreviewSecure(w http.ResponseWriter, r *http.Request) {
+	sendExternalFetch(w, r, r.URL.Query().Get("url"))
+}
+
+// Исправление 19: каждое перенаправление ведет к новому подключению и проходит ту же проверку
+func apiV1AvatarImportSecure(w http.ResponseWriter, r *http.Request) {
+	sendExternalFetch(w, r, r.URL.Query().Get("url"))
+}
+
+// Исправление 20: проверяется адрес, к которому действительно идет подключение,
+// поэтому повторное разрешение имени ничего не дает
+func apiV1FeedFetchSecure(w http.ResponseWriter, r *http.Request) {
+	sendExternalFetch(w, r, r.URL.Query().Get("url"))
+}
+
+// Исправление 21: логин и пароль - только строки
+func apiV1AccountsLoginSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		nosqlLoginForm(w)
+		return
+	}
+	creds, err := loginCredentials(r)
+	// ИСПРАВЛЕНИЕ: объект вместо строки ({"$ne": "x"}) отклоняется до запроса к базе
+	username, uok := creds["username"].(string)
+	password, pok := creds["password"].(string)
+	if err != nil || !uok || !pok {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "username and password must be strings",
+		})
+		return
+	}
+	found, _ := findDocuments(usersCollection(learnerID(w, r), learnerDomain(w, r)), map[string]interface{}{"username": username})
+	if len(found) == 0 || subtle.ConstantTimeCompare([]byte(found[0]["password"].(string)), []byte(password)) != 1 {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid username or password",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Welcome, %s", username),
+		"user":    found[0].public(),
+	})
+}
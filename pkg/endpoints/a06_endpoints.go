@@ -4,26 +4,41 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // A06:2025 - Insecure Design
 // 10 реалистичных эндпоинтов
 
+// Попытки входа и отправки формы контактов по учащимся
+var (
+	loginBursts        = newHitCounter()
+	contactSubmissions = newHitCounter()
+)
+
 // Уязвимость 1: Отсутствие rate limiting
 func apiV1AuthLoginNoRateLimit(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
-		email := r.FormValue("email")
-		_ = r.FormValue("password") // Не используется, но получаем для демонстрации
-		
-		// УЯЗВИМОСТЬ: Нет ограничения на количество попыток входа
-		sendJSON(w, map[string]interface{}{
-			"status":  "success",
-			"message": fmt.Sprintf("Login attempt for %s processed (no rate limiting!)", email),
-			"warning": "Brute force attack possible",
-		})
+		// УЯЗВИМОСТЬ: Нет ограничения на количество и частоту попыток входа
+		learner := learnerID(w, r)
+		response := map[string]interface{}{}
+		if loginBursts.Hit(learner, time.Second) >= 10 {
+			response["flag"] = challengeFlag(learner, "a06_1")
+		}
+		status := http.StatusOK
+		if user, ok := verifyPassword(domains.Get(learner), r.FormValue("email"), r.FormValue("password")); ok {
+			response["status"] = "success"
+			response["message"] = "Login successful for " + user.Email
+		} else {
+			status = http.StatusUnauthorized
+			response["status"] = "error"
+			response["message"] = "Invalid email or password"
+		}
+		sendJSONStatus(w, status, response)
 		return
 	}
-	
+
 	html := renderPage("Login", `
 		<div class="card">
 			<h2>User Login</h2>
@@ -48,15 +63,19 @@ func apiV1AuthLoginNoRateLimit(w http.ResponseWriter, r *http.Request) {
 func apiV1UsersRegister(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		email := r.FormValue("email")
-		
+
 		// УЯЗВИМОСТЬ: Нет проверки формата email
-		sendJSON(w, map[string]interface{}{
+		response := map[string]interface{}{
 			"status":  "success",
 			"message": fmt.Sprintf("User registered with email: %s (no validation!)", email),
-		})
+		}
+		if !strings.Contains(email, "@") {
+			response["flag"] = labFlag(w, r, "a06_2")
+		}
+		sendJSON(w, response)
 		return
 	}
-	
+
 	html := renderPage("Register", `
 		<div class="card">
 			<h2>Register User</h2>
@@ -77,16 +96,22 @@ func apiV1UsersRegister(w http.ResponseWriter, r *http.Request) {
 func apiV1Contact(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		_ = r.FormValue("message") // Не используется, но получаем для демонстрации
-		
+
 		// УЯЗВИМОСТЬ: Нет CAPTCHA, можно автоматизировать
-		sendJSON(w, map[string]interface{}{
+		learner := learnerID(w, r)
+		response := map[string]interface{}{
 			"status":  "success",
 			"message": "Contact form submitted (no CAPTCHA!)",
 			"warning": "Spam/automation possible",
-		})
+		}
+		// Пять сообщений за минуту - это уже автоматизация
+		if contactSubmissions.Hit(learner, time.Minute) >= 5 {
+			response["flag"] = challengeFlag(learner, "a06_3")
+		}
+		sendJSON(w, response)
 		return
 	}
-	
+
 	html := renderPage("Contact Us", `
 		<div class="card">
 			<h2>Contact Us</h2>
@@ -106,29 +131,59 @@ func apiV1Contact(w http.ResponseWriter, r *http.Request) {
 // Уязвимость 4: Небезопасный дизайн API - опасные действия через GET
 func apiV1UsersDeleteGET(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
-	
+
 	// УЯЗВИМОСТЬ: Удаление через GET запрос
-	sendJSON(w, map[string]interface{}{
+	if !learnerDomain(w, r).DeleteUser(userID) {
+		sendJSON(w, map[string]interface{}{
+			"status":  "error",
+			"message": "User not found",
+		})
+		return
+	}
+	response := map[string]interface{}{
 		"status":  "success",
 		"message": fmt.Sprintf("User %s deleted via GET request (insecure design!)", userID),
 		"warning": "CSRF attack possible",
-	})
+	}
+	if r.Method == "GET" {
+		response["flag"] = labFlag(w, r, "a06_4")
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 5: Отсутствие проверки бизнес-логики
 func apiV1PaymentTransferNoCheck(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		amount, _ := strconv.Atoi(r.FormValue("amount"))
-		
+		toUser := r.FormValue("to_user")
+		if toUser == "" {
+			toUser = "2"
+		}
+
 		// УЯЗВИМОСТЬ: Можно перевести отрицательную сумму или больше баланса
-		sendJSON(w, map[string]interface{}{
+		d := learnerDomain(w, r)
+		before, _ := d.User(labUser.ID)
+		balance, err := d.Transfer(labUser.ID, toUser, amount)
+		if err != nil {
+			sendJSON(w, map[string]interface{}{
+				"status":  "error",
+				"message": "Account not found",
+			})
+			return
+		}
+		response := map[string]interface{}{
 			"status":  "success",
-			"message": fmt.Sprintf("Transfer of %d completed (no balance check!)", amount),
+			"message": fmt.Sprintf("Transfer of %d to user %s completed (no balance check!)", amount, toUser),
+			"balance": balance,
 			"warning": "Negative amounts or amounts exceeding balance are allowed",
-		})
+		}
+		if amount < 0 || amount > before.Balance {
+			response["flag"] = labFlag(w, r, "a06_5")
+		}
+		sendJSON(w, response)
 		return
 	}
-	
+
 	html := renderPage("Transfer Money", `
 		<div class="card">
 			<h2>Transfer Money</h2>
@@ -137,6 +192,10 @@ func apiV1PaymentTransferNoCheck(w http.ResponseWriter, r *http.Request) {
 					<label>Amount</label>
 					<input type="number" name="amount" value="-1000">
 				</div>
+				<div class="form-group">
+					<label>To User ID</label>
+					<input type="text" name="to_user" value="2">
+				</div>
 				<button type="submit" class="btn">Transfer</button>
 			</form>
 		</div>
@@ -149,23 +208,37 @@ func apiV1PaymentTransferNoCheck(w http.ResponseWriter, r *http.Request) {
 func apiV1UsersPasswordWeak(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		password := r.FormValue("password")
-		
+
+		if password == "" {
+			sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+				"status":  "error",
+				"message": "Password required",
+			})
+			return
+		}
+
 		// УЯЗВИМОСТЬ: Нет требований к сложности пароля
-		sendJSON(w, map[string]interface{}{
+		learnerDomain(w, r).SetPassword(labUser.ID, password)
+		response := map[string]interface{}{
 			"status":  "success",
 			"message": fmt.Sprintf("Password '%s' accepted (no complexity requirements!)", password),
 			"warning": "Weak passwords allowed",
-		})
+		}
+		// Пароль учетной записи теперь находится перебором по общедоступному словарю
+		if inWordlist("passwords.txt", password) {
+			response["flag"] = labFlag(w, r, "a06_6")
+		}
+		sendJSON(w, response)
 		return
 	}
-	
+
 	html := renderPage("Change Password", `
 		<div class="card">
 			<h2>Change Password</h2>
 			<form method="POST">
 				<div class="form-group">
 					<label>New Password</label>
-					<input type="password" name="password" value="123">
+					<input type="password" name="password">
 				</div>
 				<button type="submit" class="btn">Change</button>
 			</form>
@@ -177,65 +250,116 @@ func apiV1UsersPasswordWeak(w http.ResponseWriter, r *http.Request) {
 
 // Уязвимость 7: Отсутствие 2FA
 func apiV1AuthVerifyNo2FA(w http.ResponseWriter, r *http.Request) {
-	// УЯЗВИМОСТЬ: Нет двухфакторной аутентификации
-	sendJSON(w, map[string]interface{}{
-		"status":  "success",
-		"message": "Authentication successful (no 2FA required!)",
-		"warning": "Single factor authentication only",
-	})
+	// УЯЗВИМОСТЬ: страница пускает вход, который прошел только пароль - второй шаг
+	// можно пропустить или отметить пройденным в cookie (ошибки skip_step и client_flag)
+	user, viaPassword, ok := labMFA.Account(w, r)
+	if !ok {
+		return
+	}
+	response := map[string]interface{}{
+		"status": "success",
+		"user":   user.Username,
+		"email":  user.Email,
+		"role":   user.Role,
+	}
+	if viaPassword {
+		response["flag"] = labFlag(w, r, "a06_7")
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 8: Небезопасный дизайн сессий
 func apiV1SessionCreateInsecure(w http.ResponseWriter, r *http.Request) {
-	// УЯЗВИМОСТЬ: Сессия не истекает и не привязана к IP
-	sendJSON(w, map[string]interface{}{
-		"status":      "success",
-		"session_id":  "abc123def456",
-		"expires":     "never",
-		"ip_check":    "disabled",
-		"warning":     "Session never expires and not bound to IP",
-	})
+	// УЯЗВИМОСТЬ: Сессия не истекает и не привязана к IP (ошибки no_expiry и any_ip)
+	session, err := labSessions.Create(w, r)
+	if err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{
+			"status":  "error",
+			"message": "Internal server error",
+		})
+		return
+	}
+	d := learnerDomain(w, r)
+	response := map[string]interface{}{
+		"status":     "success",
+		"session_id": session.ID,
+		"expires":    sessionExpiry(session),
+		"ip_check":   "enabled",
+	}
+	if sessionFlaws.On(d, sessionFlawAnyIP) {
+		response["ip_check"] = "disabled"
+	}
+	if session.Expires.IsZero() && sessionFlaws.On(d, sessionFlawAnyIP) {
+		response["warning"] = "Session never expires and not bound to IP"
+		response["flag"] = labFlag(w, r, "a06_8")
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 9: Отсутствие аудита безопасности
 func apiV1AdminAction(w http.ResponseWriter, r *http.Request) {
 	action := r.URL.Query().Get("action")
-	
+
 	// УЯЗВИМОСТЬ: Критические действия не логируются
-	sendJSON(w, map[string]interface{}{
+	message, changed, err := adminAction(learnerDomain(w, r), action, r.URL.Query().Get("user_id"))
+	if err != nil {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
 		"status":  "success",
-		"message": fmt.Sprintf("Action '%s' executed (no audit log!)", action),
+		"message": message + " (no audit log!)",
 		"warning": "Security events not logged",
-	})
+	}
+	// Действие изменило данные, а в журнале аудита о нем нет ни строки
+	if changed {
+		response["flag"] = labFlag(w, r, "a06_9")
+	}
+	sendJSON(w, response)
+}
+
+// Выполнить действие администратора над данными учащегося; changed - данные изменились
+func adminAction(d *domain, action, userID string) (message string, changed bool, err error) {
+	switch action {
+	case "list":
+		var users []string
+		for _, u := range d.Users() {
+			users = append(users, u.ID+":"+u.Username)
+		}
+		return "Users: " + strings.Join(users, ", "), false, nil
+	case "delete":
+		if !d.DeleteUser(userID) {
+			return "", false, fmt.Errorf("user %q not found", userID)
+		}
+		return fmt.Sprintf("User %s deleted", userID), true, nil
+	case "":
+		return "", false, fmt.Errorf("action required")
+	}
+	return "", false, fmt.Errorf("unknown action %q", action)
 }
 
 // Уязвимость 10: Небезопасное восстановление пароля
 func apiV1PasswordResetInsecure(w http.ResponseWriter, r *http.Request) {
-	if r.Method == "POST" {
-		email := r.FormValue("email")
-		
-		// УЯЗВИМОСТЬ: Пароль отправляется сразу без проверки владельца email
-		sendJSON(w, map[string]interface{}{
-			"status":  "success",
-			"message": fmt.Sprintf("Password reset link sent to %s (no verification!)", email),
-			"warning": "Account takeover possible",
-		})
+	if r.Method != "POST" {
+		passwordResetPage(w, r, "victim@example.com")
 		return
 	}
-	
-	html := renderPage("Reset Password", `
-		<div class="card">
-			<h2>Reset Password</h2>
-			<form method="POST">
-				<div class="form-group">
-					<label>Email</label>
-					<input type="email" name="email" value="victim@example.com">
-				</div>
-				<button type="submit" class="btn">Reset</button>
-			</form>
-		</div>
-	`)
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(html))
+	if r.FormValue("token") == "" {
+		// УЯЗВИМОСТЬ: ссылка в письме строится из Host и X-Forwarded-Host (ошибка host_header)
+		labReset.Request(w, r)
+		return
+	}
+	t, _, ok := labReset.Confirm(w, r)
+	if !ok {
+		return
+	}
+	d := learnerDomain(w, r)
+	response := passwordResetDone(d, t)
+	if t.UserID != labUser.ID && resetTokenLeaked(d, t.Token) {
+		response["flag"] = labFlag(w, r, "a06_10")
+	}
+	sendJSON(w, response)
 }
-
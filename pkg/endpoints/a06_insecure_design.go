@@ -78,19 +78,19 @@ func a06InsecureDesign(w http.ResponseWriter, r *http.Request) {
 		<button>Восстановить пароль</button>
 	</form>
 	`
-	
+
 	vuln := r.URL.Query().Get("vuln")
 	if r.Method == "POST" {
 		vuln = r.FormValue("vuln")
 	}
-	
+
 	// Уязвимость 1: Отсутствие rate limiting
 	if vuln == "1" {
 		// Нет ограничений на количество запросов
 		w.Write([]byte("Запрос обработан! Можно отправлять тысячи запросов в секунду (DoS возможен!)"))
 		return
 	}
-	
+
 	// Уязвимость 2: Слабая валидация
 	if vuln == "2" {
 		email := r.URL.Query().Get("email")
@@ -98,14 +98,14 @@ func a06InsecureDesign(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Email '%s' принят без проверки!", email)))
 		return
 	}
-	
+
 	// Уязвимость 3: Отсутствие проверки CAPTCHA
 	if vuln == "3" {
 		// Нет CAPTCHA, можно автоматизировать
 		w.Write([]byte("Аккаунт создан без проверки CAPTCHA! Боты могут создавать тысячи аккаунтов!"))
 		return
 	}
-	
+
 	// Уязвимость 4: Небезопасный дизайн API
 	if vuln == "4" {
 		action := r.URL.Query().Get("action")
@@ -113,7 +113,7 @@ func a06InsecureDesign(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Действие '%s' выполнено без дополнительных проверок!", action)))
 		return
 	}
-	
+
 	// Уязвимость 5: Отсутствие проверки бизнес-логики
 	if vuln == "5" {
 		amount := r.FormValue("amount")
@@ -121,7 +121,7 @@ func a06InsecureDesign(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Перевод %s выполнен без проверки баланса и валидности суммы!", amount)))
 		return
 	}
-	
+
 	// Уязвимость 6: Небезопасный дизайн паролей
 	if vuln == "6" {
 		pass := r.FormValue("pass")
@@ -130,7 +130,7 @@ func a06InsecureDesign(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Пароль '%s' установлен! Нет проверки на сложность!", pass)))
 		return
 	}
-	
+
 	// Уязвимость 7: Отсутствие многофакторной аутентификации
 	if vuln == "7" {
 		user := r.FormValue("user")
@@ -139,20 +139,20 @@ func a06InsecureDesign(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Вход выполнен для %s без двухфакторной аутентификации!", user)))
 		return
 	}
-	
+
 	// Уязвимость 8: Небезопасный дизайн сессий
 	if vuln == "8" {
 		// Сессия не истекает, нет проверки IP
 		w.Write([]byte("Сессия создана навсегда без проверки IP и времени жизни!"))
 		return
 	}
-	
+
 	// Уязвимость 9: Отсутствие аудита безопасности
 	if vuln == "9" {
 		w.Write([]byte("Аудит безопасности не настроен! Нет логирования критических действий!"))
 		return
 	}
-	
+
 	// Уязвимость 10: Небезопасный дизайн восстановления пароля
 	if vuln == "10" {
 		email := r.FormValue("email")
@@ -160,8 +160,7 @@ func a06InsecureDesign(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Новый пароль отправлен на %s без проверки владельца!", email)))
 		return
 	}
-	
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
 }
-
@@ -0,0 +1,278 @@
+package endpoints
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A06:2025 - Insecure Design
+// Исправленные версии эндпоинтов (режим secure)
+
+// Отправки формы контактов в режиме secure
+var secureContactPosts = newHitCounter()
+
+// Ответ 429 с указанием, когда можно повторить запрос
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+	sendJSONStatus(w, http.StatusTooManyRequests, map[string]interface{}{
+		"status":  "error",
+		"message": "Too many requests, try again later",
+	})
+}
+
+// Исправление 1: ограничение числа попыток входа
+func apiV1AuthLoginNoRateLimitSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1AuthLoginNoRateLimit(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: неудачные попытки считаются по IP и учетной записи, IP получает
+	// растущую задержку, учетная запись блокируется после 5 ошибок
+	user, _, ok := secureLoginGuard.Login(w, r, r.FormValue("email"), r.FormValue("password"))
+	if !ok {
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": "Login successful for " + user.Email,
+	})
+}
+
+// Исправление 2: формат email проверяется
+func apiV1UsersRegisterSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1UsersRegister(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: адрес разбирается по RFC 5322, имя отправителя и лишний текст не допускаются
+	email := r.FormValue("email")
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || !strings.Contains(addr.Address[strings.LastIndex(addr.Address, "@"):], ".") {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid email address",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": "Confirmation email sent to " + addr.Address,
+	})
+}
+
+// Исправление 3: ограничение частоты отправки формы
+func apiV1ContactSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1Contact(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: не больше 3 сообщений в минуту; при превышении нужна CAPTCHA
+	if secureContactPosts.Hit(learnerID(w, r), time.Minute) > 3 {
+		tooManyRequests(w, time.Minute)
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": "Contact form submitted",
+	})
+}
+
+// CSRF токен учащегося: HMAC от его идентификатора
+func csrfToken(learner string) string {
+	mac := hmac.New(sha256.New, serverKey("csrf"))
+	mac.Write([]byte(learner))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Исправление 4: удаление только через POST с CSRF токеном
+func apiV1UsersDeleteGETSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: GET не меняет состояние, POST требует токен, которого нет у чужого сайта
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		sendJSONStatus(w, http.StatusMethodNotAllowed, map[string]interface{}{
+			"status":  "error",
+			"message": "Use POST with a CSRF token",
+		})
+		return
+	}
+	token := r.Header.Get("X-CSRF-Token")
+	if token == "" {
+		token = r.FormValue("csrf_token")
+	}
+	if !hmac.Equal([]byte(token), []byte(csrfToken(learnerID(w, r)))) {
+		sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid CSRF token",
+		})
+		return
+	}
+	userID := r.FormValue("user_id")
+	if !learnerDomain(w, r).DeleteUser(userID) {
+		sendJSONStatus(w, http.StatusNotFound, map[string]interface{}{
+			"status":  "error",
+			"message": "User not found",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("User %s deleted", userID),
+	})
+}
+
+// Исправление 5: сумма перевода проверяется
+func apiV1PaymentTransferNoCheckSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1PaymentTransferNoCheck(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: сумма положительная и не больше баланса
+	amount, err := strconv.Atoi(r.FormValue("amount"))
+	if err != nil || amount <= 0 {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Amount must be a positive number",
+		})
+		return
+	}
+	toUser := r.FormValue("to_user")
+	if toUser == "" {
+		toUser = "2"
+	}
+	balance, err := learnerDomain(w, r).TransferFunds(labUser.ID, toUser, amount)
+	switch {
+	case errors.Is(err, errInsufficientFunds):
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": fmt.Sprintf("Amount must be between 1 and %d", balance),
+		})
+		return
+	case err != nil:
+		sendJSONStatus(w, http.StatusNotFound, map[string]interface{}{
+			"status":  "error",
+			"message": "Account not found",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Transfer of %d completed", amount),
+		"balance": balance,
+	})
+}
+
+// Самые распространенные пароли из утечек
+var commonPasswords = map[string]bool{
+	"123456789012": true, "password1234": true, "qwertyuiop12": true,
+	"passwordpassword": true, "111111111111": true, "administrator": true,
+}
+
+// Исправление 6: требования к длине и проверка по списку распространенных паролей
+func apiV1UsersPasswordWeakSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1UsersPasswordWeak(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: не короче 12 символов (NIST SP 800-63B) и не из словаря; пароль не возвращается
+	password := r.FormValue("password")
+	if len([]rune(password)) < 12 || commonPasswords[strings.ToLower(password)] {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Password must be at least 12 characters and not a common password",
+		})
+		return
+	}
+	learnerDomain(w, r).SetPassword(labUser.ID, password)
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": "Password changed",
+	})
+}
+
+// Исправление 7: после пароля требуется второй фактор
+func apiV1AuthVerifyNo2FASecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: доступ только по сессии, которую выдает второй шаг
+	user, _, ok := secureMFA.Account(w, r)
+	if !ok {
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"user":   user.Username,
+		"email":  user.Email,
+		"role":   user.Role,
+	})
+}
+
+// Исправление 8: сессия со сроком жизни и привязкой к IP
+func apiV1SessionCreateInsecureSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: случайный ID в HttpOnly cookie, истекает через 30 минут
+	session, err := secureSessions.Create(w, r)
+	if err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{
+			"status":  "error",
+			"message": "Internal server error",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":   "success",
+		"expires":  session.Expires.Format(time.RFC3339),
+		"ip_check": "enabled",
+	})
+}
+
+// Исправление 9: критические действия требуют роль и записываются в журнал аудита
+func apiV1AdminActionSecure(w http.ResponseWriter, r *http.Request) {
+	action := r.URL.Query().Get("action")
+	// ИСПРАВЛЕНИЕ: каждое действие, в том числе отклоненное, попадает в журнал
+	if sessionRole(r) != "admin" {
+		log.Printf("[AUDIT] denied admin action %q from %s", action, clientIP(r))
+		sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": "Admin access required",
+		})
+		return
+	}
+	message, _, err := adminAction(learnerDomain(w, r), action, r.URL.Query().Get("user_id"))
+	if err != nil {
+		log.Printf("[AUDIT] failed admin action %q from %s: %v", action, clientIP(r), err)
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	log.Printf("[AUDIT] admin action %q from %s: %s", action, clientIP(r), message)
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": message + ", audited",
+	})
+}
+
+// Исправление 10: ссылка для сброса уходит только владельцу адреса
+func apiV1PasswordResetInsecureSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		passwordResetPage(w, r, "victim@example.com")
+		return
+	}
+	if r.FormValue("token") == "" {
+		// ИСПРАВЛЕНИЕ: адрес сайта в ссылке не зависит от заголовков запроса
+		secureReset.Request(w, r)
+		return
+	}
+	t, _, ok := secureReset.Confirm(w, r)
+	if !ok {
+		return
+	}
+	sendJSON(w, passwordResetDone(learnerDomain(w, r), t))
+}
@@ -88,12 +88,12 @@ func a07AuthenticationFailures(w http.ResponseWriter, r *http.Request) {
 		<button>Войти (логируются в открытом виде!)</button>
 	</form>
 	`
-	
+
 	vuln := r.URL.Query().Get("vuln")
 	if r.Method == "POST" {
 		vuln = r.FormValue("vuln")
 	}
-	
+
 	// Уязвимость 1: Слабые пароли по умолчанию
 	if vuln == "1" {
 		user := r.FormValue("user")
@@ -105,7 +105,7 @@ func a07AuthenticationFailures(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	
+
 	// Уязвимость 2: Отсутствие блокировки после неудачных попыток
 	if vuln == "2" {
 		user := r.FormValue("user")
@@ -118,7 +118,7 @@ func a07AuthenticationFailures(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	
+
 	// Уязвимость 3: Пароли в открытом виде
 	if vuln == "3" {
 		user := r.URL.Query().Get("user")
@@ -127,7 +127,7 @@ func a07AuthenticationFailures(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	
+
 	// Уязвимость 4: Слабая проверка сессии
 	if vuln == "4" {
 		session := r.URL.Query().Get("session")
@@ -137,14 +137,14 @@ func a07AuthenticationFailures(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	
+
 	// Уязвимость 5: Отсутствие проверки времени сессии
 	if vuln == "5" {
 		// Сессия никогда не истекает
 		w.Write([]byte("Сессия активна навсегда! Нет проверки времени жизни!"))
 		return
 	}
-	
+
 	// Уязвимость 6: Небезопасное восстановление пароля
 	if vuln == "6" {
 		user := r.FormValue("user")
@@ -154,7 +154,7 @@ func a07AuthenticationFailures(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	
+
 	// Уязвимость 7: Отсутствие многофакторной аутентификации
 	if vuln == "7" {
 		user := r.FormValue("user")
@@ -164,7 +164,7 @@ func a07AuthenticationFailures(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	
+
 	// Уязвимость 8: Подделка сессий
 	if vuln == "8" {
 		sid := r.URL.Query().Get("sid")
@@ -176,14 +176,14 @@ func a07AuthenticationFailures(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	
+
 	// Уязвимость 9: Отсутствие проверки IP адреса
 	if vuln == "9" {
 		// IP не проверяется, можно использовать сессию с другого IP
 		w.Write([]byte("Сессия валидна с любого IP адреса!"))
 		return
 	}
-	
+
 	// Уязвимость 10: Утечка учетных данных
 	if vuln == "10" {
 		user := r.FormValue("user")
@@ -193,8 +193,7 @@ func a07AuthenticationFailures(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Учетные данные логируются в открытом виде! user=%s, pass=%s", user, pass)))
 		return
 	}
-	
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
 }
-
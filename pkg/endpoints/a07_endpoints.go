@@ -3,28 +3,26 @@ package endpoints
 import (
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // A07:2025 - Authentication Failures
 // 10 реалистичных эндпоинтов
 
-var userDB = map[string]string{
-	"admin@company.com": "admin123",
-	"user@company.com": "password",
-}
-
 // Уязвимость 1: Слабые пароли по умолчанию
 func apiV1AuthDefaultLogin(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		email := r.FormValue("email")
 		password := r.FormValue("password")
-		
+
 		// УЯЗВИМОСТЬ: Пароли по умолчанию не изменены
-		if userDB[email] == password {
+		if user, ok := learnerDomain(w, r).UserByEmail(email); ok && user.Password == password {
 			sendJSON(w, map[string]interface{}{
 				"status":  "success",
 				"message": fmt.Sprintf("Login successful for %s (default password not changed!)", email),
 				"warning": "Default credentials still active",
+				"role":    user.Role,
+				"flag":    labFlag(w, r, "a07_1"),
 			})
 		} else {
 			sendJSON(w, map[string]interface{}{
@@ -34,7 +32,7 @@ func apiV1AuthDefaultLogin(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	
+
 	html := renderPage("Login (Default Credentials)", `
 		<div class="card">
 			<h2>Login</h2>
@@ -59,24 +57,25 @@ func apiV1AuthDefaultLogin(w http.ResponseWriter, r *http.Request) {
 // Уязвимость 2: Отсутствие блокировки после неудачных попыток
 func apiV1AuthBruteforce(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
-		email := r.FormValue("email")
-		password := r.FormValue("password")
-		
-		// УЯЗВИМОСТЬ: Нет блокировки, можно брутфорсить
-		if userDB[email] == password {
-			sendJSON(w, map[string]interface{}{
-				"status": "success",
-				"message": "Login successful",
-			})
-		} else {
-			sendJSON(w, map[string]interface{}{
-				"status":  "error",
-				"message": "Invalid credentials (unlimited attempts allowed!)",
-			})
+		// УЯЗВИМОСТЬ: блокировка обходится - X-Forwarded-For, регистр email, сброс счетчика
+		// чужим входом и гонка параллельных запросов (ошибки labLoginGuard)
+		user, bypassed, ok := labLoginGuard.Login(w, r, r.FormValue("email"), r.FormValue("password"))
+		if !ok {
+			return
+		}
+		response := map[string]interface{}{
+			"status":  "success",
+			"message": "Login successful",
+			"user":    user.Username,
 		}
+		// Флаг - за пароль, подобранный в обход блокировки
+		if bypassed {
+			response["flag"] = labFlag(w, r, "a07_2")
+		}
+		sendJSON(w, response)
 		return
 	}
-	
+
 	html := renderPage("Login (No Rate Limit)", `
 		<div class="card">
 			<h2>Login</h2>
@@ -100,18 +99,14 @@ func apiV1AuthBruteforce(w http.ResponseWriter, r *http.Request) {
 // Уязвимость 3: Пароли в открытом виде в базе
 func apiV1UsersPasswordDB(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
-	
+
 	// УЯЗВИМОСТЬ: Пароли хранятся в открытом виде
-	passwords := map[string]string{
-		"1": "password123",
-		"2": "admin456",
-	}
-	
-	if pass, ok := passwords[userID]; ok {
+	if user, ok := learnerDomain(w, r).User(userID); ok {
 		sendJSON(w, map[string]interface{}{
 			"status":   "success",
 			"user_id":  userID,
-			"password": pass,
+			"password": user.Password,
+			"flag":     labFlag(w, r, "a07_3"),
 		})
 	} else {
 		sendJSON(w, map[string]interface{}{
@@ -124,92 +119,155 @@ func apiV1UsersPasswordDB(w http.ResponseWriter, r *http.Request) {
 // Уязвимость 4: Слабая проверка сессии
 func apiV1SessionVerify(w http.ResponseWriter, r *http.Request) {
 	sessionID := r.URL.Query().Get("session_id")
-	
-	// УЯЗВИМОСТЬ: Любая строка принимается как валидная сессия
-	if len(sessionID) > 0 {
-		sendJSON(w, map[string]interface{}{
-			"status":    "success",
-			"session_id": sessionID,
-			"message":   "Session verified (no actual validation!)",
-		})
-	} else {
+	if sessionID == "" {
 		sendJSON(w, map[string]interface{}{
 			"status":  "error",
 			"message": "Session ID required",
 		})
+		return
+	}
+
+	// УЯЗВИМОСТЬ: ID сессий угадываются (predictable_id), а проверка принимает бессрочные
+	// сессии (no_expiry) и сессии чужих IP (any_ip)
+	session, ok := labSessions.Lookup(r, sessionID)
+	if !ok {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid or expired session",
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"status":     "success",
+		"session_id": session.ID,
+		"user_id":    session.UserID,
+		"role":       session.Role,
+		"expires":    sessionExpiry(session),
 	}
+	if session.Role == "admin" {
+		response["flag"] = labFlag(w, r, "a07_4")
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 5: Сессия никогда не истекает
 func apiV1SessionInfo(w http.ResponseWriter, r *http.Request) {
-	// УЯЗВИМОСТЬ: Сессия активна навсегда
-	sendJSON(w, map[string]interface{}{
+	session, ok := labSessions.FromRequest(r)
+	if !ok {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "No active session",
+		})
+		return
+	}
+	response := map[string]interface{}{
 		"status":     "active",
-		"expires_at": "never",
-		"created_at": "2024-01-01T00:00:00Z",
-		"warning":    "Session never expires",
-	})
+		"user_id":    session.UserID,
+		"created_at": session.Created.Format(time.RFC3339),
+		"expires_at": sessionExpiry(session),
+	}
+	// УЯЗВИМОСТЬ: Сессия активна навсегда (ошибка no_expiry)
+	if session.Expires.IsZero() {
+		response["warning"] = "Session never expires"
+		response["flag"] = labFlag(w, r, "a07_5")
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 6: Небезопасное восстановление пароля
 func apiV1PasswordResetAuth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		passwordResetPage(w, r, "user@company.com")
+		return
+	}
+	if r.FormValue("token") == "" {
+		// УЯЗВИМОСТЬ: токены - порядковые номера, а ответ выдает, есть ли учетная запись
+		// (ошибки predictable_token и enumeration)
+		labReset.Request(w, r)
+		return
+	}
+	// УЯЗВИМОСТЬ: токен действует после использования и смены пароля (ошибка no_invalidation)
+	t, reused, ok := labReset.Confirm(w, r)
+	if !ok {
+		return
+	}
+	d := learnerDomain(w, r)
+	response := passwordResetDone(d, t)
+	// Токен чужой учетной записи, который не уходил на сервер учащегося, - подобран
+	guessed := t.UserID != labUser.ID && !resetTokenLeaked(d, t.Token)
+	if guessed || reused {
+		response["flag"] = labFlag(w, r, "a07_6")
+	}
+	sendJSON(w, response)
+}
+
+// Уязвимость 7: Слабый второй фактор
+func apiV1AuthLoginNo2FA(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
-		email := r.FormValue("email")
-		
-		// УЯЗВИМОСТЬ: Новый пароль отправляется сразу без проверки
-		if pass, ok := userDB[email]; ok {
-			sendJSON(w, map[string]interface{}{
-				"status":  "success",
-				"message": fmt.Sprintf("New password sent to %s: %s (no verification!)", email, pass),
-			})
-		} else {
-			sendJSON(w, map[string]interface{}{
-				"status":  "error",
-				"message": "User not found",
-			})
-		}
+		labMFA.Login(w, r)
+		return
+	}
+	mfaLoginPage(w)
+}
+
+func apiV1AuthMFAVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		mfaVerifyPage(w)
+		return
+	}
+	// УЯЗВИМОСТЬ: код можно использовать повторно и перебирать без ограничений
+	// (ошибки otp_reuse и no_attempt_limit)
+	user, bypassed, ok := labMFA.Verify(w, r)
+	if !ok {
 		return
 	}
-	
-	html := renderPage("Reset Password", `
+	response := map[string]interface{}{
+		"status": "success",
+		"user":   user.Username,
+		"role":   user.Role,
+	}
+	if bypassed {
+		response["flag"] = labFlag(w, r, "a07_7")
+	}
+	sendJSON(w, response)
+}
+
+func apiV1AuthMFAEnroll(w http.ResponseWriter, r *http.Request) {
+	labMFA.Enroll(w, r)
+}
+
+func mfaLoginPage(w http.ResponseWriter) {
+	html := renderPage("Login", `
 		<div class="card">
-			<h2>Reset Password</h2>
+			<h2>Login</h2>
 			<form method="POST">
 				<div class="form-group">
 					<label>Email</label>
 					<input type="email" name="email" value="user@company.com">
 				</div>
-				<button type="submit" class="btn">Reset</button>
+				<div class="form-group">
+					<label>Password</label>
+					<input type="password" name="password">
+				</div>
+				<button type="submit" class="btn">Login</button>
 			</form>
+			<p>Accounts with two-factor authentication continue at <a href="/api/v1/auth/mfa/verify">/api/v1/auth/mfa/verify</a></p>
 		</div>
 	`)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
 }
 
-// Уязвимость 7: Отсутствие 2FA
-func apiV1AuthLoginNo2FA(w http.ResponseWriter, r *http.Request) {
-	if r.Method == "POST" {
-		email := r.FormValue("email")
-		
-		// УЯЗВИМОСТЬ: Вход без двухфакторной аутентификации
-		sendJSON(w, map[string]interface{}{
-			"status":  "success",
-			"message": fmt.Sprintf("Login successful for %s (no 2FA required!)", email),
-			"token":   "jwt_token_abc123",
-		})
-		return
-	}
-	
-	html := renderPage("Login (No 2FA)", `
+func mfaVerifyPage(w http.ResponseWriter) {
+	html := renderPage("Two-Factor Authentication", `
 		<div class="card">
-			<h2>Login</h2>
+			<h2>Enter Code</h2>
 			<form method="POST">
 				<div class="form-group">
-					<label>Email</label>
-					<input type="email" name="email" value="user@company.com">
+					<label>Code from your authenticator app or recovery code</label>
+					<input type="text" name="code" autocomplete="one-time-code">
 				</div>
-				<button type="submit" class="btn">Login</button>
+				<button type="submit" class="btn">Verify</button>
 			</form>
 		</div>
 	`)
@@ -219,32 +277,66 @@ func apiV1AuthLoginNo2FA(w http.ResponseWriter, r *http.Request) {
 
 // Уязвимость 8: Подделка сессий
 func apiV1SessionCreateForgery(w http.ResponseWriter, r *http.Request) {
-	sessionID := r.URL.Query().Get("session_id")
-	
-	// УЯЗВИМОСТЬ: Можно подделать сессию, зная формат
-	if sessionID == "admin_session_123" {
-		sendJSON(w, map[string]interface{}{
-			"status":  "success",
-			"message": "Admin session created (session ID predictable!)",
-			"role":    "admin",
-		})
-	} else {
-		sendJSON(w, map[string]interface{}{
-			"status":  "success",
-			"message": "User session created",
-			"role":    "user",
+	// УЯЗВИМОСТЬ: ID сессии задает клиент (fixation). Если сессия с таким ID уже есть,
+	// клиент просто присоединяется к ней, а ID сессий предсказуемы
+	session, err := labSessions.Create(w, r)
+	if err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{
+			"status":  "error",
+			"message": "Internal server error",
 		})
+		return
+	}
+	response := map[string]interface{}{
+		"status":     "success",
+		"message":    "User session created",
+		"session_id": session.ID,
+		"user_id":    session.UserID,
+		"role":       session.Role,
+	}
+	if session.Role == "admin" {
+		response["message"] = "Admin session created"
+		response["flag"] = labFlag(w, r, "a07_8")
 	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 9: Отсутствие проверки IP адреса
 func apiV1SessionValidate(w http.ResponseWriter, r *http.Request) {
-	// УЯЗВИМОСТЬ: Сессия валидна с любого IP
+	// УЯЗВИМОСТЬ: Сессия валидна с любого IP (ошибка any_ip)
+	session, ok := labSessions.FromRequest(r)
+	if !ok {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "invalid",
+			"message": "Session is missing or expired",
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"status":     "valid",
+		"user_id":    session.UserID,
+		"session_ip": session.IP,
+		"client_ip":  clientIP(r),
+	}
+	if session.IP != clientIP(r) {
+		response["warning"] = "Session used from another IP address"
+		response["flag"] = labFlag(w, r, "a07_9")
+	}
+	sendJSON(w, response)
+}
+
+// Вход с выдачей cookie-сессии. Администраторы входят через SSO
+func apiV1SessionLogin(w http.ResponseWriter, r *http.Request) {
+	session, rotated, ok := sessionLogin(w, r, labSessions)
+	if !ok {
+		return
+	}
+	// УЯЗВИМОСТЬ: сессия, полученная до входа, остается действующей (ошибка no_rotation)
 	sendJSON(w, map[string]interface{}{
-		"status":    "valid",
-		"ip_check":  "disabled",
-		"message":   "Session valid from any IP address",
-		"warning":   "Session hijacking possible",
+		"status":     "success",
+		"session_id": session.ID,
+		"user_id":    session.UserID,
+		"rotated":    rotated,
 	})
 }
 
@@ -253,17 +345,19 @@ func apiV1AuthLoginLog(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		email := r.FormValue("email")
 		password := r.FormValue("password")
-		
+
 		// УЯЗВИМОСТЬ: Учетные данные логируются в открытом виде
 		fmt.Printf("[LOG] Login attempt - email: %s, password: %s\n", email, password)
-		
+
 		sendJSON(w, map[string]interface{}{
 			"status":  "success",
 			"message": "Login processed (credentials logged in plain text!)",
+			"log":     fmt.Sprintf("[LOG] Login attempt - email: %s, password: %s", email, password),
+			"flag":    labFlag(w, r, "a07_10"),
 		})
 		return
 	}
-	
+
 	html := renderPage("Login (Credentials Logged)", `
 		<div class="card">
 			<h2>Login</h2>
@@ -283,4 +377,3 @@ func apiV1AuthLoginLog(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
 }
-
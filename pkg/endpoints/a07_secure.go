@@ -0,0 +1,217 @@
+package endpoints
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// A07:2025 - Authentication Failures
+// Исправленные версии эндпоинтов (режим secure)
+
+// Исправление 1: пароль по умолчанию нужно сменить до первого входа
+func apiV1AuthDefaultLoginSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1AuthDefaultLogin(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: учетная запись с заводским паролем не получает сессию
+	if user, ok := learnerDomain(w, r).UserByEmail(r.FormValue("email")); ok && user.Password == r.FormValue("password") {
+		sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": "Default password must be changed before first login",
+		})
+		return
+	}
+	sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+		"status":  "error",
+		"message": "Invalid credentials",
+	})
+}
+
+// Исправление 2: учетная запись блокируется после серии неудачных попыток
+func apiV1AuthBruteforceSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1AuthBruteforce(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: после 5 ошибок учетная запись заблокирована на 15 минут даже для верного
+	// пароля, IP получает растущую задержку; счетчики по адресу соединения и email
+	// в нижнем регистре, попытка учитывается до проверки пароля
+	if _, _, ok := secureLoginGuard.Login(w, r, r.FormValue("email"), r.FormValue("password")); !ok {
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": "Login successful",
+	})
+}
+
+// Исправление 3: пароли хранятся только в виде хеша
+func apiV1UsersPasswordDBSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: в базе PBKDF2-хеш, API не отдает ни пароль, ни хеш
+	sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+		"status":  "error",
+		"message": "Passwords are not retrievable",
+	})
+}
+
+// Исправление 4: сессия ищется в хранилище сессий сервера
+func apiV1SessionVerifySecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: ID должен существовать, не истечь и принадлежать этому IP
+	session, ok := secureSessions.Lookup(r, r.URL.Query().Get("session_id"))
+	if !ok {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid or expired session",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"user_id": session.UserID,
+		"expires": session.Expires.Format(time.RFC3339),
+	})
+}
+
+// Исправление 5: у сессии есть срок жизни
+func apiV1SessionInfoSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: информация о сессии из cookie, истекшая сессия не действует
+	session, ok := secureSessions.FromRequest(r)
+	if !ok {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "No active session",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":     "active",
+		"created_at": session.Created.Format(time.RFC3339),
+		"expires_at": session.Expires.Format(time.RFC3339),
+	})
+}
+
+// Исправление 6: пароль не отправляется, только одноразовая ссылка владельцу адреса
+func apiV1PasswordResetAuthSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		passwordResetPage(w, r, "user@company.com")
+		return
+	}
+	if r.FormValue("token") == "" {
+		// ИСПРАВЛЕНИЕ: случайный токен, ответ одинаков для существующих и несуществующих адресов
+		secureReset.Request(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: после смены пароля все токены пользователя недействительны
+	t, _, ok := secureReset.Confirm(w, r)
+	if !ok {
+		return
+	}
+	sendJSON(w, passwordResetDone(learnerDomain(w, r), t))
+}
+
+// Исправление 7: токен выдается только после второго фактора
+func apiV1AuthLoginNo2FASecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		mfaLoginPage(w)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: после пароля - запрос одноразового кода, сессии до него нет
+	secureMFA.Login(w, r)
+}
+
+func apiV1AuthMFAVerifySecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		mfaVerifyPage(w)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: код действует один раз, после 5 ошибок вход начинается заново
+	user, _, ok := secureMFA.Verify(w, r)
+	if !ok {
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"user":   user.Username,
+		"role":   user.Role,
+	})
+}
+
+func apiV1AuthMFAEnrollSecure(w http.ResponseWriter, r *http.Request) {
+	secureMFA.Enroll(w, r)
+}
+
+// Исправление 8: ID сессии генерирует сервер
+func apiV1SessionCreateForgerySecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: session_id из запроса игнорируется, роль берется из учетной записи
+	session, err := secureSessions.Create(w, r)
+	if err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{
+			"status":  "error",
+			"message": "Internal server error",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": "User session created",
+		"role":    session.Role,
+	})
+}
+
+// Исправление 9: сессия действует только с IP, на котором создана
+func apiV1SessionValidateSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: Lookup сверяет IP запроса с IP сессии
+	if _, ok := secureSessions.FromRequest(r); !ok {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "invalid",
+			"message": "Session is missing, expired or bound to another IP",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":   "valid",
+		"ip_check": "enabled",
+	})
+}
+
+// Исправление входа: новый ID сессии после входа
+func apiV1SessionLoginSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: старая сессия удаляется, ID выдается заново и только в HttpOnly cookie
+	session, _, ok := sessionLogin(w, r, secureSessions)
+	if !ok {
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"user_id": session.UserID,
+	})
+}
+
+// Исправление 10: учетные данные не попадают в логи
+func apiV1AuthLoginLogSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1AuthLoginLog(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: пароль не логируется, email маскируется
+	line := fmt.Sprintf("[LOG] Login attempt - email: %s", maskEmail(r.FormValue("email")))
+	log.Print(line)
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": "Login processed",
+		"log":     line,
+	})
+}
+
+// Замаскировать email: первая буква имени и домен
+func maskEmail(email string) string {
+	for i := 0; i < len(email); i++ {
+		if email[i] == '@' && i > 0 {
+			return email[:1] + "***" + email[i:]
+		}
+	}
+	return "***"
+}
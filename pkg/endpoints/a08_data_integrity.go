@@ -79,12 +79,12 @@ func a08DataIntegrity(w http.ResponseWriter, r *http.Request) {
 		<button>Проверить файл</button>
 	</form>
 	`
-	
+
 	vuln := r.URL.Query().Get("vuln")
 	if r.Method == "POST" {
 		vuln = r.FormValue("vuln")
 	}
-	
+
 	// Уязвимость 1: Отсутствие проверки подписи
 	if vuln == "1" {
 		file := r.FormValue("file")
@@ -92,7 +92,7 @@ func a08DataIntegrity(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Файл %s загружен без проверки цифровой подписи!", file)))
 		return
 	}
-	
+
 	// Уязвимость 2: Небезопасное обновление
 	if vuln == "2" {
 		version := r.URL.Query().Get("version")
@@ -100,7 +100,7 @@ func a08DataIntegrity(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Обновление до версии %s выполнено без проверки подписи разработчика!", version)))
 		return
 	}
-	
+
 	// Уязвимость 3: Отсутствие проверки целостности данных
 	if vuln == "3" {
 		data := r.FormValue("data")
@@ -108,7 +108,7 @@ func a08DataIntegrity(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Данные '%s' сохранены без проверки целостности (можно изменить!)", data)))
 		return
 	}
-	
+
 	// Уязвимость 4: Небезопасная загрузка зависимостей
 	if vuln == "4" {
 		packageName := r.URL.Query().Get("package")
@@ -116,7 +116,7 @@ func a08DataIntegrity(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Пакет %s установлен без проверки подписи и целостности!", packageName)))
 		return
 	}
-	
+
 	// Уязвимость 5: Отсутствие проверки checksum
 	if vuln == "5" {
 		file := r.FormValue("file")
@@ -124,13 +124,13 @@ func a08DataIntegrity(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Файл %s загружен без проверки checksum (может быть изменен!)", file)))
 		return
 	}
-	
+
 	// Уязвимость 6: Небезопасный CI/CD pipeline
 	if vuln == "6" {
 		w.Write([]byte("CI/CD pipeline настроен без проверки подписи кода! Можно внедрить вредоносный код!"))
 		return
 	}
-	
+
 	// Уязвимость 7: Подмена репозитория
 	if vuln == "7" {
 		repo := r.URL.Query().Get("repo")
@@ -138,7 +138,7 @@ func a08DataIntegrity(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Репозиторий %s клонирован без проверки подписи коммитов!", repo)))
 		return
 	}
-	
+
 	// Уязвимость 8: Отсутствие проверки подписи кода
 	if vuln == "8" {
 		code := r.FormValue("code")
@@ -146,13 +146,13 @@ func a08DataIntegrity(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Код '%s' выполнен без проверки цифровой подписи!", code)))
 		return
 	}
-	
+
 	// Уязвимость 9: Небезопасная цепочка доверия
 	if vuln == "9" {
 		w.Write([]byte("Цепочка доверия не проверяется! Можно использовать поддельные сертификаты!"))
 		return
 	}
-	
+
 	// Уязвимость 10: Отсутствие проверки времени модификации
 	if vuln == "10" {
 		file := r.URL.Query().Get("file")
@@ -160,8 +160,7 @@ func a08DataIntegrity(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Файл %s проверен без учета времени модификации (можно откатить!)", file)))
 		return
 	}
-	
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
 }
-
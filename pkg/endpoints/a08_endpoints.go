@@ -12,16 +12,20 @@ import (
 func apiV1UpdateUpload(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		file := r.FormValue("file")
-		
+
 		// УЯЗВИМОСТЬ: Файл загружается без проверки цифровой подписи
-		sendJSON(w, map[string]interface{}{
+		response := map[string]interface{}{
 			"status":  "success",
 			"message": fmt.Sprintf("File %s uploaded without signature verification", file),
 			"warning": "Malicious code may be executed",
-		})
+		}
+		if file != "" {
+			response["flag"] = labFlag(w, r, "a08_1")
+		}
+		sendJSON(w, response)
 		return
 	}
-	
+
 	html := renderPage("Upload Update", `
 		<div class="card">
 			<h2>Upload Update File</h2>
@@ -41,29 +45,37 @@ func apiV1UpdateUpload(w http.ResponseWriter, r *http.Request) {
 // Уязвимость 2: Обновление без проверки подписи
 func apiV1UpdateInstall(w http.ResponseWriter, r *http.Request) {
 	version := r.URL.Query().Get("version")
-	
+
 	// УЯЗВИМОСТЬ: Обновление выполняется без проверки подписи разработчика
-	sendJSON(w, map[string]interface{}{
+	response := map[string]interface{}{
 		"status":  "success",
 		"message": fmt.Sprintf("Updated to version %s without signature verification", version),
 		"warning": "Compromised update may be installed",
-	})
+	}
+	if version != "" {
+		response["flag"] = labFlag(w, r, "a08_2")
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 3: Данные без проверки целостности
 func apiV1DataSave(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		data := r.FormValue("data")
-		
+
 		// УЯЗВИМОСТЬ: Данные сохраняются без checksum
-		sendJSON(w, map[string]interface{}{
+		response := map[string]interface{}{
 			"status":  "success",
 			"message": fmt.Sprintf("Data saved without integrity check: %s", data),
 			"warning": "Data may be modified without detection",
-		})
+		}
+		if data != "" {
+			response["flag"] = labFlag(w, r, "a08_3")
+		}
+		sendJSON(w, response)
 		return
 	}
-	
+
 	html := renderPage("Save Data", `
 		<div class="card">
 			<h2>Save Data</h2>
@@ -83,29 +95,37 @@ func apiV1DataSave(w http.ResponseWriter, r *http.Request) {
 // Уязвимость 4: Загрузка зависимостей без проверки
 func apiV1DependenciesInstall(w http.ResponseWriter, r *http.Request) {
 	packageName := r.URL.Query().Get("package")
-	
+
 	// УЯЗВИМОСТЬ: Пакет устанавливается без проверки подписи
-	sendJSON(w, map[string]interface{}{
+	response := map[string]interface{}{
 		"status":  "success",
 		"message": fmt.Sprintf("Package %s installed without signature verification", packageName),
 		"warning": "Compromised package may be installed",
-	})
+	}
+	if packageName != "" {
+		response["flag"] = labFlag(w, r, "a08_4")
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 5: Файлы без проверки checksum
 func apiV1FilesUpload(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		file := r.FormValue("file")
-		
+
 		// УЯЗВИМОСТЬ: Файл загружается без проверки SHA256/MD5
-		sendJSON(w, map[string]interface{}{
+		response := map[string]interface{}{
 			"status":  "success",
 			"message": fmt.Sprintf("File %s uploaded without checksum verification", file),
 			"warning": "File integrity not verified",
-		})
+		}
+		if file != "" {
+			response["flag"] = labFlag(w, r, "a08_5")
+		}
+		sendJSON(w, response)
 		return
 	}
-	
+
 	html := renderPage("Upload File", `
 		<div class="card">
 			<h2>Upload File</h2>
@@ -129,35 +149,44 @@ func apiV1CICDDeploy(w http.ResponseWriter, r *http.Request) {
 		"status":  "success",
 		"message": "Code deployed via CI/CD without signature verification",
 		"warning": "Compromised code may be deployed",
+		"flag":    labFlag(w, r, "a08_6"),
 	})
 }
 
 // Уязвимость 7: Репозиторий без проверки подписи коммитов
 func apiV1RepoPull(w http.ResponseWriter, r *http.Request) {
 	repo := r.URL.Query().Get("repo")
-	
+
 	// УЯЗВИМОСТЬ: Репозиторий клонируется без проверки подписи
-	sendJSON(w, map[string]interface{}{
+	response := map[string]interface{}{
 		"status":  "success",
 		"message": fmt.Sprintf("Repository %s pulled without commit signature verification", repo),
 		"warning": "Compromised commits may be executed",
-	})
+	}
+	if repo != "" {
+		response["flag"] = labFlag(w, r, "a08_7")
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 8: Код выполняется без проверки подписи
 func apiV1CodeExecute(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		code := r.FormValue("code")
-		
+
 		// УЯЗВИМОСТЬ: Код выполняется без проверки цифровой подписи
-		sendJSON(w, map[string]interface{}{
+		response := map[string]interface{}{
 			"status":  "success",
 			"message": fmt.Sprintf("Code executed without signature verification: %s", code),
 			"warning": "Malicious code may be executed",
-		})
+		}
+		if code != "" {
+			response["flag"] = labFlag(w, r, "a08_8")
+		}
+		sendJSON(w, response)
 		return
 	}
-	
+
 	html := renderPage("Execute Code", `
 		<div class="card">
 			<h2>Execute Code</h2>
@@ -181,19 +210,23 @@ func apiV1CertificateVerify(w http.ResponseWriter, r *http.Request) {
 		"status":  "success",
 		"message": "Certificate chain not verified",
 		"warning": "Fake certificates may be accepted",
+		"flag":    labFlag(w, r, "a08_9"),
 	})
 }
 
 // Уязвимость 10: Нет проверки времени модификации
 func apiV1FileCheck(w http.ResponseWriter, r *http.Request) {
 	file := r.URL.Query().Get("file")
-	
+
 	// УЯЗВИМОСТЬ: Нет проверки времени модификации файла
-	sendJSON(w, map[string]interface{}{
+	response := map[string]interface{}{
 		"status":  "success",
 		"file":    file,
 		"message": fmt.Sprintf("File %s checked without timestamp verification", file),
 		"warning": "File may be rolled back to previous version",
-	})
+	}
+	if file != "" {
+		response["flag"] = labFlag(w, r, "a08_10")
+	}
+	sendJSON(w, response)
 }
-
@@ -3,6 +3,8 @@ package endpoints
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // A09:2025 - Security Logging and Alerting Failures
@@ -11,12 +13,20 @@ import (
 // Уязвимость 1: Критические действия не логируются
 func apiV1UsersDeleteNoLog(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
-	
+
 	// УЯЗВИМОСТЬ: Удаление пользователя не логируется
+	if !learnerDomain(w, r).DeleteUser(userID) {
+		sendJSON(w, map[string]interface{}{
+			"status":  "error",
+			"message": "User not found",
+		})
+		return
+	}
 	sendJSON(w, map[string]interface{}{
 		"status":  "success",
 		"message": fmt.Sprintf("User %s deleted (no audit log!)", userID),
 		"warning": "Security event not logged",
+		"flag":    labFlag(w, r, "a09_1"),
 	})
 }
 
@@ -25,17 +35,18 @@ func apiV1AuthLoginLogSensitive(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		email := r.FormValue("email")
 		password := r.FormValue("password")
-		
+
 		// УЯЗВИМОСТЬ: Пароль логируется в открытом виде
 		fmt.Printf("[LOG] Login attempt - email: %s, password: %s\n", email, password)
-		
+
 		sendJSON(w, map[string]interface{}{
 			"status":  "success",
 			"message": "Login successful (password logged in plain text!)",
+			"flag":    labFlag(w, r, "a09_2"),
 		})
 		return
 	}
-	
+
 	html := renderPage("Login", `
 		<div class="card">
 			<h2>Login</h2>
@@ -60,9 +71,10 @@ func apiV1AuthLoginLogSensitive(w http.ResponseWriter, r *http.Request) {
 func apiV1SystemStatus(w http.ResponseWriter, r *http.Request) {
 	// УЯЗВИМОСТЬ: Нет мониторинга подозрительной активности
 	sendJSON(w, map[string]interface{}{
-		"status":    "operational",
+		"status":     "operational",
 		"monitoring": "disabled",
-		"warning":   "No security monitoring enabled",
+		"warning":    "No security monitoring enabled",
+		"flag":       labFlag(w, r, "a09_3"),
 	})
 }
 
@@ -70,18 +82,27 @@ func apiV1SystemStatus(w http.ResponseWriter, r *http.Request) {
 func apiV1PaymentProcessInsufficientLog(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		amount := r.FormValue("amount")
-		
+
 		// УЯЗВИМОСТЬ: Логируется только сумма, без IP, времени, пользователя
 		fmt.Printf("[LOG] Payment: %s\n", amount)
-		
-		sendJSON(w, map[string]interface{}{
+
+		response := map[string]interface{}{
 			"status":  "success",
 			"message": fmt.Sprintf("Payment processed: %s (insufficient logging!)", amount),
 			"warning": "Missing: IP address, timestamp, user ID, transaction ID",
-		})
+		}
+		if value, err := strconv.Atoi(amount); err == nil {
+			if balance, err := learnerDomain(w, r).AdjustBalance(labUser.ID, -value); err == nil {
+				response["balance"] = balance
+			}
+		}
+		if amount != "" {
+			response["flag"] = labFlag(w, r, "a09_4")
+		}
+		sendJSON(w, response)
 		return
 	}
-	
+
 	html := renderPage("Process Payment", `
 		<div class="card">
 			<h2>Process Payment</h2>
@@ -98,25 +119,36 @@ func apiV1PaymentProcessInsufficientLog(w http.ResponseWriter, r *http.Request)
 	w.Write([]byte(html))
 }
 
+// Неудачные попытки входа, на которые не было алерта
+var unalertedFailures = newHitCounter()
+
 // Уязвимость 5: Отсутствие алертов
 func apiV1AuthFailedLogin(w http.ResponseWriter, r *http.Request) {
 	// УЯЗВИМОСТЬ: Нет алерта при множественных неудачных попытках
-	sendJSON(w, map[string]interface{}{
+	learner := learnerID(w, r)
+	response := map[string]interface{}{
 		"status":  "error",
 		"message": "Login failed (no alert sent!)",
 		"warning": "Brute force attack not detected",
-	})
+	}
+	// Пять неудачных попыток за минуту должны были вызвать алерт
+	if unalertedFailures.Hit(learner, time.Minute) >= 5 {
+		response["flag"] = challengeFlag(learner, "a09_5")
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 6: Логи в открытом доступе
 func apiV1LogsAccess(w http.ResponseWriter, r *http.Request) {
 	// УЯЗВИМОСТЬ: Логи доступны без аутентификации
+	flag := labFlag(w, r, "a09_6")
 	w.Write([]byte(`2024-01-15 10:30:15 [INFO] User login: admin@company.com
 2024-01-15 10:30:20 [INFO] API call: GET /api/v1/users/123
 2024-01-15 10:30:25 [ERROR] Database connection: postgresql://admin:password@db:5432
 2024-01-15 10:30:30 [INFO] Payment: amount=1000, user_id=123, card=****1234
 2024-01-15 10:30:35 [DEBUG] JWT token: eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...
-2024-01-15 10:30:40 [INFO] Password reset: user@company.com, token=abc123`))
+2024-01-15 10:30:40 [INFO] Password reset: user@company.com, token=abc123
+2024-01-15 10:30:45 [DEBUG] Internal token: ` + flag))
 }
 
 // Уязвимость 7: Отсутствие корреляции событий
@@ -126,21 +158,26 @@ func apiV1EventsList(w http.ResponseWriter, r *http.Request) {
 		"status":  "success",
 		"message": "Events listed (no correlation!)",
 		"warning": "Attack patterns cannot be detected",
+		"flag":    labFlag(w, r, "a09_7"),
 	})
 }
 
 // Уязвимость 8: Недостаточная детализация логов
 func apiV1ActionExecute(w http.ResponseWriter, r *http.Request) {
 	action := r.URL.Query().Get("action")
-	
+
 	// УЯЗВИМОСТЬ: Логируется только действие, без деталей
 	fmt.Printf("[LOG] Action: %s\n", action)
-	
-	sendJSON(w, map[string]interface{}{
+
+	response := map[string]interface{}{
 		"status":  "success",
 		"message": fmt.Sprintf("Action '%s' executed (insufficient details in log!)", action),
 		"warning": "Missing: user, IP, timestamp, parameters, result",
-	})
+	}
+	if action != "" {
+		response["flag"] = labFlag(w, r, "a09_8")
+	}
+	sendJSON(w, response)
 }
 
 // Уязвимость 9: Анализ логов не выполняется
@@ -150,6 +187,7 @@ func apiV1LogsAnalyze(w http.ResponseWriter, r *http.Request) {
 		"status":  "success",
 		"message": "Log analysis disabled",
 		"warning": "Suspicious activity not detected automatically",
+		"flag":    labFlag(w, r, "a09_9"),
 	})
 }
 
@@ -160,6 +198,6 @@ func apiV1LogsStorage(w http.ResponseWriter, r *http.Request) {
 		"status":  "success",
 		"storage": "/var/log/app/access.log (unencrypted!)",
 		"warning": "Logs accessible without encryption",
+		"flag":    labFlag(w, r, "a09_10"),
 	})
 }
-
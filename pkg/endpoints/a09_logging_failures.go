@@ -75,12 +75,12 @@ func a09LoggingFailures(w http.ResponseWriter, r *http.Request) {
 		<button>Показать настройки хранения</button>
 	</form>
 	`
-	
+
 	vuln := r.URL.Query().Get("vuln")
 	if r.Method == "POST" {
 		vuln = r.FormValue("vuln")
 	}
-	
+
 	// Уязвимость 1: Отсутствие логирования
 	if vuln == "1" {
 		action := r.FormValue("action")
@@ -88,7 +88,7 @@ func a09LoggingFailures(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Действие '%s' выполнено без логирования!", action)))
 		return
 	}
-	
+
 	// Уязвимость 2: Логирование чувствительных данных
 	if vuln == "2" {
 		pass := r.FormValue("pass")
@@ -97,13 +97,13 @@ func a09LoggingFailures(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Пароль '%s' записан в логи в открытом виде!", pass)))
 		return
 	}
-	
+
 	// Уязвимость 3: Отсутствие мониторинга
 	if vuln == "3" {
 		w.Write([]byte("Мониторинг не настроен! Нет отслеживания подозрительной активности!"))
 		return
 	}
-	
+
 	// Уязвимость 4: Недостаточное логирование
 	if vuln == "4" {
 		userID := r.FormValue("user_id")
@@ -112,7 +112,7 @@ func a09LoggingFailures(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Пользователь %s удален, но логи недостаточны (нет IP, времени, исполнителя)!", userID)))
 		return
 	}
-	
+
 	// Уязвимость 5: Отсутствие алертов
 	if vuln == "5" {
 		count := r.FormValue("count")
@@ -120,19 +120,19 @@ func a09LoggingFailures(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("%s неудачных попыток входа, но алерт не отправлен!", count)))
 		return
 	}
-	
+
 	// Уязвимость 6: Логи в открытом доступе
 	if r.URL.Path == "/logs/access.log" {
 		w.Write([]byte("2024-01-01 10:00:00 GET /admin - 200\n2024-01-01 10:01:00 POST /login user=admin pass=admin123 - 200\n2024-01-01 10:02:00 DELETE /users/5 - 200"))
 		return
 	}
-	
+
 	// Уязвимость 7: Отсутствие корреляции событий
 	if vuln == "7" {
 		w.Write([]byte("События не коррелируются! Невозможно отследить цепочку атак!"))
 		return
 	}
-	
+
 	// Уязвимость 8: Недостаточная детализация логов
 	if vuln == "8" {
 		action := r.FormValue("action")
@@ -142,20 +142,19 @@ func a09LoggingFailures(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Действие '%s' на сумму %s выполнено, но в логах нет деталей!", action, amount)))
 		return
 	}
-	
+
 	// Уязвимость 9: Отсутствие анализа логов
 	if vuln == "9" {
 		w.Write([]byte("Анализ логов не выполняется! Подозрительная активность не обнаруживается!"))
 		return
 	}
-	
+
 	// Уязвимость 10: Небезопасное хранение логов
 	if vuln == "10" {
 		w.Write([]byte("Логи хранятся в открытом виде без шифрования! Доступ: /logs/"))
 		return
 	}
-	
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
 }
-
@@ -80,12 +80,12 @@ func a10ExceptionHandling(w http.ResponseWriter, r *http.Request) {
 		<button>Проверить сервис</button>
 	</form>
 	`
-	
+
 	vuln := r.URL.Query().Get("vuln")
 	if r.Method == "POST" {
 		vuln = r.FormValue("vuln")
 	}
-	
+
 	// Уязвимость 1: Раскрытие информации в ошибках
 	if vuln == "1" {
 		id := r.URL.Query().Get("id")
@@ -93,7 +93,7 @@ func a10ExceptionHandling(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Ошибка: пользователь с ID=%s не найден в таблице users в базе данных postgresql на сервере db.internal:5432. SQL запрос: SELECT * FROM users WHERE id=%s", id, id)))
 		return
 	}
-	
+
 	// Уязвимость 2: Отсутствие обработки ошибок
 	if vuln == "2" {
 		numStr := r.URL.Query().Get("num")
@@ -103,7 +103,7 @@ func a10ExceptionHandling(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Результат: %d (может вызвать панику!)", result)))
 		return
 	}
-	
+
 	// Уязвимость 3: Небезопасное логирование ошибок
 	if vuln == "3" {
 		query := r.URL.Query().Get("query")
@@ -112,14 +112,14 @@ func a10ExceptionHandling(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Ошибка SQL запроса '%s' залогирована с полной информацией!", query)))
 		return
 	}
-	
+
 	// Уязвимость 4: Stack trace в ответе
 	if vuln == "4" {
 		// Показываем полный stack trace
 		w.Write([]byte("Panic: runtime error: invalid memory address\n\ngoroutine 1 [running]:\nmain.handleRequest(0x123456)\n\t/home/user/app/main.go:42\nmain.main()\n\t/home/user/app/main.go:15"))
 		return
 	}
-	
+
 	// Уязвимость 5: Отсутствие валидации входных данных
 	if vuln == "5" {
 		amount := r.URL.Query().Get("amount")
@@ -127,7 +127,7 @@ func a10ExceptionHandling(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Перевод %s выполнен без проверки валидности!", amount)))
 		return
 	}
-	
+
 	// Уязвимость 6: Неправильная обработка исключений
 	if vuln == "6" {
 		file := r.URL.Query().Get("file")
@@ -135,7 +135,7 @@ func a10ExceptionHandling(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Ошибка чтения файла %s: permission denied. Файл существует, но нет доступа. Система: Linux, пользователь: www-data", file)))
 		return
 	}
-	
+
 	// Уязвимость 7: Race condition в обработке ошибок
 	if vuln == "7" {
 		action := r.FormValue("action")
@@ -143,7 +143,7 @@ func a10ExceptionHandling(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("Действие '%s' выполнено, но обработка ошибок не thread-safe!", action)))
 		return
 	}
-	
+
 	// Уязвимость 8: Утечка информации через таймауты
 	if vuln == "8" {
 		user := r.URL.Query().Get("user")
@@ -156,7 +156,7 @@ func a10ExceptionHandling(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	
+
 	// Уязвимость 9: Небезопасная обработка null значений
 	if vuln == "9" {
 		data := r.URL.Query().Get("data")
@@ -168,15 +168,14 @@ func a10ExceptionHandling(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	
+
 	// Уязвимость 10: Отсутствие graceful degradation
 	if vuln == "10" {
 		// При ошибке сервис полностью падает
 		w.Write([]byte("Ошибка подключения к базе данных! Весь сервис недоступен (нет graceful degradation!)"))
 		return
 	}
-	
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write([]byte(html))
 }
-
@@ -0,0 +1,190 @@
+package endpoints
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A10:2025 - Mishandling of Exceptional Conditions
+// Исправленные версии эндпоинтов (режим secure)
+
+// Ответ с кратким сообщением; подробности остаются в логе сервера под номером инцидента
+func sendIncident(w http.ResponseWriter, status int, message, details string) {
+	incident, err := randomHex(6)
+	if err != nil {
+		incident = "unknown"
+	}
+	log.Printf("[ERROR] incident=%s %s", incident, details)
+	sendJSONStatus(w, status, map[string]interface{}{
+		"status":      "error",
+		"message":     message,
+		"incident_id": incident,
+	})
+}
+
+// Исправление 1: клиент получает короткое сообщение без внутренних деталей
+func apiV1UsersGetSecure(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	// ИСПРАВЛЕНИЕ: ни stack trace, ни адреса БД, ни версий в ответе
+	if userID == "" {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "user_id parameter is required",
+		})
+		return
+	}
+	apiV1UsersGet(w, r)
+}
+
+// Исправление 2: ввод проверяется до вычислений
+func apiV1CalculateSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: ошибка разбора и ноль обрабатываются явно, паники нет
+	num, err := strconv.Atoi(r.URL.Query().Get("number"))
+	if err != nil || num == 0 {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "number must be a non-zero integer",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status": "success",
+		"result": 100 / num,
+	})
+}
+
+// Исправление 3: в лог ошибок не попадают учетные данные
+func apiV1DatabaseQuerySecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: строка подключения без пароля, текст запроса не логируется целиком
+	sendIncident(w, http.StatusInternalServerError, "Query failed",
+		fmt.Sprintf("database query failed: db=prod-db:5432/production query_length=%d", len(r.URL.Query().Get("query"))))
+}
+
+// Исправление 4: паника перехватывается, клиент видит только номер инцидента
+func apiV1ProcessSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: recover пишет подробности в лог сервера, а не в ответ
+	defer func() {
+		if err := recover(); err != nil {
+			sendIncident(w, http.StatusInternalServerError, "Internal server error", fmt.Sprintf("panic: %v", err))
+		}
+	}()
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": "Request processed",
+	})
+}
+
+// Максимальная сумма одного перевода
+const transferLimit = 10000
+
+// Исправление 5: сумма проверяется на знак, конечность и лимит
+func apiV1TransferSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1Transfer(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: NaN, Inf, дробные, отрицательные и слишком большие суммы отклоняются
+	amount, err := strconv.ParseFloat(r.FormValue("amount"), 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) || amount != math.Trunc(amount) || amount <= 0 || amount > transferLimit {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": fmt.Sprintf("Amount must be a whole number between 1 and %d", transferLimit),
+		})
+		return
+	}
+	balance, err := learnerDomain(w, r).TransferFunds(labUser.ID, "2", int(amount))
+	if err != nil {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Transfer declined",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Transfer of %d completed", int(amount)),
+		"balance": balance,
+	})
+}
+
+// Исправление 6: ошибка чтения файла не раскрывает детали системы
+func apiV1FileReadSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: только локальные пути внутри каталога данных;
+	// одинаковое сообщение для отсутствующего и недоступного файла
+	file := r.URL.Query().Get("file")
+	name, ok := resolveSafe(appDataDir, file)
+	if !ok {
+		sendIncident(w, http.StatusNotFound, "File not available", fmt.Sprintf("file read rejected: %q", file))
+		return
+	}
+	content, err := readServerFile(w, r, name)
+	if err != nil {
+		sendIncident(w, http.StatusNotFound, "File not available", fmt.Sprintf("file read failed: %v", err))
+		return
+	}
+	w.Write(content)
+}
+
+// Действия одного учащегося выполняются последовательно
+var actionLocks = newKeyedMutex()
+
+// Исправление 7: общее состояние защищено блокировкой
+func apiV1ConcurrentSecure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		apiV1Concurrent(w, r)
+		return
+	}
+	// ИСПРАВЛЕНИЕ: параллельные запросы не пересекаются
+	unlock := actionLocks.Lock(learnerID(w, r))
+	time.Sleep(100 * time.Millisecond) // Имитация обработки
+	unlock()
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Action '%s' processed", r.FormValue("action")),
+	})
+}
+
+// Исправление 8: время и текст ответа не зависят от существования пользователя
+func apiV1UserCheckSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: одинаковый ответ и одинаковая задержка для любого имени
+	time.Sleep(200 * time.Millisecond)
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": "If the user exists, further instructions have been sent",
+	})
+}
+
+// Исправление 9: пустое значение отклоняется до обработки
+func apiV1DataProcessSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: обработка не продолжается без данных
+	data := r.URL.Query().Get("data")
+	if data == "" {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "data is required",
+		})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Data processed: %s", data),
+	})
+}
+
+// Исправление 10: при отказе БД сервис продолжает работать в ограниченном режиме
+func apiV1ServiceStatusSecure(w http.ResponseWriter, r *http.Request) {
+	// ИСПРАВЛЕНИЕ: чтение из кеша, запись в очередь до восстановления БД
+	sendJSON(w, map[string]interface{}{
+		"status":  "degraded",
+		"message": "Database unavailable, serving cached data; writes are queued",
+		"services": map[string]string{
+			"database": "down",
+			"api":      "up",
+			"cache":    "up",
+		},
+	})
+}
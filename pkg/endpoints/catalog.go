@@ -0,0 +1,466 @@
+package endpoints
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Каталог заданий: каждое задание лежит в отдельной директории catalog/<ключ>/
+//
+//	challenge.json   - описание задания и правила проверки ответа
+//	form.html        - форма для отправки ответа
+//	explanation.html - подробное объяснение уязвимости
+//
+// Каталог встраивается в бинарник, разбирается и проверяется один раз при старте.
+
+//go:embed catalog
+var catalogFS embed.FS
+
+// Загруженный каталог заданий по ключу (a01_1 ... a10_10)
+var catalog = map[string]Challenge{}
+
+var (
+	challengeKeyRe = regexp.MustCompile(`^a\d{2}_\d+$`)
+	difficulties   = []string{"Легкий", "Средний", "Сложный"}
+)
+
+// Описание задания в challenge.json
+type challengeFile struct {
+	Title       string          `json:"title"`
+	Category    string          `json:"category"`
+	Difficulty  string          `json:"difficulty"`
+	Description string          `json:"description"`
+	Task        string          `json:"task"`
+	Hints       []Hint          `json:"hints"`
+	Check       *ChallengeCheck `json:"check"`
+}
+
+// Подсказка к заданию. Подсказки открываются по одной, от общей к конкретной;
+// Cost - сколько очков снимается за открытие (по умолчанию hint_cost из настроек соревнования)
+type Hint struct {
+	Text string `json:"text"`
+	Cost *int   `json:"cost,omitempty"`
+}
+
+// Правила проверки ответа. Если Flag включен, учащийся должен отправить свой флаг,
+// полученный при эксплуатации эндпоинта. Если задан Param, берем параметр запроса, при необходимости
+// приводим его к нужному виду и сравниваем: все правила из All должны выполниться, а из Any - хотя бы одно.
+// Evidence - запросы, которые должны найтись в журнале учащегося (см. journal.go).
+// Все заданные способы проверки должны пройти одновременно
+type ChallengeCheck struct {
+	Flag      bool           `json:"flag,omitempty"`
+	Param     string         `json:"param,omitempty"`
+	Transform string         `json:"transform,omitempty"`
+	All       []AnswerRule   `json:"all,omitempty"`
+	Any       []AnswerRule   `json:"any,omitempty"`
+	Evidence  []EvidenceRule `json:"evidence,omitempty"`
+}
+
+// Доказательство эксплуатации: в журнале учащегося должен быть запрос к маршруту Route,
+// подходящий под все условия. Правила для параметров и заголовков применяются к их значению
+// (отсутствующий параметр - пустая строка), Body и Response - к телу запроса и ответа
+type EvidenceRule struct {
+	Route           string                  `json:"route"`
+	Method          string                  `json:"method,omitempty"`
+	Status          int                     `json:"status,omitempty"`
+	Path            []AnswerRule            `json:"path,omitempty"`
+	Params          map[string][]AnswerRule `json:"params,omitempty"`
+	Headers         map[string][]AnswerRule `json:"headers,omitempty"`
+	Body            []AnswerRule            `json:"body,omitempty"`
+	Response        []AnswerRule            `json:"response,omitempty"`
+	ResponseHeaders map[string][]AnswerRule `json:"response_headers,omitempty"`
+}
+
+// Одно правило сравнения ответа
+type AnswerRule struct {
+	Op     string    `json:"op"`
+	Value  ruleValue `json:"value,omitempty"`
+	Values []string  `json:"values,omitempty"`
+	Not    bool      `json:"not,omitempty"`
+
+	re  *regexp.Regexp
+	num float64
+}
+
+// Значение правила: в JSON может быть строкой или числом
+type ruleValue string
+
+func (v *ruleValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = ruleValue(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("value must be a string or a number")
+	}
+	*v = ruleValue(n.String())
+	return nil
+}
+
+// Загрузить и проверить все задания каталога
+func loadCatalog(fsys fs.FS) (map[string]Challenge, error) {
+	entries, err := fs.ReadDir(fsys, "catalog")
+	if err != nil {
+		return nil, fmt.Errorf("read catalog: %w", err)
+	}
+
+	challenges := make(map[string]Challenge, len(entries))
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		key := entry.Name()
+		challenge, err := loadChallenge(fsys, key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("challenge %s: %w", key, err))
+			continue
+		}
+		challenges[key] = challenge
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return challenges, nil
+}
+
+func loadChallenge(fsys fs.FS, key string) (Challenge, error) {
+	if !challengeKeyRe.MatchString(key) {
+		return Challenge{}, fmt.Errorf("directory name must look like a01_1")
+	}
+	dir := path.Join("catalog", key)
+
+	data, err := fs.ReadFile(fsys, path.Join(dir, "challenge.json"))
+	if err != nil {
+		return Challenge{}, err
+	}
+	var file challengeFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return Challenge{}, fmt.Errorf("challenge.json: %w", err)
+	}
+
+	form, err := fs.ReadFile(fsys, path.Join(dir, "form.html"))
+	if err != nil {
+		return Challenge{}, err
+	}
+	explanation, err := fs.ReadFile(fsys, path.Join(dir, "explanation.html"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Challenge{}, err
+	}
+
+	challenge := Challenge{
+		Key:         key,
+		Title:       file.Title,
+		Category:    file.Category,
+		Difficulty:  file.Difficulty,
+		Description: file.Description,
+		Task:        file.Task,
+		Hints:       file.Hints,
+		FormHTML:    string(form),
+		Explanation: string(explanation),
+		Check:       file.Check,
+	}
+	if err := challenge.validate(); err != nil {
+		return Challenge{}, err
+	}
+	return challenge, nil
+}
+
+// Проверить обязательные поля и скомпилировать правила
+func (c *Challenge) validate() error {
+	var errs []error
+	required := []struct{ name, value string }{
+		{"title", c.Title},
+		{"category", c.Category},
+		{"task", c.Task},
+	}
+	for _, field := range required {
+		if strings.TrimSpace(field.value) == "" {
+			errs = append(errs, fmt.Errorf("%s is required", field.name))
+		}
+	}
+
+	if len(c.Hints) == 0 {
+		errs = append(errs, fmt.Errorf("at least one hint is required"))
+	}
+	for i, hint := range c.Hints {
+		if strings.TrimSpace(hint.Text) == "" {
+			errs = append(errs, fmt.Errorf("hints[%d]: text is required", i))
+		}
+		if hint.Cost != nil && *hint.Cost < 0 {
+			errs = append(errs, fmt.Errorf("hints[%d]: cost must not be negative", i))
+		}
+	}
+
+	knownDifficulty := false
+	for _, d := range difficulties {
+		knownDifficulty = knownDifficulty || c.Difficulty == d
+	}
+	if !knownDifficulty {
+		errs = append(errs, fmt.Errorf("difficulty %q must be one of %s", c.Difficulty, strings.Join(difficulties, ", ")))
+	}
+
+	if c.Check == nil {
+		errs = append(errs, fmt.Errorf("check is required"))
+	} else {
+		if err := c.Check.compile(); err != nil {
+			errs = append(errs, fmt.Errorf("check: %w", err))
+		}
+		// Форма должна отправлять параметр, который проверяется
+		if c.Check.Param != "" && !strings.Contains(c.FormHTML, `name="`+c.Check.Param+`"`) {
+			errs = append(errs, fmt.Errorf("form.html has no field named %q", c.Check.Param))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *ChallengeCheck) compile() error {
+	hasRules := len(c.All) > 0 || len(c.Any) > 0
+	if !c.Flag && c.Param == "" && !hasRules && len(c.Evidence) == 0 {
+		return fmt.Errorf("flag, param or evidence is required")
+	}
+	if c.Flag && (c.Param != "" || hasRules) {
+		return fmt.Errorf("flag check cannot have answer rules")
+	}
+
+	var errs []error
+	if c.Param != "" || hasRules {
+		if c.Param == "" {
+			errs = append(errs, fmt.Errorf("param is required"))
+		}
+		if !hasRules {
+			errs = append(errs, fmt.Errorf("at least one rule in all or any is required"))
+		}
+	}
+	switch c.Transform {
+	case "", "lower", "upper", "trim":
+	default:
+		errs = append(errs, fmt.Errorf("unknown transform %q", c.Transform))
+	}
+	errs = append(errs, compileRules("all", c.All), compileRules("any", c.Any))
+	for i := range c.Evidence {
+		if err := c.Evidence[i].compile(); err != nil {
+			errs = append(errs, fmt.Errorf("evidence[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *EvidenceRule) compile() error {
+	var errs []error
+	if !strings.HasPrefix(e.Route, "/") {
+		errs = append(errs, fmt.Errorf("route must start with /"))
+	}
+	errs = append(errs,
+		compileRules("path", e.Path),
+		compileRules("body", e.Body),
+		compileRules("response", e.Response),
+	)
+	for name, rules := range e.Params {
+		errs = append(errs, compileRules("params."+name, rules))
+	}
+	for name, rules := range e.Headers {
+		errs = append(errs, compileRules("headers."+name, rules))
+	}
+	for name, rules := range e.ResponseHeaders {
+		errs = append(errs, compileRules("response_headers."+name, rules))
+	}
+	return errors.Join(errs...)
+}
+
+func compileRules(field string, rules []AnswerRule) error {
+	var errs []error
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			errs = append(errs, fmt.Errorf("%s[%d]: %w", field, i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (rule *AnswerRule) compile() error {
+	switch rule.Op {
+	case "equals", "contains", "prefix", "suffix":
+		if rule.Value == "" {
+			return fmt.Errorf("%s: value is required", rule.Op)
+		}
+	case "one_of":
+		if len(rule.Values) == 0 {
+			return fmt.Errorf("one_of: values are required")
+		}
+	case "regex":
+		re, err := regexp.Compile(string(rule.Value))
+		if err != nil {
+			return fmt.Errorf("regex: %w", err)
+		}
+		rule.re = re
+	case "gt", "gte", "lt", "lte", "min_length", "max_length":
+		n, err := strconv.ParseFloat(string(rule.Value), 64)
+		if err != nil {
+			return fmt.Errorf("%s: value must be a number", rule.Op)
+		}
+		rule.num = n
+	default:
+		return fmt.Errorf("unknown op %q", rule.Op)
+	}
+	return nil
+}
+
+// Проверить решение учащегося; ошибка объясняет, чего не хватает
+func (c *ChallengeCheck) Verify(r *http.Request, learner, challengeKey string) error {
+	if c.Flag && !validFlag(learner, challengeKey, r.FormValue("flag")) {
+		return errors.New("Неверный флаг. Попробуйте еще раз.")
+	}
+	if c.Param != "" && !c.matchAnswer(r.FormValue(c.Param)) {
+		return errors.New("Неверный ответ. Попробуйте еще раз.")
+	}
+	if !c.MatchJournal(journal.Entries(learner)) {
+		return errors.New("В журнале ваших запросов нет подтверждения эксплуатации. Выполните атаку на эндпоинт и проверьте снова.")
+	}
+	return nil
+}
+
+// Проверить, что в журнале есть подходящий запрос для каждого правила Evidence
+func (c *ChallengeCheck) MatchJournal(entries []journalEntry) bool {
+	for _, rule := range c.Evidence {
+		found := false
+		for _, entry := range entries {
+			if rule.Match(entry) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *ChallengeCheck) matchAnswer(answer string) bool {
+	switch c.Transform {
+	case "lower":
+		answer = strings.ToLower(answer)
+	case "upper":
+		answer = strings.ToUpper(answer)
+	case "trim":
+		answer = strings.TrimSpace(answer)
+	}
+
+	for _, rule := range c.All {
+		if !rule.Match(answer) {
+			return false
+		}
+	}
+	if len(c.Any) == 0 {
+		return true
+	}
+	for _, rule := range c.Any {
+		if rule.Match(answer) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e EvidenceRule) Match(entry journalEntry) bool {
+	// Запрос к исправленной версии эндпоинта не доказывает эксплуатацию
+	if entry.Route != e.Route || entry.Mode == modeSecure {
+		return false
+	}
+	if e.Method != "" && !strings.EqualFold(entry.Method, e.Method) {
+		return false
+	}
+	if e.Status != 0 && entry.Status != e.Status {
+		return false
+	}
+	if !matchAll(e.Path, entry.Path) || !matchAll(e.Body, entry.Body) || !matchAll(e.Response, entry.ResponseBody) {
+		return false
+	}
+	for name, rules := range e.Params {
+		if !matchAll(rules, entry.Params.Get(name)) {
+			return false
+		}
+	}
+	for name, rules := range e.Headers {
+		if !matchAll(rules, entry.Header.Get(name)) {
+			return false
+		}
+	}
+	for name, rules := range e.ResponseHeaders {
+		if !matchAll(rules, entry.ResponseHeader.Get(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAll(rules []AnswerRule, value string) bool {
+	for _, rule := range rules {
+		if !rule.Match(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (rule AnswerRule) Match(answer string) bool {
+	return rule.match(answer) != rule.Not
+}
+
+func (rule AnswerRule) match(answer string) bool {
+	value := string(rule.Value)
+	switch rule.Op {
+	case "equals":
+		return answer == value
+	case "one_of":
+		for _, v := range rule.Values {
+			if answer == v {
+				return true
+			}
+		}
+		return false
+	case "contains":
+		return strings.Contains(answer, value)
+	case "prefix":
+		return strings.HasPrefix(answer, value)
+	case "suffix":
+		return strings.HasSuffix(answer, value)
+	case "regex":
+		return rule.re.MatchString(answer)
+	case "min_length":
+		return float64(len([]rune(answer))) >= rule.num
+	case "max_length":
+		return float64(len([]rune(answer))) <= rule.num
+	}
+
+	// Числовые сравнения: нечисловой ответ не подходит
+	n, err := strconv.ParseFloat(strings.TrimSpace(answer), 64)
+	if err != nil {
+		return false
+	}
+	switch rule.Op {
+	case "gt":
+		return n > rule.num
+	case "gte":
+		return n >= rule.num
+	case "lt":
+		return n < rule.num
+	case "lte":
+		return n <= rule.num
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func mustRule(t *testing.T, src string) AnswerRule {
+	t.Helper()
+	var rule AnswerRule
+	if err := json.Unmarshal([]byte(src), &rule); err != nil {
+		t.Fatalf("%s: %v", src, err)
+	}
+	if err := rule.compile(); err != nil {
+		t.Fatalf("%s: %v", src, err)
+	}
+	return rule
+}
+
+func TestAnswerRuleMatch(t *testing.T) {
+	tests := []struct {
+		rule   string
+		answer string
+		want   bool
+	}{
+		{`{"op": "equals", "value": "admin"}`, "admin", true},
+		{`{"op": "equals", "value": "admin"}`, "Admin", false},
+		{`{"op": "equals", "value": "admin", "not": true}`, "Admin", true},
+		{`{"op": "contains", "value": "' OR"}`, "x' OR 1=1", true},
+		{`{"op": "contains", "value": "' OR"}`, "x' or 1=1", false},
+		{`{"op": "prefix", "value": "FLAG{"}`, "FLAG{abc}", true},
+		{`{"op": "prefix", "value": "FLAG{"}`, " FLAG{abc}", false},
+		{`{"op": "suffix", "value": ".php"}`, "shell.php", true},
+		{`{"op": "suffix", "value": ".php"}`, "shell.php.png", false},
+		{`{"op": "one_of", "values": ["true", "1"]}`, "1", true},
+		{`{"op": "one_of", "values": ["true", "1"]}`, "yes", false},
+		{`{"op": "one_of", "values": ["true", "1"], "not": true}`, "", true},
+		{`{"op": "regex", "value": "(?i)\\bor\\b"}`, "1' Or '1'='1", true},
+		{`{"op": "regex", "value": "(?i)\\bor\\b"}`, "order", false},
+		{`{"op": "gt", "value": 100}`, "100.5", true},
+		{`{"op": "gt", "value": 100}`, "100", false},
+		{`{"op": "gte", "value": "100"}`, " 100 ", true},
+		{`{"op": "lt", "value": 0}`, "-1", true},
+		{`{"op": "lt", "value": 0}`, "abc", false},
+		{`{"op": "lt", "value": 0, "not": true}`, "abc", true},
+		{`{"op": "lte", "value": 3}`, "3", true},
+		{`{"op": "min_length", "value": 3}`, "абв", true},
+		{`{"op": "min_length", "value": 3}`, "ab", false},
+		{`{"op": "max_length", "value": 3}`, "абвг", false},
+		{`{"op": "max_length", "value": 3}`, "", true},
+	}
+	for _, tt := range tests {
+		rule := mustRule(t, tt.rule)
+		if got := rule.Match(tt.answer); got != tt.want {
+			t.Errorf("%s: Match(%q) = %v, want %v", tt.rule, tt.answer, got, tt.want)
+		}
+	}
+}
+
+func TestAnswerRuleCompileErrors(t *testing.T) {
+	for _, src := range []string{
+		`{"op": "equals"}`,
+		`{"op": "contains", "value": ""}`,
+		`{"op": "one_of"}`,
+		`{"op": "regex", "value": "("}`,
+		`{"op": "gt", "value": "many"}`,
+		`{"op": "min_length"}`,
+		`{"op": "like", "value": "x"}`,
+	} {
+		var rule AnswerRule
+		if err := json.Unmarshal([]byte(src), &rule); err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if err := rule.compile(); err == nil {
+			t.Errorf("%s: no error", src)
+		}
+	}
+
+	var rule AnswerRule
+	if err := json.Unmarshal([]byte(`{"op": "equals", "value": ["a"]}`), &rule); err == nil {
+		t.Error("array value: no error")
+	}
+}
+
+func TestChallengeCheckCompile(t *testing.T) {
+	tests := []struct {
+		check string
+		ok    bool
+	}{
+		{`{"flag": true}`, true},
+		{`{"param": "answer", "all": [{"op": "equals", "value": "42"}]}`, true},
+		{`{"param": "answer", "transform": "lower", "any": [{"op": "equals", "value": "a"}, {"op": "equals", "value": "b"}]}`, true},
+		{`{"evidence": [{"route": "/api/v1/users/"}]}`, true},
+		{`{"flag": true, "evidence": [{"route": "/api/v1/users/", "params": {"id": [{"op": "equals", "value": "3"}]}}]}`, true},
+		{`{}`, false},
+		{`{"flag": true, "param": "answer", "all": [{"op": "equals", "value": "42"}]}`, false},
+		{`{"param": "answer"}`, false},
+		{`{"all": [{"op": "equals", "value": "42"}]}`, false},
+		{`{"param": "answer", "transform": "title", "all": [{"op": "equals", "value": "a"}]}`, false},
+		{`{"param": "answer", "all": [{"op": "regex", "value": "["}]}`, false},
+		{`{"evidence": [{"route": "api/v1/users/"}]}`, false},
+		{`{"evidence": [{"route": "/api/v1/users/", "headers": {"Cookie": [{"op": "nope"}]}}]}`, false},
+		{`{"evidence": [{"route": "/api/v1/users/", "response_headers": {"Location": [{"op": "prefix"}]}}]}`, false},
+	}
+	for _, tt := range tests {
+		var check ChallengeCheck
+		if err := json.Unmarshal([]byte(tt.check), &check); err != nil {
+			t.Fatalf("%s: %v", tt.check, err)
+		}
+		if err := check.compile(); (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok=%v", tt.check, err, tt.ok)
+		}
+	}
+}
+
+func TestMatchAnswer(t *testing.T) {
+	check := ChallengeCheck{
+		Param:     "answer",
+		Transform: "trim",
+		All:       []AnswerRule{mustRule(t, `{"op": "min_length", "value": 2}`)},
+		Any:       []AnswerRule{mustRule(t, `{"op": "equals", "value": "md5"}`), mustRule(t, `{"op": "equals", "value": "sha1"}`)},
+	}
+	tests := map[string]bool{
+		" md5 ":  true,
+		"sha1":   true,
+		"MD5":    false,
+		"sha256": false,
+		"":       false,
+	}
+	for answer, want := range tests {
+		if got := check.matchAnswer(answer); got != want {
+			t.Errorf("matchAnswer(%q) = %v, want %v", answer, got, want)
+		}
+	}
+}
+
+func TestEvidenceRuleMatch(t *testing.T) {
+	var rule EvidenceRule
+	src := `{
+		"route": "/api/v1/users/",
+		"method": "post",
+		"status": 200,
+		"path": [{"op": "suffix", "value": "/3"}],
+		"params": {"is_admin": [{"op": "one_of", "values": ["true", "1"]}]},
+		"headers": {"X-Role": [{"op": "equals", "value": "admin"}]},
+		"body": [{"op": "contains", "value": "is_admin"}],
+		"response": [{"op": "contains", "value": "admin@company.com"}],
+		"response_headers": {"Content-Type": [{"op": "prefix", "value": "application/json"}]}
+	}`
+	if err := json.Unmarshal([]byte(src), &rule); err != nil {
+		t.Fatal(err)
+	}
+	if err := rule.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := func(change func(*journalEntry)) journalEntry {
+		e := journalEntry{
+			Method:         "POST",
+			Route:          "/api/v1/users/",
+			Path:           "/api/v1/users/3",
+			Params:         url.Values{"is_admin": {"1"}},
+			Header:         http.Header{"X-Role": {"admin"}},
+			Body:           "is_admin=1",
+			Mode:           modeVulnerable,
+			Status:         200,
+			ResponseHeader: http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+			ResponseBody:   `{"email": "admin@company.com"}`,
+		}
+		if change != nil {
+			change(&e)
+		}
+		return e
+	}
+	tests := []struct {
+		name   string
+		change func(*journalEntry)
+		want   bool
+	}{
+		{"match", nil, true},
+		{"secure mode", func(e *journalEntry) { e.Mode = modeSecure }, false},
+		{"other route", func(e *journalEntry) { e.Route = "/api/v1/users" }, false},
+		{"method", func(e *journalEntry) { e.Method = "GET" }, false},
+		{"status", func(e *journalEntry) { e.Status = 403 }, false},
+		{"path", func(e *journalEntry) { e.Path = "/api/v1/users/1" }, false},
+		{"missing param", func(e *journalEntry) { e.Params = url.Values{} }, false},
+		{"header", func(e *journalEntry) { e.Header = http.Header{} }, false},
+		{"body", func(e *journalEntry) { e.Body = "" }, false},
+		{"response", func(e *journalEntry) { e.ResponseBody = "{}" }, false},
+		{"response header", func(e *journalEntry) { e.ResponseHeader.Set("Content-Type", "text/html") }, false},
+	}
+	for _, tt := range tests {
+		if got := rule.Match(entry(tt.change)); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	check := ChallengeCheck{Evidence: []EvidenceRule{rule, {Route: "/api/v1/orders/"}}}
+	if check.MatchJournal([]journalEntry{entry(nil)}) {
+		t.Error("journal without the second request matched")
+	}
+	if !check.MatchJournal([]journalEntry{{Route: "/api/v1/orders/"}, entry(nil)}) {
+		t.Error("journal with both requests did not match")
+	}
+}
+
+// Все встроенные задания должны загружаться без ошибок
+func TestLoadEmbeddedCatalog(t *testing.T) {
+	entries, err := fs.ReadDir(catalogFS, "catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenges, err := loadCatalog(catalogFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(challenges) != len(entries) {
+		t.Errorf("loaded %d challenges from %d directories", len(challenges), len(entries))
+	}
+	for key, c := range challenges {
+		if c.Key != key || c.Check == nil || c.FormHTML == "" {
+			t.Errorf("%s: incomplete challenge", key)
+		}
+	}
+}
+
+func TestLoadCatalogErrors(t *testing.T) {
+	valid := `{"title": "T", "category": "A01", "difficulty": "Легкий", "task": "do it", "hints": [{"text": "h"}], "check": {"flag": true}}`
+	tests := []struct {
+		name string
+		dir  string
+		json string
+		form string
+	}{
+		{"bad key", "a1_1", valid, "<form></form>"},
+		{"unknown field", "a01_1", strings.Replace(valid, `"task"`, `"tusk": "x", "task"`, 1), "<form></form>"},
+		{"no hints", "a01_1", strings.Replace(valid, `[{"text": "h"}]`, `[]`, 1), "<form></form>"},
+		{"difficulty", "a01_1", strings.Replace(valid, "Легкий", "Простой", 1), "<form></form>"},
+		{"no check", "a01_1", strings.Replace(valid, `, "check": {"flag": true}`, "", 1), "<form></form>"},
+		{"param not in form", "a01_1", strings.Replace(valid, `{"flag": true}`, `{"param": "answer", "all": [{"op": "equals", "value": "1"}]}`, 1), `<input name="other">`},
+	}
+	for _, tt := range tests {
+		fsys := fstest.MapFS{
+			"catalog/" + tt.dir + "/challenge.json": {Data: []byte(tt.json)},
+			"catalog/" + tt.dir + "/form.html":      {Data: []byte(tt.form)},
+		}
+		if _, err := loadCatalog(fsys); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+
+	fsys := fstest.MapFS{
+		"catalog/a01_1/challenge.json": {Data: []byte(valid)},
+		"catalog/a01_1/form.html":      {Data: []byte("<form></form>")},
+	}
+	if _, err := loadCatalog(fsys); err != nil {
+		t.Errorf("valid challenge: %v", err)
+	}
+}
@@ -1,8 +1,12 @@
 package endpoints
 
 import (
-	"fmt"
+	"bytes"
+	"encoding/json"
+	"log"
 	"net/http"
+	"sync"
+	"time"
 )
 
 // Общие стили для всех страниц (имитация реального приложения)
@@ -189,6 +193,9 @@ func renderPage(title, content string) string {
 		<div class="nav">
 			<a href="/">Главная</a>
 			<a href="/explanations">Объяснения уязвимостей</a>
+			<a href="/scoreboard">Таблица результатов</a>
+			<a href="/team">Команда</a>
+			<a href="/mode">Режим эндпоинтов</a>
 		</div>
 		` + content + `
 	</div>
@@ -196,56 +203,162 @@ func renderPage(title, content string) string {
 </html>`
 }
 
-// Функция для отправки JSON ответа (упрощенная версия)
+// Отправить JSON ответ. Ключи объектов выводятся по алфавиту, строки экранируются,
+// вложенные значения сохраняют свои типы. Символы < > & не заменяются на \u003c и т.п.,
+// чтобы учащийся видел свой payload в ответе как есть
 func sendJSON(w http.ResponseWriter, data map[string]interface{}) {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		log.Printf("encode JSON: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body.Bytes())
+}
+
+// JSON ответ с кодом статуса
+func sendJSONStatus(w http.ResponseWriter, status int, data map[string]interface{}) {
 	w.Header().Set("Content-Type", "application/json")
-	json := "{\n"
-	first := true
-	for k, v := range data {
-		if !first {
-			json += ",\n"
+	w.WriteHeader(status)
+	sendJSON(w, data)
+}
+
+// Счетчик одновременно выполняющихся запросов (для заданий на race condition)
+type inFlightCounter struct {
+	mu     sync.Mutex
+	active map[string]int
+	peak   map[string]int
+}
+
+func newInFlightCounter() *inFlightCounter {
+	return &inFlightCounter{active: make(map[string]int), peak: make(map[string]int)}
+}
+
+// Отметить начало запроса
+func (c *inFlightCounter) enter(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active[key]++
+	if c.active[key] > c.peak[key] {
+		c.peak[key] = c.active[key]
+	}
+}
+
+// Отметить завершение запроса; возвращает максимальное число одновременных запросов,
+// пересекавшихся с этим запросом
+func (c *inFlightCounter) leave(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	peak := c.peak[key]
+	c.active[key]--
+	if c.active[key] <= 0 {
+		delete(c.active, key)
+		delete(c.peak, key)
+	}
+	return peak
+}
+
+// Счетчик событий в скользящем окне (попытки входа, отправки форм и т.п.)
+type hitCounter struct {
+	mu    sync.Mutex
+	hits  map[string]hitWindow
+	swept time.Time
+}
+
+// События одного ключа и окно, с которым их считали
+type hitWindow struct {
+	times  []time.Time
+	window time.Duration
+}
+
+const (
+	hitsPerKey          = 1000        // Событий одного ключа в окне; сверх этого счетчик не растет
+	hitCounterSweepTime = time.Minute // Как часто удалять ключи с пустым окном
+)
+
+func newHitCounter() *hitCounter {
+	return &hitCounter{hits: make(map[string]hitWindow)}
+}
+
+// Зарегистрировать событие; возвращает число событий за последний window
+func (c *hitCounter) Hit(key string, window time.Duration) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.sweep(now)
+	recent := c.hits[key].times[:0]
+	for _, t := range c.hits[key].times {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
 		}
-		first = false
-		json += `  "` + k + `": `
-		switch val := v.(type) {
-		case string:
-			json += `"` + val + `"`
-		case int:
-			json += fmt.Sprintf("%d", val)
-		case []map[string]string:
-			json += "["
-			for i, item := range val {
-				if i > 0 {
-					json += ", "
-				}
-				json += "{"
-				firstItem := true
-				for k2, v2 := range item {
-					if !firstItem {
-						json += ", "
-					}
-					firstItem = false
-					json += `"` + k2 + `": "` + v2 + `"`
-				}
-				json += "}"
-			}
-			json += "]"
-		case map[string]string:
-			json += "{"
-			firstItem := true
-			for k2, v2 := range val {
-				if !firstItem {
-					json += ", "
-				}
-				firstItem = false
-				json += `"` + k2 + `": "` + v2 + `"`
-			}
-			json += "}"
-		default:
-			json += `"` + fmt.Sprintf("%v", val) + `"`
+	}
+	if len(recent) >= hitsPerKey {
+		recent = recent[len(recent)-hitsPerKey+1:]
+	}
+	recent = append(recent, now)
+	c.hits[key] = hitWindow{times: recent, window: window}
+	return len(recent)
+}
+
+// Удалить ключи, у которых в окне не осталось событий; не чаще раза в hitCounterSweepTime
+func (c *hitCounter) sweep(now time.Time) {
+	if now.Sub(c.swept) < hitCounterSweepTime {
+		return
+	}
+	c.swept = now
+	for key, h := range c.hits {
+		if now.Sub(h.times[len(h.times)-1]) >= h.window {
+			delete(c.hits, key)
 		}
 	}
-	json += "\n}"
-	w.Write([]byte(json))
 }
 
+// Сбросить счетчик
+func (c *hitCounter) Reset(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.hits, key)
+}
+
+// Мьютексы по ключу: запросы одного учащегося выполняются по очереди.
+// Мьютекс ключа удаляется, когда его никто не держит и не ждет
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*refMutex
+}
+
+type refMutex struct {
+	sync.Mutex
+	refs int // Держит и ждут блокировку
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: make(map[string]*refMutex)}
+}
+
+// Захватить блокировку ключа; возвращает функцию освобождения
+func (m *keyedMutex) Lock(key string) func() {
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &refMutex{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		m.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
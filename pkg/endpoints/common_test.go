@@ -0,0 +1,70 @@
+package endpoints
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHitCounter(t *testing.T) {
+	c := newHitCounter()
+	for i := 1; i <= 3; i++ {
+		if n := c.Hit("a", time.Minute); n != i {
+			t.Fatalf("hit %d: count %d", i, n)
+		}
+	}
+	for i := 0; i < hitsPerKey+10; i++ {
+		c.Hit("b", time.Minute)
+	}
+	if n := len(c.hits["b"].times); n != hitsPerKey {
+		t.Errorf("%d hits stored, want %d", n, hitsPerKey)
+	}
+
+	// Ключи с пустым окном удаляются при очередной очистке
+	c.Hit("old", time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	c.swept = time.Time{}
+	c.Hit("a", time.Minute)
+	if _, ok := c.hits["old"]; ok {
+		t.Error("expired key kept")
+	}
+	if n := c.Hit("a", time.Minute); n != 5 {
+		t.Errorf("count %d after sweep, want 5", n)
+	}
+}
+
+func TestKeyedMutex(t *testing.T) {
+	m := newKeyedMutex()
+	var wg sync.WaitGroup
+	counter := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := m.Lock("a")
+			counter++
+			unlock()
+		}()
+	}
+	wg.Wait()
+	if counter != 50 {
+		t.Errorf("counter %d", counter)
+	}
+	if len(m.locks) != 0 {
+		t.Errorf("%d mutexes left after unlock", len(m.locks))
+	}
+
+	unlock := m.Lock("b")
+	locked := make(chan struct{})
+	go func() {
+		defer m.Lock("b")()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("second Lock did not wait")
+	case <-time.After(10 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+}
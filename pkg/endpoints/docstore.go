@@ -0,0 +1,362 @@
+package endpoints
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Документное хранилище в стиле MongoDB для заданий NoSQL Injection. Коллекция users
+// строится из данных стенда учащегося, фильтр - JSON-объект с операторами
+// $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $regex, $exists, $not, $or, $and, $nor
+// и $where. В $where вместо JavaScript - ограниченное подмножество выражений
+// (сравнения, &&, ||, !, this.field, .length, .startsWith, .endsWith, .includes, .match):
+// циклов и вызовов функций нет, поэтому зависнуть на нем сервер не может
+
+type document map[string]interface{}
+
+var (
+	errBadFilter = errors.New("filter must be a JSON object")
+	errWhereSize = errors.New("$where expression is too long")
+)
+
+const whereMaxLen = 512
+
+// Коллекция users учащегося. Пароль и ключ API хранятся в документе, в выдачу не попадают
+func usersCollection(learner string, d *domain) []document {
+	var docs []document
+	for _, u := range d.Users() {
+		docs = append(docs, document{
+			"_id":      u.ID,
+			"username": u.Username,
+			"name":     u.Name,
+			"email":    u.Email,
+			"role":     u.Role,
+			"password": u.Password,
+			"balance":  float64(u.Balance),
+			"api_key":  userAPIKey(learner, u),
+		})
+	}
+	return docs
+}
+
+// Ключ API администратора - флаг задания a05_5, у остальных - случайный на вид ключ
+func userAPIKey(learner string, u account) string {
+	if u.Role == "admin" {
+		return challengeFlag(learner, "a05_5")
+	}
+	mac := hmac.New(sha256.New, serverKey("api_key"))
+	mac.Write([]byte(learner + ":" + u.ID))
+	return "sk_live_" + hex.EncodeToString(mac.Sum(nil))[:24]
+}
+
+// Публичные поля документа
+func (doc document) public() map[string]interface{} {
+	return map[string]interface{}{"_id": doc["_id"], "username": doc["username"], "role": doc["role"]}
+}
+
+// Документы, подходящие под фильтр
+func findDocuments(docs []document, filter map[string]interface{}) ([]document, error) {
+	var found []document
+	for _, doc := range docs {
+		ok, err := matchFilter(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			found = append(found, doc)
+		}
+	}
+	return found, nil
+}
+
+func matchFilter(doc document, filter map[string]interface{}) (bool, error) {
+	// Порядок ключей фиксирован, чтобы ошибки были воспроизводимыми
+	keys := make([]string, 0, len(filter))
+	for k := range filter {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		cond := filter[key]
+		var ok bool
+		var err error
+		switch key {
+		case "$or", "$and", "$nor":
+			ok, err = matchLogical(doc, key, cond)
+		case "$where":
+			expr, isString := cond.(string)
+			if !isString {
+				return false, errors.New("$where must be a string")
+			}
+			ok, err = evalWhere(doc, expr)
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("unknown top level operator: %s", key)
+			}
+			value, exists := doc[key]
+			ok, err = matchField(value, exists, cond)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchLogical(doc document, op string, cond interface{}) (bool, error) {
+	list, ok := cond.([]interface{})
+	if !ok || len(list) == 0 {
+		return false, fmt.Errorf("%s must be a nonempty array", op)
+	}
+	matched := 0
+	for _, item := range list {
+		sub, ok := item.(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s entries must be objects", op)
+		}
+		m, err := matchFilter(doc, sub)
+		if err != nil {
+			return false, err
+		}
+		if m {
+			matched++
+		}
+	}
+	switch op {
+	case "$or":
+		return matched > 0, nil
+	case "$and":
+		return matched == len(list), nil
+	}
+	return matched == 0, nil
+}
+
+// Условие на поле: значение для сравнения или объект с операторами
+func matchField(value interface{}, exists bool, cond interface{}) (bool, error) {
+	ops, ok := cond.(map[string]interface{})
+	if !ok || !hasOperator(ops) {
+		return valuesEqual(value, cond), nil
+	}
+	for op, arg := range ops {
+		var m bool
+		switch op {
+		case "$eq":
+			m = valuesEqual(value, arg)
+		case "$ne":
+			m = !valuesEqual(value, arg)
+		case "$gt", "$gte", "$lt", "$lte":
+			c, comparable := compareValues(value, arg)
+			m = comparable && (op == "$gt" && c > 0 || op == "$gte" && c >= 0 || op == "$lt" && c < 0 || op == "$lte" && c <= 0)
+		case "$in", "$nin":
+			list, isList := arg.([]interface{})
+			if !isList {
+				return false, fmt.Errorf("%s needs an array", op)
+			}
+			for _, item := range list {
+				if valuesEqual(value, item) {
+					m = true
+					break
+				}
+			}
+			if op == "$nin" {
+				m = !m
+			}
+		case "$regex":
+			options, _ := ops["$options"].(string)
+			re, err := compileMongoRegex(arg, options)
+			if err != nil {
+				return false, err
+			}
+			s, isString := value.(string)
+			m = isString && re.MatchString(s)
+		case "$options":
+			continue
+		case "$exists":
+			m = exists == truthy(arg)
+		case "$not":
+			inner, err := matchField(value, exists, arg)
+			if err != nil {
+				return false, err
+			}
+			m = !inner
+		default:
+			return false, fmt.Errorf("unknown operator: %s", op)
+		}
+		if !m {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func hasOperator(ops map[string]interface{}) bool {
+	for k := range ops {
+		if strings.HasPrefix(k, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// Флаги i, m, s, x как у MongoDB
+func compileMongoRegex(arg interface{}, options string) (*regexp.Regexp, error) {
+	pattern, ok := arg.(string)
+	if !ok {
+		return nil, errors.New("$regex has to be a string")
+	}
+	flags := ""
+	for _, o := range options {
+		switch o {
+		case 'i', 'm', 's':
+			flags += string(o)
+		case 'x':
+			pattern = regexp.MustCompile(`\s+|#.*`).ReplaceAllString(pattern, "")
+		default:
+			return nil, fmt.Errorf("invalid flag in regex options: %c", o)
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("$regex: %v", err)
+	}
+	return re, nil
+}
+
+// null равен отсутствующему полю; числа сравниваются по значению
+func valuesEqual(a, b interface{}) bool {
+	if c, ok := compareValues(a, b); ok {
+		return c == 0
+	}
+	return a == nil && b == nil
+}
+
+// Сравнение значений одного типа (числа или строки); false - типы несравнимы
+func compareValues(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok && x == y {
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// Значение в логическом контексте, как в JavaScript
+func truthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0 && !math.IsNaN(x)
+	case string:
+		return x != ""
+	}
+	return true
+}
+
+// Параметры формы или строки запроса во вложенные объекты, как это делают qs (Express)
+// и PHP: password[$ne]=x -> {"password": {"$ne": "x"}}, role[$in][]=a -> массив,
+// $or[0][username]=admin -> массив объектов
+func decodeBracketParams(values url.Values) map[string]interface{} {
+	root := make(map[string]interface{})
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		path := bracketPath(key)
+		for _, v := range values[key] {
+			setBracketValue(root, path, v)
+		}
+	}
+	for k, child := range root {
+		root[k] = arraysFromIndexes(child)
+	}
+	return root
+}
+
+// a[b][] -> [a b ""]
+func bracketPath(key string) []string {
+	i := strings.IndexByte(key, '[')
+	if i <= 0 || !strings.HasSuffix(key, "]") {
+		return []string{key}
+	}
+	path := []string{key[:i]}
+	for _, part := range strings.Split(key[i+1:len(key)-1], "][") {
+		path = append(path, part)
+	}
+	return path
+}
+
+// Пустой сегмент [] добавляет элемент: индекс - следующий номер
+func setBracketValue(node map[string]interface{}, path []string, value string) {
+	for i, seg := range path {
+		if seg == "" {
+			seg = strconv.Itoa(len(node))
+		}
+		if i == len(path)-1 {
+			if _, exists := node[seg]; !exists {
+				node[seg] = value
+			}
+			return
+		}
+		child, ok := node[seg].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			node[seg] = child
+		}
+		node = child
+	}
+}
+
+// Объекты, у которых все ключи - индексы 0..n-1, становятся массивами
+func arraysFromIndexes(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	for k, child := range m {
+		m[k] = arraysFromIndexes(child)
+	}
+	if len(m) == 0 {
+		return m
+	}
+	list := make([]interface{}, len(m))
+	for k, child := range m {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(m) || strconv.Itoa(i) != k {
+			return m
+		}
+		list[i] = child
+	}
+	return list
+}
@@ -0,0 +1,513 @@
+package endpoints
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Вычисление $where. Поддерживается подмножество JavaScript без циклов, присваиваний
+// и произвольных вызовов: литералы, this/obj, доступ к полям, сравнения (== === != !== < <= > >=),
+// ! && || и скобки, строковые методы length, startsWith, endsWith, includes, indexOf,
+// charAt, toLowerCase, toUpperCase, match и test у регулярных выражений.
+// Все остальное (sleep, while, function) - ошибка.
+//
+// Тот же интерпретатор выполняет "скрипты" в задании на Code Injection (evalScript):
+// последовательность выражений через ; с глобальными объектами вроде process и console
+
+type whereToken struct {
+	kind  byte // n - число, s - строка, r - регулярное выражение, i - имя, p - знак
+	text  string
+	value interface{}
+}
+
+func evalWhere(doc document, expr string) (bool, error) {
+	if len(expr) > whereMaxLen {
+		return false, errWhereSize
+	}
+	tokens, err := lexWhere(expr)
+	if err != nil {
+		return false, fmt.Errorf("$where: %v", err)
+	}
+	p := &whereParser{tokens: tokens, doc: doc}
+	// Как и MongoDB, принимаем и выражение, и тело функции с return
+	if p.peek("return") {
+		p.pos++
+	}
+	v, err := p.or()
+	if err == nil && p.peek(";") {
+		p.pos++
+	}
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return false, fmt.Errorf("$where: %v", err)
+	}
+	return truthy(v), nil
+}
+
+// Функция, доступная скрипту как метод глобального объекта (console.log)
+type whereFunc func(args []interface{}) (interface{}, error)
+
+// Выполнить выражения, разделенные ;, и вернуть значение последнего.
+// Имена из globals доступны скрипту как переменные
+func evalScript(code string, globals document) (interface{}, error) {
+	if len(code) > whereMaxLen {
+		return nil, errWhereSize
+	}
+	tokens, err := lexWhere(code)
+	if err != nil {
+		return nil, err
+	}
+	p := &whereParser{tokens: tokens, globals: globals}
+	var v interface{}
+	for p.pos < len(p.tokens) {
+		if v, err = p.or(); err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.tokens) {
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+var wherePunct = []string{"===", "!==", "==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ".", ",", ";"}
+
+func lexWhere(s string) ([]whereToken, error) {
+	var tokens []whereToken
+	operand := true // Ожидается значение: / начинает регулярное выражение
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			n, err := strconv.ParseFloat(s[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad number %q", s[i:j])
+			}
+			tokens = append(tokens, whereToken{kind: 'n', text: s[i:j], value: n})
+			i, operand = j, false
+		case c == '\'' || c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(s) && s[j] != c; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, whereToken{kind: 's', text: s[i : j+1], value: b.String()})
+			i, operand = j+1, false
+		case c == '/' && operand:
+			j := i + 1
+			for ; j < len(s) && s[j] != '/'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated regular expression")
+			}
+			k := j + 1
+			for k < len(s) && isASCIILetter(s[k]) {
+				k++
+			}
+			re, err := compileMongoRegex(s[i+1:j], s[j+1:k])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, whereToken{kind: 'r', text: s[i:k], value: re})
+			i, operand = k, false
+		case isASCIILetter(c) || c == '_' || c == '$':
+			j := i
+			for j < len(s) && (isASCIILetter(s[j]) || s[j] >= '0' && s[j] <= '9' || s[j] == '_' || s[j] == '$') {
+				j++
+			}
+			tokens = append(tokens, whereToken{kind: 'i', text: s[i:j]})
+			i, operand = j, false
+		default:
+			found := false
+			for _, p := range wherePunct {
+				if strings.HasPrefix(s[i:], p) {
+					tokens = append(tokens, whereToken{kind: 'p', text: p})
+					i += len(p)
+					operand = p != ")" && p != "]"
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+type whereParser struct {
+	tokens  []whereToken
+	pos     int
+	doc     document
+	globals document // Глобальные имена скрипта (evalScript)
+	skip    int      // > 0 - правый операнд && или || только разбирается, но не вычисляется
+}
+
+func (p *whereParser) peek(text string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].text == text
+}
+
+func (p *whereParser) expect(text string) error {
+	if !p.peek(text) {
+		return fmt.Errorf("expected %q", text)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *whereParser) or() (interface{}, error) {
+	left, err := p.and()
+	for err == nil && p.peek("||") {
+		p.pos++
+		var right interface{}
+		if truthy(left) {
+			p.skip++
+			_, err = p.and()
+			p.skip--
+			continue
+		}
+		right, err = p.and()
+		left = right
+	}
+	return left, err
+}
+
+func (p *whereParser) and() (interface{}, error) {
+	left, err := p.compare()
+	for err == nil && p.peek("&&") {
+		p.pos++
+		var right interface{}
+		if !truthy(left) {
+			p.skip++
+			_, err = p.compare()
+			p.skip--
+			continue
+		}
+		right, err = p.compare()
+		left = right
+	}
+	return left, err
+}
+
+func (p *whereParser) compare() (interface{}, error) {
+	left, err := p.unary()
+	if err != nil || p.pos >= len(p.tokens) {
+		return left, err
+	}
+	op := p.tokens[p.pos].text
+	switch op {
+	case "==", "===", "!=", "!==", "<", "<=", ">", ">=":
+	default:
+		return left, nil
+	}
+	p.pos++
+	right, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "==":
+		return looseEqual(left, right), nil
+	case "!=":
+		return !looseEqual(left, right), nil
+	case "===":
+		return strictEqual(left, right), nil
+	case "!==":
+		return !strictEqual(left, right), nil
+	}
+	c, ok := jsCompare(left, right)
+	if !ok {
+		return false, nil
+	}
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+// ! связывает сильнее сравнений: !a == b - это (!a) == b
+func (p *whereParser) unary() (interface{}, error) {
+	if p.peek("!") {
+		p.pos++
+		v, err := p.unary()
+		return !truthy(v), err
+	}
+	return p.postfix()
+}
+
+func (p *whereParser) postfix() (interface{}, error) {
+	v, err := p.primary()
+	for err == nil && p.pos < len(p.tokens) {
+		switch p.tokens[p.pos].text {
+		case ".":
+			p.pos++
+			if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != 'i' {
+				return nil, fmt.Errorf("expected property name")
+			}
+			name := p.tokens[p.pos].text
+			p.pos++
+			switch {
+			case p.peek("("):
+				v, err = p.call(v, name)
+			case p.skip > 0:
+				v = nil
+			default:
+				v, err = whereProperty(v, name)
+			}
+		case "[":
+			p.pos++
+			var index interface{}
+			index, err = p.or()
+			if err == nil {
+				err = p.expect("]")
+			}
+			if err == nil {
+				v = whereIndex(v, index)
+			}
+		default:
+			return v, nil
+		}
+	}
+	return v, err
+}
+
+func (p *whereParser) primary() (interface{}, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case 'n', 's', 'r':
+		return t.value, nil
+	case 'i':
+		switch t.text {
+		case "this", "obj":
+			return p.doc, nil
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null", "undefined":
+			return nil, nil
+		}
+		if v, ok := p.globals[t.text]; ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("%s is not allowed", t.text)
+	}
+	if t.text == "(" {
+		v, err := p.or()
+		if err == nil {
+			err = p.expect(")")
+		}
+		return v, err
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+func (p *whereParser) call(recv interface{}, name string) (interface{}, error) {
+	p.pos++ // (
+	var args []interface{}
+	for !p.peek(")") {
+		arg, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.peek(",") {
+			break
+		}
+		p.pos++
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if p.skip > 0 {
+		return nil, nil
+	}
+	arg := func(i int) interface{} {
+		if i < len(args) {
+			return args[i]
+		}
+		return nil
+	}
+
+	if obj, ok := recv.(document); ok {
+		if fn, ok := obj[name].(whereFunc); ok {
+			return fn(args)
+		}
+	}
+	if re, ok := recv.(*regexp.Regexp); ok && name == "test" {
+		return re.MatchString(jsString(arg(0))), nil
+	}
+	s, ok := recv.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a function", name)
+	}
+	switch name {
+	case "startsWith":
+		return strings.HasPrefix(s, jsString(arg(0))), nil
+	case "endsWith":
+		return strings.HasSuffix(s, jsString(arg(0))), nil
+	case "includes":
+		return strings.Contains(s, jsString(arg(0))), nil
+	case "indexOf":
+		return float64(strings.Index(s, jsString(arg(0)))), nil
+	case "charAt":
+		return whereIndex(s, arg(0)), nil
+	case "toLowerCase":
+		return strings.ToLower(s), nil
+	case "toUpperCase":
+		return strings.ToUpper(s), nil
+	case "match":
+		re, ok := arg(0).(*regexp.Regexp)
+		if !ok {
+			var err error
+			if re, err = regexp.Compile(jsString(arg(0))); err != nil {
+				return nil, err
+			}
+		}
+		if m := re.FindString(s); m != "" || re.MatchString(s) {
+			return []interface{}{m}, nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%s is not allowed", name)
+}
+
+func whereProperty(v interface{}, name string) (interface{}, error) {
+	switch x := v.(type) {
+	case document:
+		return x[name], nil
+	case string:
+		if name == "length" {
+			return float64(len(x)), nil
+		}
+	case []interface{}:
+		if name == "length" {
+			return float64(len(x)), nil
+		}
+	case nil:
+		return nil, fmt.Errorf("cannot read property %q of null", name)
+	}
+	return nil, nil
+}
+
+func whereIndex(v, index interface{}) interface{} {
+	switch x := v.(type) {
+	case document:
+		return x[jsString(index)]
+	case string:
+		i, ok := index.(float64)
+		if ok && i >= 0 && int(i) < len(x) && i == math.Trunc(i) {
+			return x[int(i) : int(i)+1]
+		}
+		return ""
+	}
+	return nil
+}
+
+func jsString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	return fmt.Sprint(v)
+}
+
+func jsNumber(v interface{}) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case bool:
+		if x {
+			return 1
+		}
+		return 0
+	case nil:
+		return 0
+	case string:
+		if strings.TrimSpace(x) == "" {
+			return 0
+		}
+		if n, err := strconv.ParseFloat(strings.TrimSpace(x), 64); err == nil {
+			return n
+		}
+	}
+	return math.NaN()
+}
+
+func strictEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	c, ok := compareValues(a, b)
+	return ok && c == 0
+}
+
+// == в JavaScript: null равен только null, остальное приводится к числу
+func looseEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if c, ok := compareValues(a, b); ok {
+		return c == 0
+	}
+	return jsNumber(a) == jsNumber(b)
+}
+
+// < и > в JavaScript: две строки - лексикографически, иначе как числа
+func jsCompare(a, b interface{}) (int, bool) {
+	if x, ok := a.(string); ok {
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	}
+	x, y := jsNumber(a), jsNumber(b)
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return 0, false
+	}
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	}
+	return 0, true
+}
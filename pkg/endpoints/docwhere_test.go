@@ -0,0 +1,78 @@
+package endpoints
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvalWhere(t *testing.T) {
+	doc := document{"username": "admin", "role": "admin", "age": float64(40)}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"this.username == 'admin'", true},
+		{"return this.role === 'user';", false},
+		{"this.age > 30 && this.username.startsWith('a')", true},
+		{"!this.missing", true},
+		{"this.username == 'x' || /^ad/.test(this.role)", true},
+		{"false && this.missing.length", false},
+		{"true || this.missing.length", true},
+		{"this.username.charAt(0) == 'a'", true},
+	}
+	for _, tt := range tests {
+		got, err := evalWhere(doc, tt.expr)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: %v, %v, want %v", tt.expr, got, err, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"sleep(1000)", "this.missing.length", "while(true){}", "this.username ==", "process.env"} {
+		if _, err := evalWhere(doc, expr); err == nil {
+			t.Errorf("%s: no error", expr)
+		}
+	}
+}
+
+func TestEvalScript(t *testing.T) {
+	var output []interface{}
+	globals := func() document {
+		output = nil
+		return document{
+			"process": document{"env": document{"FLAG": "FLAG{env}"}},
+			"console": document{"log": whereFunc(func(args []interface{}) (interface{}, error) {
+				output = append(output, args...)
+				return nil, nil
+			})},
+		}
+	}
+
+	tests := []struct {
+		code   string
+		result interface{}
+		output []interface{}
+	}{
+		{"console.log('Hello')", nil, []interface{}{"Hello"}},
+		{"process.env.FLAG", "FLAG{env}", nil},
+		{"console.log(process.env.FLAG); process.env.FLAG == 'x'", false, []interface{}{"FLAG{env}"}},
+		{"console.log('a', 'b'); process.env['FLAG'].length", float64(9), []interface{}{"a", "b"}},
+		{"process.env.FLAG.startsWith('FLAG{');", true, nil},
+		{"", nil, nil},
+	}
+	for _, tt := range tests {
+		result, err := evalScript(tt.code, globals())
+		if err != nil {
+			t.Errorf("%s: %v", tt.code, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.result) || !reflect.DeepEqual(output, tt.output) {
+			t.Errorf("%s: result %v output %v, want %v %v", tt.code, result, output, tt.result, tt.output)
+		}
+	}
+
+	for _, code := range []string{"require('fs')", "process.exit(1)", "console.log('a') console.log('b')", "eval('1')"} {
+		if _, err := evalScript(code, globals()); err == nil {
+			t.Errorf("%s: no error", code)
+		}
+	}
+}
@@ -1,147 +1,251 @@
 package endpoints
 
-import "net/http"
+import (
+	"net/http"
+	"path/filepath"
+)
 
 type endpoints struct {
-	addr string
-	r    *http.ServeMux
+	dataDir      string // Каталог для сохраняемых данных (прогресс учащихся и т.п.)
+	flagSecret   string // Секрет для флагов; пустой - из каталога данных
+	trainerToken string // Токен для смены режима; пустой - генерируется при старте
+	r            *http.ServeMux
+	handler      http.Handler
 }
 
-func New(addr string, r *http.ServeMux) *endpoints {
-	return &endpoints{addr: addr, r: r}
+// Настройки эндпоинтов из конфигурации сервера
+type Options struct {
+	DataDir      string
+	FlagSecret   string
+	TrainerToken string
 }
 
-func (e *endpoints) FillEndpoints() {
+func New(opts Options, r *http.ServeMux) *endpoints {
+	return &endpoints{dataDir: opts.DataDir, flagSecret: opts.FlagSecret, trainerToken: opts.TrainerToken, r: r}
+}
+
+func (e *endpoints) FillEndpoints() error {
+	// Каталог заданий разбирается и проверяется один раз при старте
+	challenges, err := loadCatalog(catalogFS)
+	if err != nil {
+		return err
+	}
+	catalog = challenges
+
+	// Секрет для флагов тоже сохраняется, иначе после перезапуска флаги изменятся
+	if err := loadFlagSecret(filepath.Join(e.dataDir, "flag_secret"), e.flagSecret); err != nil {
+		return err
+	}
+	if err := loadTrainerToken(e.trainerToken); err != nil {
+		return err
+	}
+	// Прогресс учащихся переживает перезапуск сервера
+	if err := progress.Load(filepath.Join(e.dataDir, "progress.json")); err != nil {
+		return err
+	}
+	if err := teams.Load(filepath.Join(e.dataDir, "teams.json")); err != nil {
+		return err
+	}
+	// Настройки соревнования; без файла работает режим тренировки
+	cfg, err := loadEvent(filepath.Join(e.dataDir, "event.json"))
+	if err != nil {
+		return err
+	}
+	event = cfg
+
 	// Главная страница
 	e.r.HandleFunc("/", index)
 	// Страница с объяснениями
 	e.r.HandleFunc("/explanations", explanationsPage)
 	// Страницы с заданиями для уязвимостей
 	e.r.HandleFunc("/challenge/", challengePage)
+	// Открытие подсказок к заданиям
+	e.r.HandleFunc("/hint/", hintUnlock)
+	// Таблица результатов соревнования
+	e.r.HandleFunc("/scoreboard", scoreboardPage)
+	e.r.HandleFunc("/api/scoreboard", apiScoreboard)
+	e.r.HandleFunc("/team", teamPage)
+	// Переключение уязвимого и исправленного режимов
+	e.r.HandleFunc("/mode", modePage)
+	e.r.HandleFunc("/api/mode", apiMode)
+	// Данные стенда учащегося и их сброс
+	e.r.HandleFunc("/api/lab/data", apiLabData)
+	e.r.HandleFunc("/api/lab/reset", apiLabReset)
+	// Сервер учащегося в сети стенда (oob.lab) для out-of-band атак
+	e.r.HandleFunc("/api/lab/oob", apiLabOOB)
+	// Сборщик учащегося для кражи cookie через XSS и отчеты бота-модератора
+	e.r.HandleFunc(collectorPath, apiLabCollector)
+	e.r.HandleFunc(collectorPath+"/", apiLabCollect)
+	// Словари для офлайн-перебора и настройки проверки JWT
+	e.r.Handle("/api/lab/wordlists/", apiLabWordlists())
+	e.r.HandleFunc("/api/lab/jwt", apiLabJWT)
+	e.r.HandleFunc("/api/lab/sessions", apiLabSessions)
+	e.r.HandleFunc("/api/lab/lockout", apiLabLockout)
+	e.r.HandleFunc("/api/lab/mfa", apiLabMFA)
+	e.r.HandleFunc("/api/lab/password-reset", apiLabPasswordReset)
+	e.r.HandleFunc(mailPath, apiLabMail)
+	e.r.HandleFunc(mailPath+"/", apiLabMail)
 
 	// A01: Broken Access Control (10 эндпоинтов)
-	e.r.HandleFunc("/api/v1/users/", apiV1UsersID)
-	e.r.HandleFunc("/api/v1/admin/users", apiV1AdminUsers)
-	e.r.HandleFunc("/api/v1/auth/login", apiV1AuthLoginRedirect)
-	e.r.HandleFunc("/api/v1/auth/verify", apiV1AuthVerifyJWT)
-	e.r.HandleFunc("/api/v1/files", apiV1Files)
-	e.r.HandleFunc("/api/v1/admin/config", apiV1AdminConfig)
-	e.r.HandleFunc("/api/v1/user/profile", apiV1UserProfile)
-	e.r.HandleFunc("/api/v1/payment/transfer", apiV1PaymentTransferRace)
-	e.r.HandleFunc("/api/v1/admin/dashboard", apiV1AdminDashboard)
-	e.r.HandleFunc("/api/v1/user/settings", apiV1UserSettings)
+	e.handleLab("/api/v1/users/", "a01_1", apiV1UsersID, apiV1UsersIDSecure)
+	e.handleLab("/api/v1/admin/users", "a01_2", apiV1AdminUsers, apiV1AdminUsersSecure)
+	e.handleLab("/api/v1/auth/login", "a01_3", apiV1AuthLoginRedirect, apiV1AuthLoginRedirectSecure)
+	e.handleLab("/api/v1/auth/verify", "a01_4", apiV1AuthVerifyJWT, apiV1AuthVerifyJWTSecure)
+	e.r.HandleFunc("/api/v1/auth/jwt/login", apiV1AuthJWTLogin)
+	e.r.HandleFunc("/.well-known/jwks.json", apiJWKS)
+	e.r.HandleFunc("/api/v1/auth/public.pem", apiJWTPublicPEM)
+	e.handleLab("/api/v1/files", "a01_5", apiV1Files, apiV1FilesSecure)
+	e.handleLab("/api/v1/admin/config", "a01_6", apiV1AdminConfig, apiV1AdminConfigSecure)
+	e.handleLab("/api/v1/user/profile", "a01_7", apiV1UserProfile, apiV1UserProfileSecure)
+	e.handleLab("/api/v1/payment/transfer", "a01_8", apiV1PaymentTransferRace, apiV1PaymentTransferRaceSecure)
+	e.handleLab("/api/v1/admin/dashboard", "a01_9", apiV1AdminDashboard, apiV1AdminDashboardSecure)
+	e.handleLab("/api/v1/user/settings", "a01_10", apiV1UserSettings, apiV1UserSettingsSecure)
 
 	// A02: Security Misconfiguration (10 эндпоинтов)
-	e.r.HandleFunc("/.env", apiV1ConfigEnv)
-	e.r.HandleFunc("/api/v1/debug/users/search", apiV1UsersSearchDebug)
-	e.r.HandleFunc("/metrics", apiV1Metrics)
-	e.r.HandleFunc("/.git/config", apiV1GitConfig)
-	e.r.HandleFunc("/api/v1/api/data", apiV1ApiData)
-	e.r.HandleFunc("/api/v1/health", apiV1Health)
-	e.r.HandleFunc("/api/v1/auth/session", apiV1AuthSession)
-	e.r.HandleFunc("/api/v1/backup", apiV1Backup)
-	e.r.HandleFunc("/api/v1/logs", apiV1Logs)
-	e.r.HandleFunc("/api/v1/config/database", apiV1ConfigDatabase)
+	e.handleLab("/.env", "a02_1", apiV1ConfigEnv, apiV1ConfigEnvSecure)
+	e.handleLab("/api/v1/debug/users/search", "a02_2", apiV1UsersSearchDebug, apiV1UsersSearchDebugSecure)
+	e.handleLab("/metrics", "a02_3", apiV1Metrics, apiV1MetricsSecure)
+	e.handleLab("/.git/config", "a02_4", apiV1GitConfig, apiV1GitConfigSecure)
+	e.handleLab("/api/v1/api/data", "a02_5", apiV1ApiData, apiV1ApiDataSecure)
+	e.handleLab("/api/v1/health", "a02_6", apiV1Health, apiV1HealthSecure)
+	e.handleLab("/api/v1/auth/session", "a02_7", apiV1AuthSession, apiV1AuthSessionSecure)
+	e.handleLab("/api/v1/backup", "a02_8", apiV1Backup, apiV1BackupSecure)
+	e.handleLab("/api/v1/logs", "a02_9", apiV1Logs, apiV1LogsSecure)
+	e.handleLab("/api/v1/config/database", "a02_10", apiV1ConfigDatabase, apiV1ConfigDatabaseSecure)
 
 	// A03: Software Supply Chain (10 эндпоинтов)
-	e.r.HandleFunc("/api/v1/packages/install", apiV1PackagesInstall)
-	e.r.HandleFunc("/api/v1/dependencies/update", apiV1DependenciesUpdate)
-	e.r.HandleFunc("/api/v1/build", apiV1Build)
-	e.r.HandleFunc("/api/v1/update", apiV1Update)
-	e.r.HandleFunc("/api/v1/dependencies/list", apiV1DependenciesList)
-	e.r.HandleFunc("/api/v1/packages/search", apiV1PackagesSearch)
-	e.r.HandleFunc("/api/v1/repo/clone", apiV1RepoClone)
-	e.r.HandleFunc("/api/v1/webhook/update", apiV1WebhookUpdate)
-	e.r.HandleFunc("/api/v1/package/registry", apiV1PackageRegistry)
-	e.r.HandleFunc("/api/v1/dependencies/tree", apiV1DependenciesTree)
+	e.handleLab("/api/v1/packages/install", "a03_1", apiV1PackagesInstall, apiV1PackagesInstallSecure)
+	e.handleLab("/api/v1/dependencies/update", "a03_2", apiV1DependenciesUpdate, apiV1DependenciesUpdateSecure)
+	e.handleLab("/api/v1/build", "a03_3", apiV1Build, apiV1BuildSecure)
+	e.handleLab("/api/v1/update", "a03_4", apiV1Update, apiV1UpdateSecure)
+	e.handleLab("/api/v1/dependencies/list", "a03_5", apiV1DependenciesList, apiV1DependenciesListSecure)
+	e.handleLab("/api/v1/packages/search", "a03_6", apiV1PackagesSearch, apiV1PackagesSearchSecure)
+	e.handleLab("/api/v1/repo/clone", "a03_7", apiV1RepoClone, apiV1RepoCloneSecure)
+	e.handleLab("/api/v1/webhook/update", "a03_8", apiV1WebhookUpdate, apiV1WebhookUpdateSecure)
+	e.handleLab("/api/v1/package/registry", "a03_9", apiV1PackageRegistry, apiV1PackageRegistrySecure)
+	e.handleLab("/api/v1/dependencies/tree", "a03_10", apiV1DependenciesTree, apiV1DependenciesTreeSecure)
 
 	// A04: Cryptographic Failures (10 эндпоинтов)
-	e.r.HandleFunc("/api/v1/users/password", apiV1UsersPasswordPlain)
-	e.r.HandleFunc("/api/v1/auth/hash", apiV1AuthHash)
-	e.r.HandleFunc("/api/v1/api/sign", apiV1ApiSign)
-	e.r.HandleFunc("/api/v1/encrypt", apiV1Encrypt)
-	e.r.HandleFunc("/api/v1/config/keys", apiV1ConfigKeys)
-	e.r.HandleFunc("/api/v1/payment/process", apiV1PaymentProcessHTTP)
-	e.r.HandleFunc("/api/v1/auth/token", apiV1AuthToken)
-	e.r.HandleFunc("/api/v1/key/exchange", apiV1KeyExchange)
-	e.r.HandleFunc("/api/v1/external/api", apiV1ExternalApi)
-	e.r.HandleFunc("/api/v1/api/call", apiV1ApiCall)
+	e.handleLab("/api/v1/users/password", "a04_1", apiV1UsersPasswordPlain, apiV1UsersPasswordPlainSecure)
+	e.handleLab("/api/v1/auth/hash", "a04_2", apiV1AuthHash, apiV1AuthHashSecure)
+	e.handleLab("/api/v1/api/sign", "a04_3", apiV1ApiSign, apiV1ApiSignSecure)
+	e.handleLab("/api/v1/encrypt", "a04_4", apiV1Encrypt, apiV1EncryptSecure)
+	e.handleLab("/api/v1/config/keys", "a04_5", apiV1ConfigKeys, apiV1ConfigKeysSecure)
+	e.handleLab("/api/v1/payment/process", "a04_6", apiV1PaymentProcessHTTP, apiV1PaymentProcessHTTPSecure)
+	e.handleLab("/api/v1/auth/token", "a04_7", apiV1AuthToken, apiV1AuthTokenSecure)
+	e.handleLab("/api/v1/key/exchange", "a04_8", apiV1KeyExchange, apiV1KeyExchangeSecure)
+	e.handleLab("/api/v1/external/api", "a04_9", apiV1ExternalApi, apiV1ExternalApiSecure)
+	e.handleLab("/api/v1/api/call", "a04_10", apiV1ApiCall, apiV1ApiCallSecure)
 
-	// A05: Injection (10 эндпоинтов)
-	e.r.HandleFunc("/api/v1/users/search", apiV1UsersSearchSQL)
-	e.r.HandleFunc("/api/v1/network/ping", apiV1NetworkPing)
-	e.r.HandleFunc("/api/v1/comments", apiV1Comments)
-	e.r.HandleFunc("/api/v1/ldap/search", apiV1LdapSearch)
-	e.r.HandleFunc("/api/v1/users/find", apiV1UsersFind)
-	e.r.HandleFunc("/api/v1/render", apiV1Render)
-	e.r.HandleFunc("/api/v1/xml/parse", apiV1XmlParse)
-	e.r.HandleFunc("/api/v1/files/download", apiV1FilesDownload)
-	e.r.HandleFunc("/api/v1/webhook", apiV1Webhook)
-	e.r.HandleFunc("/api/v1/execute", apiV1Execute)
+	// A05: Injection (21 эндпоинт)
+	e.handleLab("/api/v1/users/search", "a05_1", apiV1UsersSearchSQL, apiV1UsersSearchSQLSecure)
+	e.handleLab("/api/v1/network/ping", "a05_2", apiV1NetworkPing, apiV1NetworkPingSecure)
+	e.handleLab("/api/v1/comments", "a05_3", apiV1Comments, apiV1CommentsSecure)
+	e.r.HandleFunc(moderationRoute, switchable("a05_3", apiV1AdminComments, apiV1AdminCommentsSecure))
+	e.handleLab("/api/v1/ldap/search", "a05_4", apiV1LdapSearch, apiV1LdapSearchSecure)
+	e.handleLab("/api/v1/users/find", "a05_5", apiV1UsersFind, apiV1UsersFindSecure)
+	e.handleLab("/api/v1/render", "a05_6", apiV1Render, apiV1RenderSecure)
+	e.handleLab("/api/v1/xml/parse", "a05_7", apiV1XmlParse, apiV1XmlParseSecure)
+	e.handleLab("/api/v1/files/download", "a05_8", apiV1FilesDownload, apiV1FilesDownloadSecure)
+	e.handleLab("/api/v1/webhook", "a05_9", apiV1Webhook, apiV1WebhookSecure)
+	e.handleLab("/api/v1/execute", "a05_10", apiV1Execute, apiV1ExecuteSecure)
+	e.handleLab("/api/v1/profile/export", "a05_11", apiV1ProfileExport, apiV1ProfileExportSecure)
+	e.handleLab("/api/v1/orders/search", "a05_12", apiV1OrdersSearch, apiV1OrdersSearchSecure)
+	e.handleLab("/api/v1/users/exists", "a05_13", apiV1UsersExists, apiV1UsersExistsSecure)
+	e.handleLab("/api/v1/orders/track", "a05_14", apiV1OrdersTrack, apiV1OrdersTrackSecure)
+	e.handleLab("/api/v1/render/email", "a05_15", apiV1RenderEmail, apiV1RenderEmailSecure)
+	e.handleLab("/api/v1/render/page", "a05_16", apiV1RenderPage, apiV1RenderPageSecure)
+	e.handleLab("/api/v1/xml/import", "a05_17", apiV1XmlImport, apiV1XmlImportSecure)
+	e.handleLab("/api/v1/link/preview", "a05_18", apiV1LinkPreview, apiV1LinkPreviewSecure)
+	e.handleLab("/api/v1/avatar/import", "a05_19", apiV1AvatarImport, apiV1AvatarImportSecure)
+	e.handleLab("/api/v1/feed/fetch", "a05_20", apiV1FeedFetch, apiV1FeedFetchSecure)
+	e.handleLab("/api/v1/accounts/login", "a05_21", apiV1AccountsLogin, apiV1AccountsLoginSecure)
 
 	// A06: Insecure Design (10 эндпоинтов)
-	e.r.HandleFunc("/api/v1/a06/auth/login", apiV1AuthLoginNoRateLimit)
-	e.r.HandleFunc("/api/v1/users/register", apiV1UsersRegister)
-	e.r.HandleFunc("/api/v1/contact", apiV1Contact)
-	e.r.HandleFunc("/api/v1/a06/users/delete", apiV1UsersDeleteGET)
-	e.r.HandleFunc("/api/v1/a06/payment/transfer", apiV1PaymentTransferNoCheck)
-	e.r.HandleFunc("/api/v1/a06/users/password", apiV1UsersPasswordWeak)
-	e.r.HandleFunc("/api/v1/a06/auth/verify", apiV1AuthVerifyNo2FA)
-	e.r.HandleFunc("/api/v1/a06/session/create", apiV1SessionCreateInsecure)
-	e.r.HandleFunc("/api/v1/admin/action", apiV1AdminAction)
-	e.r.HandleFunc("/api/v1/a06/password/reset", apiV1PasswordResetInsecure)
+	e.handleLab("/api/v1/a06/auth/login", "a06_1", apiV1AuthLoginNoRateLimit, apiV1AuthLoginNoRateLimitSecure)
+	e.handleLab("/api/v1/users/register", "a06_2", apiV1UsersRegister, apiV1UsersRegisterSecure)
+	e.handleLab("/api/v1/contact", "a06_3", apiV1Contact, apiV1ContactSecure)
+	e.handleLab("/api/v1/a06/users/delete", "a06_4", apiV1UsersDeleteGET, apiV1UsersDeleteGETSecure)
+	e.handleLab("/api/v1/a06/payment/transfer", "a06_5", apiV1PaymentTransferNoCheck, apiV1PaymentTransferNoCheckSecure)
+	e.handleLab("/api/v1/a06/users/password", "a06_6", apiV1UsersPasswordWeak, apiV1UsersPasswordWeakSecure)
+	e.handleLab("/api/v1/a06/auth/verify", "a06_7", apiV1AuthVerifyNo2FA, apiV1AuthVerifyNo2FASecure)
+	e.handleLab("/api/v1/a06/session/create", "a06_8", apiV1SessionCreateInsecure, apiV1SessionCreateInsecureSecure)
+	e.handleLab("/api/v1/admin/action", "a06_9", apiV1AdminAction, apiV1AdminActionSecure)
+	e.handleLab("/api/v1/a06/password/reset", "a06_10", apiV1PasswordResetInsecure, apiV1PasswordResetInsecureSecure)
 
 	// A07: Authentication Failures (10 эндпоинтов)
-	e.r.HandleFunc("/api/v1/auth/default/login", apiV1AuthDefaultLogin)
-	e.r.HandleFunc("/api/v1/auth/bruteforce", apiV1AuthBruteforce)
-	e.r.HandleFunc("/api/v1/a07/users/password", apiV1UsersPasswordDB)
-	e.r.HandleFunc("/api/v1/session/verify", apiV1SessionVerify)
-	e.r.HandleFunc("/api/v1/session/info", apiV1SessionInfo)
-	e.r.HandleFunc("/api/v1/a07/password/reset", apiV1PasswordResetAuth)
-	e.r.HandleFunc("/api/v1/auth/login/no2fa", apiV1AuthLoginNo2FA)
-	e.r.HandleFunc("/api/v1/a07/session/create", apiV1SessionCreateForgery)
-	e.r.HandleFunc("/api/v1/session/validate", apiV1SessionValidate)
-	e.r.HandleFunc("/api/v1/auth/login/log", apiV1AuthLoginLog)
+	e.handleLab("/api/v1/auth/default/login", "a07_1", apiV1AuthDefaultLogin, apiV1AuthDefaultLoginSecure)
+	e.handleLab("/api/v1/auth/bruteforce", "a07_2", apiV1AuthBruteforce, apiV1AuthBruteforceSecure)
+	e.handleLab("/api/v1/a07/users/password", "a07_3", apiV1UsersPasswordDB, apiV1UsersPasswordDBSecure)
+	e.handleLab("/api/v1/session/verify", "a07_4", apiV1SessionVerify, apiV1SessionVerifySecure)
+	e.handleLab("/api/v1/session/info", "a07_5", apiV1SessionInfo, apiV1SessionInfoSecure)
+	e.handleLab("/api/v1/a07/password/reset", "a07_6", apiV1PasswordResetAuth, apiV1PasswordResetAuthSecure)
+	e.handleLab("/api/v1/auth/login/no2fa", "a07_7", apiV1AuthLoginNo2FA, apiV1AuthLoginNo2FASecure)
+	e.r.HandleFunc("/api/v1/auth/mfa/verify", switchable("a07_7", apiV1AuthMFAVerify, apiV1AuthMFAVerifySecure))
+	e.r.HandleFunc("/api/v1/auth/mfa/enroll", switchable("a07_7", apiV1AuthMFAEnroll, apiV1AuthMFAEnrollSecure))
+	e.handleLab("/api/v1/a07/session/create", "a07_8", apiV1SessionCreateForgery, apiV1SessionCreateForgerySecure)
+	e.r.HandleFunc("/api/v1/session/login", switchable("a07_8", apiV1SessionLogin, apiV1SessionLoginSecure))
+	e.handleLab("/api/v1/session/validate", "a07_9", apiV1SessionValidate, apiV1SessionValidateSecure)
+	e.handleLab("/api/v1/auth/login/log", "a07_10", apiV1AuthLoginLog, apiV1AuthLoginLogSecure)
 
 	// A08: Data Integrity Failures (10 эндпоинтов)
-	e.r.HandleFunc("/api/v1/update/upload", apiV1UpdateUpload)
-	e.r.HandleFunc("/api/v1/update/install", apiV1UpdateInstall)
-	e.r.HandleFunc("/api/v1/data/save", apiV1DataSave)
-	e.r.HandleFunc("/api/v1/dependencies/install", apiV1DependenciesInstall)
-	e.r.HandleFunc("/api/v1/files/upload", apiV1FilesUpload)
-	e.r.HandleFunc("/api/v1/cicd/deploy", apiV1CICDDeploy)
-	e.r.HandleFunc("/api/v1/repo/pull", apiV1RepoPull)
-	e.r.HandleFunc("/api/v1/code/execute", apiV1CodeExecute)
-	e.r.HandleFunc("/api/v1/certificate/verify", apiV1CertificateVerify)
-	e.r.HandleFunc("/api/v1/file/check", apiV1FileCheck)
+	e.handleLab("/api/v1/update/upload", "a08_1", apiV1UpdateUpload, apiV1UpdateUploadSecure)
+	e.handleLab("/api/v1/update/install", "a08_2", apiV1UpdateInstall, apiV1UpdateInstallSecure)
+	e.handleLab("/api/v1/data/save", "a08_3", apiV1DataSave, apiV1DataSaveSecure)
+	e.handleLab("/api/v1/dependencies/install", "a08_4", apiV1DependenciesInstall, apiV1DependenciesInstallSecure)
+	e.handleLab("/api/v1/files/upload", "a08_5", apiV1FilesUpload, apiV1FilesUploadSecure)
+	e.handleLab("/api/v1/cicd/deploy", "a08_6", apiV1CICDDeploy, apiV1CICDDeploySecure)
+	e.handleLab("/api/v1/repo/pull", "a08_7", apiV1RepoPull, apiV1RepoPullSecure)
+	e.handleLab("/api/v1/code/execute", "a08_8", apiV1CodeExecute, apiV1CodeExecuteSecure)
+	e.handleLab("/api/v1/certificate/verify", "a08_9", apiV1CertificateVerify, apiV1CertificateVerifySecure)
+	e.handleLab("/api/v1/file/check", "a08_10", apiV1FileCheck, apiV1FileCheckSecure)
 
 	// A09: Logging Failures (10 эндпоинтов)
-	e.r.HandleFunc("/api/v1/a09/users/delete", apiV1UsersDeleteNoLog)
-	e.r.HandleFunc("/api/v1/a09/auth/login", apiV1AuthLoginLogSensitive)
-	e.r.HandleFunc("/api/v1/system/status", apiV1SystemStatus)
-	e.r.HandleFunc("/api/v1/a09/payment/process", apiV1PaymentProcessInsufficientLog)
-	e.r.HandleFunc("/api/v1/auth/failed/login", apiV1AuthFailedLogin)
-	e.r.HandleFunc("/api/v1/logs/access", apiV1LogsAccess)
-	e.r.HandleFunc("/api/v1/events/list", apiV1EventsList)
-	e.r.HandleFunc("/api/v1/action/execute", apiV1ActionExecute)
-	e.r.HandleFunc("/api/v1/logs/analyze", apiV1LogsAnalyze)
-	e.r.HandleFunc("/api/v1/logs/storage", apiV1LogsStorage)
+	e.handleLab("/api/v1/a09/users/delete", "a09_1", apiV1UsersDeleteNoLog, apiV1UsersDeleteNoLogSecure)
+	e.handleLab("/api/v1/a09/auth/login", "a09_2", apiV1AuthLoginLogSensitive, apiV1AuthLoginLogSensitiveSecure)
+	e.handleLab("/api/v1/system/status", "a09_3", apiV1SystemStatus, apiV1SystemStatusSecure)
+	e.handleLab("/api/v1/a09/payment/process", "a09_4", apiV1PaymentProcessInsufficientLog, apiV1PaymentProcessInsufficientLogSecure)
+	e.handleLab("/api/v1/auth/failed/login", "a09_5", apiV1AuthFailedLogin, apiV1AuthFailedLoginSecure)
+	e.handleLab("/api/v1/logs/access", "a09_6", apiV1LogsAccess, apiV1LogsAccessSecure)
+	e.handleLab("/api/v1/events/list", "a09_7", apiV1EventsList, apiV1EventsListSecure)
+	e.handleLab("/api/v1/action/execute", "a09_8", apiV1ActionExecute, apiV1ActionExecuteSecure)
+	e.handleLab("/api/v1/logs/analyze", "a09_9", apiV1LogsAnalyze, apiV1LogsAnalyzeSecure)
+	e.handleLab("/api/v1/logs/storage", "a09_10", apiV1LogsStorage, apiV1LogsStorageSecure)
 
 	// A10: Exception Handling (10 эндпоинтов)
-	e.r.HandleFunc("/api/v1/users/get", apiV1UsersGet)
-	e.r.HandleFunc("/api/v1/calculate", apiV1Calculate)
-	e.r.HandleFunc("/api/v1/database/query", apiV1DatabaseQuery)
-	e.r.HandleFunc("/api/v1/process", apiV1Process)
-	e.r.HandleFunc("/api/v1/transfer", apiV1Transfer)
-	e.r.HandleFunc("/api/v1/file/read", apiV1FileRead)
-	e.r.HandleFunc("/api/v1/concurrent", apiV1Concurrent)
-	e.r.HandleFunc("/api/v1/user/check", apiV1UserCheck)
-	e.r.HandleFunc("/api/v1/data/process", apiV1DataProcess)
-	e.r.HandleFunc("/api/v1/service/status", apiV1ServiceStatus)
+	e.handleLab("/api/v1/users/get", "a10_1", apiV1UsersGet, apiV1UsersGetSecure)
+	e.handleLab("/api/v1/calculate", "a10_2", apiV1Calculate, apiV1CalculateSecure)
+	e.handleLab("/api/v1/database/query", "a10_3", apiV1DatabaseQuery, apiV1DatabaseQuerySecure)
+	e.handleLab("/api/v1/process", "a10_4", apiV1Process, apiV1ProcessSecure)
+	e.handleLab("/api/v1/transfer", "a10_5", apiV1Transfer, apiV1TransferSecure)
+	e.handleLab("/api/v1/file/read", "a10_6", apiV1FileRead, apiV1FileReadSecure)
+	e.handleLab("/api/v1/concurrent", "a10_7", apiV1Concurrent, apiV1ConcurrentSecure)
+	e.handleLab("/api/v1/user/check", "a10_8", apiV1UserCheck, apiV1UserCheckSecure)
+	e.handleLab("/api/v1/data/process", "a10_9", apiV1DataProcess, apiV1DataProcessSecure)
+	e.handleLab("/api/v1/service/status", "a10_10", apiV1ServiceStatus, apiV1ServiceStatusSecure)
+
+	if err := checkLabRoutes(catalog); err != nil {
+		return err
+	}
+	if err := checkEvidenceRoutes(e.r, catalog); err != nil {
+		return err
+	}
+	// Все запросы проходят через журнал, по нему проверяются решения
+	e.handler = journalMiddleware(e.r)
+	// Бот-модератор ходит мимо журнала: его запросы - не действия учащегося
+	startModerationBot(e.r)
+
+	return nil
+}
+
+// Обработчик всех маршрутов (после FillEndpoints)
+func (e *endpoints) Handler() http.Handler {
+	return e.handler
 }
 
-func (e *endpoints) ListenAndServe() error {
-	return http.ListenAndServe(e.addr, e.r)
+// Задать общий режим эндпоинтов (vulnerable или secure)
+func (e *endpoints) SetMode(mode string) error {
+	return modes.SetGlobal(mode)
 }
 
 // Главная страница с навигацией
@@ -157,7 +261,7 @@ func index(w http.ResponseWriter, r *http.Request) {
 				<li><a href="/challenge/a01/1" class="api-endpoint">🔓 Задание 1: IDOR</a> - Получите данные другого пользователя</li>
 				<li><a href="/challenge/a01/2" class="api-endpoint">🔓 Задание 2: Обход через параметр</a> - Получите админский доступ</li>
 				<li><a href="/challenge/a01/3" class="api-endpoint">🔓 Задание 3: Небезопасный редирект</a> - Создайте фишинговую ссылку</li>
-				<li><a href="/challenge/a01/4" class="api-endpoint">🔓 Задание 4: Слабая проверка JWT</a> - Получите админский доступ через токен</li>
+				<li><a href="/challenge/a01/4" class="api-endpoint">🔓 Задание 4: Подделка JWT</a> - Получите админский доступ через токен</li>
 				<li><a href="/challenge/a01/5" class="api-endpoint">🔓 Задание 5: Прямой доступ к файлам</a> - Получите конфигурационные файлы</li>
 				<li><a href="/challenge/a01/6" class="api-endpoint">🔓 Задание 6: Обход через заголовки</a> - Используйте заголовок X-Admin</li>
 				<li><a href="/challenge/a01/7" class="api-endpoint">🔓 Задание 7: Неправильная настройка CORS</a> - Получите данные через CORS</li>
@@ -220,14 +324,25 @@ func index(w http.ResponseWriter, r *http.Request) {
 			<ul>
 				<li><a href="/challenge/a05/1" class="api-endpoint">🔓 Задание 1: SQL Injection</a> - Получите всех пользователей</li>
 				<li><a href="/challenge/a05/2" class="api-endpoint">🔓 Задание 2: Command Injection</a> - Выполните системную команду</li>
-				<li><a href="/challenge/a05/3" class="api-endpoint">🔓 Задание 3: XSS</a> - Выполните JavaScript код</li>
+				<li><a href="/challenge/a05/3" class="api-endpoint">🔓 Задание 3: Stored XSS</a> - Украдите сессию модератора</li>
 				<li><a href="/challenge/a05/4" class="api-endpoint">🔓 Задание 4: LDAP Injection</a> - Используйте специальные символы</li>
-				<li><a href="/challenge/a05/5" class="api-endpoint">🔓 Задание 5: NoSQL Injection</a> - Используйте операторы MongoDB</li>
+				<li><a href="/challenge/a05/5" class="api-endpoint">🔓 Задание 5: NoSQL Injection</a> - Извлеките ключ API через $regex</li>
 				<li><a href="/challenge/a05/6" class="api-endpoint">🔓 Задание 6: Template Injection</a> - Выполните код в шаблоне</li>
 				<li><a href="/challenge/a05/7" class="api-endpoint">🔓 Задание 7: XXE</a> - Прочитайте файл через XML</li>
 				<li><a href="/challenge/a05/8" class="api-endpoint">🔓 Задание 8: Path Traversal</a> - Прочитайте /etc/passwd</li>
 				<li><a href="/challenge/a05/9" class="api-endpoint">🔓 Задание 9: SSRF</a> - Отправьте запрос к localhost</li>
 				<li><a href="/challenge/a05/10" class="api-endpoint">🔓 Задание 10: Code Injection</a> - Выполните произвольный код</li>
+				<li><a href="/challenge/a05/11" class="api-endpoint">🔓 Задание 11: JSON Injection</a> - Добавьте в профиль роль admin</li>
+				<li><a href="/challenge/a05/12" class="api-endpoint">🔓 Задание 12: UNION SQL Injection</a> - Достаньте ключ из скрытой таблицы</li>
+				<li><a href="/challenge/a05/13" class="api-endpoint">🔓 Задание 13: Blind SQL Injection</a> - Прочитайте ключ по ответам да/нет</li>
+				<li><a href="/challenge/a05/14" class="api-endpoint">🔓 Задание 14: Time-based SQL Injection</a> - Прочитайте ключ по времени ответа</li>
+				<li><a href="/challenge/a05/15" class="api-endpoint">🔓 Задание 15: SSTI через методы</a> - Получите пароль администратора из шаблона письма</li>
+				<li><a href="/challenge/a05/16" class="api-endpoint">🔓 Задание 16: SSTI через функции</a> - Выполните команду из шаблона страницы</li>
+				<li><a href="/challenge/a05/17" class="api-endpoint">🔓 Задание 17: Blind XXE</a> - Выведите файл через внешний DTD</li>
+				<li><a href="/challenge/a05/18" class="api-endpoint">🔓 Задание 18: SSRF с черным списком</a> - Обойдите фильтр адресов</li>
+				<li><a href="/challenge/a05/19" class="api-endpoint">🔓 Задание 19: SSRF через перенаправление</a> - Уведите запрос на внутренний адрес</li>
+				<li><a href="/challenge/a05/20" class="api-endpoint">🔓 Задание 20: SSRF через DNS rebinding</a> - Обманите проверку адреса</li>
+				<li><a href="/challenge/a05/21" class="api-endpoint">🔓 Задание 21: NoSQL обход входа</a> - Войдите администратором без пароля</li>
 			</ul>
 		</div>
 		
@@ -240,10 +355,10 @@ func index(w http.ResponseWriter, r *http.Request) {
 				<li><a href="/challenge/a06/4" class="api-endpoint">🔓 Задание 4: Опасные действия через GET</a> - Удалите пользователя через GET</li>
 				<li><a href="/challenge/a06/5" class="api-endpoint">🔓 Задание 5: Отсутствие проверки логики</a> - Переведите отрицательную сумму</li>
 				<li><a href="/challenge/a06/6" class="api-endpoint">🔓 Задание 6: Слабые требования к паролю</a> - Установите слабый пароль</li>
-				<li><a href="/challenge/a06/7" class="api-endpoint">🔓 Задание 7: Отсутствие 2FA</a> - Войдите без двухфакторной аутентификации</li>
+				<li><a href="/challenge/a06/7" class="api-endpoint">🔓 Задание 7: Пропуск второго шага</a> - Войдите как администратор без кода 2FA</li>
 				<li><a href="/challenge/a06/8" class="api-endpoint">🔓 Задание 8: Небезопасный дизайн сессий</a> - Создайте сессию без истечения</li>
 				<li><a href="/challenge/a06/9" class="api-endpoint">🔓 Задание 9: Отсутствие аудита</a> - Выполните действие без логирования</li>
-				<li><a href="/challenge/a06/10" class="api-endpoint">🔓 Задание 10: Небезопасное восстановление пароля</a> - Захватите аккаунт</li>
+				<li><a href="/challenge/a06/10" class="api-endpoint">🔓 Задание 10: Отравление ссылки сброса пароля</a> - Перехватите ссылку сброса через заголовок Host</li>
 			</ul>
 		</div>
 		
@@ -251,12 +366,12 @@ func index(w http.ResponseWriter, r *http.Request) {
 			<h2>A07: Authentication Failures (Ошибки аутентификации)</h2>
 			<ul>
 				<li><a href="/challenge/a07/1" class="api-endpoint">🔓 Задание 1: Слабые пароли по умолчанию</a> - Войдите с дефолтными данными</li>
-				<li><a href="/challenge/a07/2" class="api-endpoint">🔓 Задание 2: Отсутствие блокировки</a> - Выполните брутфорс атаку</li>
+				<li><a href="/challenge/a07/2" class="api-endpoint">🔓 Задание 2: Обход блокировки</a> - Подберите пароль в обход блокировки входа</li>
 				<li><a href="/challenge/a07/3" class="api-endpoint">🔓 Задание 3: Пароли в открытом виде</a> - Получите пароль из БД</li>
 				<li><a href="/challenge/a07/4" class="api-endpoint">🔓 Задание 4: Слабая проверка сессии</a> - Используйте произвольный session_id</li>
 				<li><a href="/challenge/a07/5" class="api-endpoint">🔓 Задание 5: Сессия не истекает</a> - Проверьте срок действия сессии</li>
-				<li><a href="/challenge/a07/6" class="api-endpoint">🔓 Задание 6: Небезопасное восстановление</a> - Получите пароль без проверки</li>
-				<li><a href="/challenge/a07/7" class="api-endpoint">🔓 Задание 7: Отсутствие 2FA</a> - Войдите без двухфакторной аутентификации</li>
+				<li><a href="/challenge/a07/6" class="api-endpoint">🔓 Задание 6: Слабые токены сброса пароля</a> - Сбросьте чужой пароль угаданным или старым токеном</li>
+				<li><a href="/challenge/a07/7" class="api-endpoint">🔓 Задание 7: Слабый второй фактор</a> - Пройдите проверку TOTP повторным или подобранным кодом</li>
 				<li><a href="/challenge/a07/8" class="api-endpoint">🔓 Задание 8: Подделка сессий</a> - Создайте админскую сессию</li>
 				<li><a href="/challenge/a07/9" class="api-endpoint">🔓 Задание 9: Отсутствие проверки IP</a> - Используйте сессию с любого IP</li>
 				<li><a href="/challenge/a07/10" class="api-endpoint">🔓 Задание 10: Утечка в логах</a> - Найдите пароль в логах</li>
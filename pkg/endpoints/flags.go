@@ -0,0 +1,75 @@
+package endpoints
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Динамические флаги: уязвимый эндпоинт при успешной эксплуатации отдает флаг,
+// вычисленный как HMAC от секрета сервера, ID учащегося и ключа задания.
+// У каждого учащегося свой флаг, поэтому поделиться ответом с группой нельзя
+
+var flagSecret []byte
+
+// Взять секрет для флагов из настроек; если он не задан, загрузить из файла path
+// или сгенерировать новый при первом запуске
+func loadFlagSecret(path, configured string) error {
+	if configured != "" {
+		flagSecret = []byte(configured)
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		secret := strings.TrimSpace(string(data))
+		if secret == "" {
+			return fmt.Errorf("flag secret %s is empty", path)
+		}
+		flagSecret = []byte(secret)
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read flag secret: %w", err)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Errorf("generate flag secret: %w", err)
+	}
+	secret := hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create data dir: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(secret+"\n"), 0o600); err != nil {
+		return fmt.Errorf("write flag secret: %w", err)
+	}
+	flagSecret = []byte(secret)
+	return nil
+}
+
+// Флаг задания для конкретного учащегося
+func challengeFlag(learner, challengeKey string) string {
+	mac := hmac.New(sha256.New, flagSecret)
+	mac.Write([]byte(learner + ":" + challengeKey))
+	return "FLAG{" + hex.EncodeToString(mac.Sum(nil))[:32] + "}"
+}
+
+// Флаг для учащегося, отправившего запрос. Вызывать до записи тела ответа:
+// учащемуся без cookie выдается новый ID
+func labFlag(w http.ResponseWriter, r *http.Request, challengeKey string) string {
+	return challengeFlag(learnerID(w, r), challengeKey)
+}
+
+// Проверить отправленный флаг
+func validFlag(learner, challengeKey, submitted string) bool {
+	expected := challengeFlag(learner, challengeKey)
+	return hmac.Equal([]byte(strings.TrimSpace(submitted)), []byte(expected))
+}
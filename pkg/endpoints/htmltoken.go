@@ -0,0 +1,205 @@
+package endpoints
+
+import (
+	"html"
+	"strings"
+)
+
+// Разбор HTML на токены по правилам токенизатора HTML5 в объеме, нужном боту-модератору:
+// теги с атрибутами (в кавычках и без), комментарии и элементы с "сырым" текстом,
+// внутри которых разметка не разбирается (<script>, <textarea>, <title> и т.п.).
+// Дерево не строится: боту достаточно знать, в каком контексте оказалась строка
+
+type htmlTokenKind int
+
+const (
+	htmlText htmlTokenKind = iota
+	htmlStartTag
+	htmlEndTag
+	htmlComment
+)
+
+type htmlAttr struct {
+	Name  string // В нижнем регистре
+	Value string // Со снятыми ссылками на символы (&#x61; -> a)
+}
+
+type htmlToken struct {
+	Kind  htmlTokenKind
+	Tag   string // Имя тега в нижнем регистре
+	Attrs []htmlAttr
+	Text  string // Текст или содержимое комментария
+}
+
+// Значение атрибута; у повторяющихся атрибутов действует первый, как в браузере
+func (t htmlToken) Attr(name string) (string, bool) {
+	for _, a := range t.Attrs {
+		if a.Name == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// Элементы, содержимое которых - текст до закрывающего тега
+var htmlRawTextTags = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true, "xmp": true,
+	"iframe": true, "noembed": true, "noframes": true, "noscript": true, "plaintext": true,
+}
+
+func tokenizeHTML(s string) []htmlToken {
+	var tokens []htmlToken
+	text := func(t string) {
+		if t != "" {
+			tokens = append(tokens, htmlToken{Kind: htmlText, Text: t})
+		}
+	}
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			text(html.UnescapeString(s))
+			break
+		}
+		text(html.UnescapeString(s[:i]))
+		s = s[i:]
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s[4:], "-->")
+			if end < 0 {
+				tokens = append(tokens, htmlToken{Kind: htmlComment, Text: s[4:]})
+				return tokens
+			}
+			tokens = append(tokens, htmlToken{Kind: htmlComment, Text: s[4 : 4+end]})
+			s = s[4+end+3:]
+		case strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?"):
+			// Doctype и "поддельный" комментарий - до первого >
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return tokens
+			}
+			tokens = append(tokens, htmlToken{Kind: htmlComment, Text: s[2:end]})
+			s = s[end+1:]
+		case len(s) > 2 && s[1] == '/' && isASCIILetter(s[2]):
+			name, rest := htmlTagName(s[2:])
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return tokens
+			}
+			tokens = append(tokens, htmlToken{Kind: htmlEndTag, Tag: name})
+			s = rest[end+1:]
+		case len(s) > 1 && isASCIILetter(s[1]):
+			tok, rest, ok := htmlStartTagToken(s[1:])
+			if !ok {
+				// Незакрытый тег в конце документа браузер отбрасывает
+				return tokens
+			}
+			tokens = append(tokens, tok)
+			s = rest
+			if htmlRawTextTags[tok.Tag] {
+				raw, after := htmlRawText(s, tok.Tag)
+				if tok.Tag == "textarea" || tok.Tag == "title" {
+					raw = html.UnescapeString(raw)
+				}
+				text(raw)
+				s = after
+			}
+		default:
+			// Одиночный < - обычный текст
+			text("<")
+			s = s[1:]
+		}
+	}
+	return tokens
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// Имя тега до пробела, / или >
+func htmlTagName(s string) (string, string) {
+	i := 0
+	for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '/' && s[i] != '>' {
+		i++
+	}
+	return strings.ToLower(s[:i]), s[i:]
+}
+
+// Открывающий тег после <. Атрибуты разделяются пробелами или /, поэтому <svg/onload=...>
+// - тег svg с атрибутом onload
+func htmlStartTagToken(s string) (htmlToken, string, bool) {
+	tok := htmlToken{Kind: htmlStartTag}
+	tok.Tag, s = htmlTagName(s)
+	seen := make(map[string]bool)
+	for {
+		for len(s) > 0 && (isHTMLSpace(s[0]) || s[0] == '/') {
+			s = s[1:]
+		}
+		if len(s) == 0 {
+			return tok, "", false
+		}
+		if s[0] == '>' {
+			return tok, s[1:], true
+		}
+
+		// Имя атрибута; = в первой позиции входит в имя
+		i := 1
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '/' && s[i] != '>' && s[i] != '=' {
+			i++
+		}
+		name := strings.ToLower(s[:i])
+		s = s[i:]
+		rest := strings.TrimLeft(s, " \t\n\r\f")
+		value := ""
+		if strings.HasPrefix(rest, "=") {
+			rest = strings.TrimLeft(rest[1:], " \t\n\r\f")
+			if len(rest) > 0 && (rest[0] == '"' || rest[0] == '\'') {
+				end := strings.IndexByte(rest[1:], rest[0])
+				if end < 0 {
+					return tok, "", false
+				}
+				value = rest[1 : 1+end]
+				rest = rest[2+end:]
+			} else {
+				j := 0
+				for j < len(rest) && !isHTMLSpace(rest[j]) && rest[j] != '>' {
+					j++
+				}
+				value = rest[:j]
+				rest = rest[j:]
+			}
+			s = rest
+		}
+		if !seen[name] {
+			seen[name] = true
+			tok.Attrs = append(tok.Attrs, htmlAttr{Name: name, Value: html.UnescapeString(value)})
+		}
+	}
+}
+
+// Содержимое элемента с сырым текстом: до </tag (без учета регистра), за которым
+// идет пробел, / или >. У <plaintext> закрывающего тега нет
+func htmlRawText(s, tag string) (string, string) {
+	if tag == "plaintext" {
+		return s, ""
+	}
+	lower := strings.ToLower(s)
+	closing := "</" + tag
+	for from := 0; ; {
+		i := strings.Index(lower[from:], closing)
+		if i < 0 {
+			return s, ""
+		}
+		i += from
+		next := i + len(closing)
+		if next == len(s) || isHTMLSpace(s[next]) || s[next] == '/' || s[next] == '>' {
+			return s[:i], s[i:]
+		}
+		from = next
+	}
+}
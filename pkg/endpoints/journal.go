@@ -0,0 +1,253 @@
+package endpoints
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Журнал запросов: middleware вокруг ServeMux записывает запросы каждого учащегося
+// к уязвимым эндпоинтам и ответы на них. Проверки заданий (evidence в challenge.json)
+// ищут в журнале доказательство реальной эксплуатации, а не ответ, подсмотренный в подсказке
+
+const (
+	journalLimit        = 200        // Сколько последних запросов хранить для каждого учащегося
+	journalMaxBody      = 4 * 1024   // Сколько байт тела запроса и ответа сохранять
+	journalLearnerBytes = 256 * 1024 // Сколько байт записей хранить для учащегося; старые записи вытесняются
+	journalMaxLearners  = 500        // Учащихся в журнале; при превышении вытесняется давно неактивный
+	journalIdleTTL      = domainIdleTTL
+)
+
+// Маршруты самого учебного стенда в журнал не попадают
+var journalSkipRoutes = map[string]bool{
+	"/":               true,
+	"/explanations":   true,
+	"/challenge/":     true,
+	"/hint/":          true,
+	"/scoreboard":     true,
+	"/api/scoreboard": true,
+	"/team":           true,
+	"/mode":           true,
+	"/api/mode":       true,
+}
+
+// Один запрос учащегося и ответ на него
+type journalEntry struct {
+	Time   time.Time
+	Method string
+	Route  string // Шаблон маршрута в ServeMux, например /api/v1/users/
+	Path   string
+	Params url.Values // Параметры из query и тела формы
+	Header http.Header
+	Body   string
+	Mode   string // Режим эндпоинта (vulnerable или secure) в момент запроса
+
+	Status         int
+	ResponseHeader http.Header
+	ResponseBody   string
+}
+
+// Записи одного учащегося, размер их тел и время последней записи
+type learnerJournal struct {
+	entries []journalEntry
+	bytes   int
+	used    time.Time
+}
+
+type requestJournal struct {
+	mu       sync.Mutex
+	learners map[string]*learnerJournal
+}
+
+var journal = newRequestJournal()
+
+func newRequestJournal() *requestJournal {
+	return &requestJournal{learners: make(map[string]*learnerJournal)}
+}
+
+// Добавить запись; старые записи вытесняются
+func (j *requestJournal) Record(learner string, entry journalEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	l, ok := j.learners[learner]
+	if !ok {
+		j.evict(entry.Time)
+		l = &learnerJournal{}
+		j.learners[learner] = l
+	}
+	l.entries = append(l.entries, entry)
+	l.bytes += entry.size()
+	for len(l.entries) > 0 && (len(l.entries) > journalLimit || l.bytes > journalLearnerBytes) {
+		l.bytes -= l.entries[0].size()
+		l.entries = l.entries[1:]
+	}
+	l.used = entry.Time
+}
+
+// Размер записи в бюджете учащегося: тела и заголовки запроса и ответа.
+// Запись больше всего бюджета не сохраняется
+func (e journalEntry) size() int {
+	n := len(e.Path) + len(e.Body) + len(e.ResponseBody)
+	for _, h := range []http.Header{e.Header, e.ResponseHeader} {
+		for k, values := range h {
+			for _, v := range values {
+				n += len(k) + len(v)
+			}
+		}
+	}
+	return n
+}
+
+// Освободить место для нового учащегося: удалить неактивных дольше journalIdleTTL,
+// а если журнал все еще полон - самого давно неактивного
+func (j *requestJournal) evict(now time.Time) {
+	oldest := ""
+	for id, l := range j.learners {
+		if now.Sub(l.used) > journalIdleTTL {
+			delete(j.learners, id)
+			continue
+		}
+		if oldest == "" || l.used.Before(j.learners[oldest].used) {
+			oldest = id
+		}
+	}
+	if len(j.learners) >= journalMaxLearners {
+		delete(j.learners, oldest)
+	}
+}
+
+// Есть ли в журнале записи учащегося
+func (j *requestJournal) Has(learner string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, ok := j.learners[learner]
+	return ok
+}
+
+// Записи учащегося, от старых к новым
+func (j *requestJournal) Entries(learner string) []journalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	l, ok := j.learners[learner]
+	if !ok {
+		return nil
+	}
+	return append([]journalEntry(nil), l.entries...)
+}
+
+// Middleware, записывающий запросы в журнал
+func journalMiddleware(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ID выдаем до обработчика, чтобы запрос и флаг в ответе относились к одному учащемуся.
+		// Запрос без cookie учащегося не записывается, а данные стенда, созданные для него,
+		// удаляются после ответа: иначе каждый такой запрос заводил бы нового учащегося.
+		// Данные появятся со следующим запросом, в котором браузер вернет cookie
+		c, err := r.Cookie(learnerCookieName)
+		known := err == nil && validLearnerID(c.Value)
+		learner := learnerID(w, r)
+		if !known {
+			mux.ServeHTTP(w, r)
+			domains.Forget(learner)
+			return
+		}
+
+		_, route := mux.Handler(r)
+		if journalSkipRoutes[route] {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// Читаем начало тела и возвращаем его обратно, обработчик получит тело целиком
+		var body []byte
+		if r.Body != nil {
+			body, _ = io.ReadAll(io.LimitReader(r.Body, journalMaxBody))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		}
+
+		rec := &journalRecorder{ResponseWriter: w, status: http.StatusOK}
+		mux.ServeHTTP(rec, r)
+
+		journal.Record(learner, journalEntry{
+			Time:           time.Now().UTC(),
+			Method:         r.Method,
+			Route:          route,
+			Path:           r.URL.Path,
+			Params:         requestParams(r, body),
+			Header:         r.Header.Clone(),
+			Body:           string(body),
+			Mode:           rec.Header().Get("X-Lab-Mode"),
+			Status:         rec.status,
+			ResponseHeader: rec.Header().Clone(),
+			ResponseBody:   rec.body.String(),
+		})
+	})
+}
+
+// Параметры запроса: query и тело формы (тело не разбираем через r.ParseForm,
+// чтобы не менять поведение обработчиков)
+func requestParams(r *http.Request, body []byte) url.Values {
+	params := r.URL.Query()
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		form, _ := url.ParseQuery(string(body))
+		for k, v := range form {
+			params[k] = append(params[k], v...)
+		}
+	}
+	return params
+}
+
+// ResponseWriter, запоминающий статус и начало тела ответа
+type journalRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *journalRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *journalRecorder) Write(p []byte) (int, error) {
+	rec.wroteHeader = true
+	if room := journalMaxBody - rec.body.Len(); room > 0 {
+		rec.body.Write(p[:min(len(p), room)])
+	}
+	return rec.ResponseWriter.Write(p)
+}
+
+// Нужен для http.ResponseController (Flush и т.п.)
+func (rec *journalRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Проверить, что маршруты из правил evidence зарегистрированы в ServeMux.
+// Опечатка в route иначе сделала бы задание нерешаемым
+func checkEvidenceRoutes(mux *http.ServeMux, challenges map[string]Challenge) error {
+	var errs []error
+	for key, challenge := range challenges {
+		if challenge.Check == nil {
+			continue
+		}
+		for i, rule := range challenge.Check.Evidence {
+			r := &http.Request{Method: http.MethodGet, URL: &url.URL{Path: rule.Route}}
+			if _, pattern := mux.Handler(r); pattern != rule.Route {
+				errs = append(errs, fmt.Errorf("challenge %s: evidence[%d]: route %s is not registered", key, i, rule.Route))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,36 @@
+package endpoints
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournalLimits(t *testing.T) {
+	j := newRequestJournal()
+	now := time.Now()
+	body := strings.Repeat("x", journalMaxBody)
+	for i := 0; i < journalLimit; i++ {
+		j.Record("a", journalEntry{Time: now, Path: "/", Body: body, ResponseBody: body})
+	}
+	entries := j.Entries("a")
+	if n := len(entries) * 2 * journalMaxBody; n > journalLearnerBytes || len(entries) == 0 {
+		t.Errorf("%d entries, %d bytes stored", len(entries), n)
+	}
+	if l := j.learners["a"]; l.bytes != len(entries)*(1+2*journalMaxBody) {
+		t.Errorf("budget %d for %d entries", l.bytes, len(entries))
+	}
+
+	for i := 0; i < journalLimit+5; i++ {
+		j.Record("b", journalEntry{Time: now, Path: "/"})
+	}
+	if n := len(j.Entries("b")); n != journalLimit {
+		t.Errorf("%d small entries, want %d", n, journalLimit)
+	}
+
+	// Запись больше бюджета не сохраняется
+	j.Record("c", journalEntry{Time: now, ResponseBody: strings.Repeat("x", journalLearnerBytes+1)})
+	if n := len(j.Entries("c")); n != 0 {
+		t.Errorf("%d oversized entries stored", n)
+	}
+}
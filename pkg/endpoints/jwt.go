@@ -0,0 +1,287 @@
+package endpoints
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWT для задания a01_4: выдача при входе (HS256 или RS256) и разбор токена. Ключи
+// сервера: "hs1" - секрет HMAC, "rs1" - пара RSA, открытый ключ опубликован
+// (/.well-known/jwks.json и /api/v1/auth/public.pem). Уязвимая проверка собрана из
+// типичных ошибок библиотек; каждую можно выключить для учащегося через /api/lab/jwt
+
+const (
+	jwtHMACKid  = "hs1"
+	jwtRSAKid   = "rs1"
+	jwtKeysDir  = "/var/www/app/keys" // Каталог ключей для kid, которых нет в наборе
+	jwtLifetime = 15 * time.Minute
+)
+
+// Ошибки проверки в уязвимом режиме
+const (
+	jwtFlawNone      = "alg_none"      // Принимается alg=none без подписи
+	jwtFlawConfusion = "key_confusion" // HS256 с открытым ключом RSA как секретом
+	jwtFlawWeak      = "weak_secret"   // Секрет HMAC из словаря
+	jwtFlawKid       = "kid_path"      // kid - путь к файлу ключа
+	jwtFlawExp       = "no_exp"        // Срок действия не проверяется
+)
+
+var jwtFlaws = flawSet{name: "jwt", flaws: []string{jwtFlawNone, jwtFlawConfusion, jwtFlawWeak, jwtFlawKid, jwtFlawExp}}
+
+var (
+	errJWTMalformed = errors.New("malformed token")
+	errJWTSignature = errors.New("signature verification failed")
+	errJWTExpired   = errors.New("token is expired")
+)
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Разобранный токен
+type jwtToken struct {
+	Header       jwtHeader
+	Claims       map[string]interface{}
+	SigningInput string
+	Signature    []byte
+}
+
+var jwtRSAKey = sync.OnceValue(func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+})
+
+// Открытый ключ RSA в PEM - ровно те байты, что отдает /api/v1/auth/public.pem
+func jwtPublicPEM() []byte {
+	der, err := x509.MarshalPKIXPublicKey(&jwtRSAKey().PublicKey)
+	if err != nil {
+		panic(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// Слабый секрет HMAC учащегося: слово из раздаваемого словаря, у каждого свое
+func jwtWeakSecret(learner string) []byte {
+	words := wordlist("jwt-secrets.txt")
+	mac := hmac.New(sha256.New, serverKey("jwt-weak"))
+	mac.Write([]byte(learner))
+	return []byte(words[binary.BigEndian.Uint64(mac.Sum(nil))%uint64(len(words))])
+}
+
+// Секрет HMAC, которым сервер подписывает токены учащегося
+func jwtHMACSecret(learner string, d *domain) []byte {
+	if modes.Mode("a01_4") != modeSecure && jwtFlaws.On(d, jwtFlawWeak) {
+		return jwtWeakSecret(learner)
+	}
+	return serverKey("jwt")
+}
+
+func signJWT(header jwtHeader, claims map[string]interface{}, key interface{}) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	var sig []byte
+	switch header.Alg {
+	case "HS256":
+		sig = jwtHMAC(key.([]byte), input)
+	case "RS256":
+		digest := sha256.Sum256([]byte(input))
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:]); err != nil {
+			return "", err
+		}
+	case "none":
+	default:
+		return "", fmt.Errorf("unsupported algorithm %s", header.Alg)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func jwtHMAC(key []byte, input string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(input))
+	return mac.Sum(nil)
+}
+
+// Разобрать токен без проверки подписи
+func parseJWT(token string) (*jwtToken, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errJWTMalformed
+	}
+	t := &jwtToken{SigningInput: parts[0] + "." + parts[1]}
+	if err := decodeSegment(parts[0], &t.Header); err != nil {
+		return nil, fmt.Errorf("malformed header: %v", err)
+	}
+	if err := decodeSegment(parts[1], &t.Claims); err != nil {
+		return nil, fmt.Errorf("malformed claims: %v", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature: %v", err)
+	}
+	t.Signature = sig
+	return t, nil
+}
+
+func (t *jwtToken) VerifyHMAC(key []byte) error {
+	if !hmac.Equal(t.Signature, jwtHMAC(key, t.SigningInput)) {
+		return errJWTSignature
+	}
+	return nil
+}
+
+func (t *jwtToken) VerifyRSA(key *rsa.PublicKey) error {
+	digest := sha256.Sum256([]byte(t.SigningInput))
+	if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], t.Signature) != nil {
+		return errJWTSignature
+	}
+	return nil
+}
+
+// Срок действия: exp обязателен и еще не наступил
+func (t *jwtToken) CheckExpiry() error {
+	exp, ok := t.Claims["exp"].(float64)
+	if !ok {
+		return errors.New("token has no exp claim")
+	}
+	if time.Now().Unix() >= int64(exp) {
+		return errJWTExpired
+	}
+	return nil
+}
+
+// Токен из параметра token или заголовка Authorization: Bearer
+func requestJWT(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// Вход с выдачей JWT. Администраторы входят через SSO, токен администратора
+// сервер не выдает - его можно только подделать
+func apiV1AuthJWTLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		sendJSONStatus(w, http.StatusMethodNotAllowed, map[string]interface{}{
+			"status":  "error",
+			"message": "POST username, password and optionally alg=HS256|RS256",
+		})
+		return
+	}
+	learner := learnerID(w, r)
+	d := domains.Get(learner)
+	var user *account
+	for _, u := range d.Users() {
+		if u.Username == r.FormValue("username") && u.Password == r.FormValue("password") {
+			user = &u
+			break
+		}
+	}
+	if user == nil {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid username or password",
+		})
+		return
+	}
+	if user.Role == "admin" {
+		sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": "Administrators must sign in via SSO",
+		})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"sub":  user.ID,
+		"name": user.Username,
+		"role": user.Role,
+		"iat":  now.Unix(),
+		"exp":  now.Add(jwtLifetime).Unix(),
+	}
+	header := jwtHeader{Alg: "HS256", Typ: "JWT", Kid: jwtHMACKid}
+	var key interface{} = jwtHMACSecret(learner, d)
+	if r.FormValue("alg") == "RS256" {
+		header = jwtHeader{Alg: "RS256", Typ: "JWT", Kid: jwtRSAKid}
+		key = jwtRSAKey()
+	}
+	token, err := signJWT(header, claims, key)
+	if err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{"status": "error", "message": "Internal server error"})
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":     "success",
+		"token":      token,
+		"expires_in": int(jwtLifetime.Seconds()),
+	})
+}
+
+// Старый токен администратора, попавший в журнал приложения: подпись настоящая, срок истек
+func leakedAdminJWT(learner string) string {
+	issued := time.Date(2024, 1, 15, 10, 30, 35, 0, time.UTC)
+	token, _ := signJWT(jwtHeader{Alg: "HS256", Typ: "JWT", Kid: jwtHMACKid}, map[string]interface{}{
+		"sub":  "3",
+		"name": "admin",
+		"role": "admin",
+		"iat":  issued.Unix(),
+		"exp":  issued.Add(time.Hour).Unix(),
+	}, jwtHMACSecret(learner, domains.Get(learner)))
+	return token
+}
+
+// Открытые ключи: JWKS и PEM
+func apiJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := jwtRSAKey().PublicKey
+	sendJSON(w, map[string]interface{}{
+		"keys": []map[string]interface{}{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": jwtRSAKid,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func apiJWTPublicPEM(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.Write(jwtPublicPEM())
+}
+
+// Ошибки проверки JWT учащегося: GET - список, POST flaw=<имя>&enabled=true|false
+var apiLabJWT = jwtFlaws.Handler(map[string]interface{}{
+	"login":      "/api/v1/auth/jwt/login",
+	"verify":     "/api/v1/auth/verify",
+	"jwks":       "/.well-known/jwks.json",
+	"public_key": "/api/v1/auth/public.pem",
+	"wordlist":   "/api/lab/wordlists/jwt-secrets.txt",
+})
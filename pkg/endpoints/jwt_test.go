@@ -0,0 +1,142 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testJWTClaims(role string, exp time.Time) map[string]interface{} {
+	return map[string]interface{}{"sub": "3", "name": "admin", "role": role, "exp": exp.Unix()}
+}
+
+func mustSignJWT(t *testing.T, header jwtHeader, claims map[string]interface{}, key interface{}) string {
+	t.Helper()
+	token, err := signJWT(header, claims, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestParseJWT(t *testing.T) {
+	token := mustSignJWT(t, jwtHeader{Alg: "HS256", Typ: "JWT", Kid: jwtHMACKid}, testJWTClaims("user", time.Now().Add(time.Hour)), []byte("secret"))
+	parsed, err := parseJWT(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header.Kid != jwtHMACKid || parsed.Claims["role"] != "user" {
+		t.Errorf("header %+v, claims %v", parsed.Header, parsed.Claims)
+	}
+	if err := parsed.VerifyHMAC([]byte("secret")); err != nil {
+		t.Error(err)
+	}
+	if err := parsed.VerifyHMAC([]byte("other")); !errors.Is(err, errJWTSignature) {
+		t.Errorf("wrong key: %v", err)
+	}
+
+	for _, bad := range []string{"", "a.b", "a.b.c.d", "!!.e30.", "e30.!!.", "e30.e30.!!", "bnVsbA.W10."} {
+		if _, err := parseJWT(bad); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
+
+func TestJWTExpiry(t *testing.T) {
+	tests := []struct {
+		claims map[string]interface{}
+		want   error
+	}{
+		{map[string]interface{}{"exp": float64(time.Now().Add(time.Minute).Unix())}, nil},
+		{map[string]interface{}{"exp": float64(time.Now().Add(-time.Minute).Unix())}, errJWTExpired},
+		{map[string]interface{}{}, errors.New("token has no exp claim")},
+		{map[string]interface{}{"exp": "tomorrow"}, errors.New("token has no exp claim")},
+	}
+	for _, tt := range tests {
+		err := (&jwtToken{Claims: tt.claims}).CheckExpiry()
+		if tt.want == nil && err != nil || tt.want != nil && (err == nil || err.Error() != tt.want.Error()) {
+			t.Errorf("%v: error %v, want %v", tt.claims, err, tt.want)
+		}
+	}
+}
+
+// Подделки из подсказок a01_4: каждая принимается уязвимой проверкой, пока включена
+// ее ошибка, и всегда отклоняется исправленной
+func TestVerifyJWTForgeries(t *testing.T) {
+	const learner = "0123456789abcdef0123456789abcdef"
+	future := time.Now().Add(time.Hour)
+	forgeries := []struct {
+		flaw  string
+		token string
+	}{
+		{jwtFlawNone, mustSignJWT(t, jwtHeader{Alg: "none", Typ: "JWT"}, testJWTClaims("admin", future), nil)},
+		{jwtFlawConfusion, mustSignJWT(t, jwtHeader{Alg: "HS256", Typ: "JWT", Kid: jwtRSAKid}, testJWTClaims("admin", future), jwtPublicPEM())},
+		{jwtFlawKid, mustSignJWT(t, jwtHeader{Alg: "HS256", Typ: "JWT", Kid: "../../../../dev/null"}, testJWTClaims("admin", future), []byte{})},
+		{jwtFlawWeak, mustSignJWT(t, jwtHeader{Alg: "HS256", Typ: "JWT", Kid: jwtHMACKid}, testJWTClaims("admin", future), jwtWeakSecret(learner))},
+		{jwtFlawExp, leakedAdminJWT(learner)},
+	}
+
+	verify := func(token string) error {
+		r := httptest.NewRequest("GET", "/api/v1/auth/verify", nil)
+		r.AddCookie(&http.Cookie{Name: learnerCookieName, Value: learner})
+		parsed, err := parseJWT(token)
+		if err != nil {
+			return err
+		}
+		return verifyJWTInsecure(httptest.NewRecorder(), r, domains.Get(learner), parsed)
+	}
+	d := domains.Get(learner)
+	for _, f := range forgeries {
+		if err := verify(f.token); err != nil {
+			t.Errorf("%s on: %v", f.flaw, err)
+		}
+		jwtFlaws.Set(d, f.flaw, false)
+		if err := verify(f.token); err == nil {
+			t.Errorf("%s off: token accepted", f.flaw)
+		}
+		jwtFlaws.Set(d, f.flaw, true)
+
+		parsed, err := parseJWT(f.token)
+		if err == nil {
+			err = verifyJWT(parsed)
+		}
+		if err == nil {
+			t.Errorf("%s: accepted by verifyJWT", f.flaw)
+		}
+	}
+}
+
+func TestVerifyJWT(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	hs := mustSignJWT(t, jwtHeader{Alg: "HS256", Typ: "JWT", Kid: jwtHMACKid}, testJWTClaims("user", future), serverKey("jwt"))
+	rs := mustSignJWT(t, jwtHeader{Alg: "RS256", Typ: "JWT", Kid: jwtRSAKid}, testJWTClaims("user", future), jwtRSAKey())
+	// Claims администратора с подписью токена пользователя
+	admin := strings.Split(mustSignJWT(t, jwtHeader{Alg: "HS256", Typ: "JWT", Kid: jwtHMACKid}, testJWTClaims("admin", future), []byte("guess")), ".")
+	tampered := admin[0] + "." + admin[1] + "." + strings.Split(hs, ".")[2]
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"hs256", hs, true},
+		{"rs256", rs, true},
+		{"tampered", tampered, false},
+		{"rs256 with hs1", mustSignJWT(t, jwtHeader{Alg: "RS256", Kid: jwtHMACKid}, testJWTClaims("user", future), jwtRSAKey()), false},
+		{"no kid", mustSignJWT(t, jwtHeader{Alg: "HS256"}, testJWTClaims("user", future), serverKey("jwt")), false},
+		{"expired", mustSignJWT(t, jwtHeader{Alg: "HS256", Kid: jwtHMACKid}, testJWTClaims("user", time.Now().Add(-time.Minute)), serverKey("jwt")), false},
+		{"no exp", mustSignJWT(t, jwtHeader{Alg: "HS256", Kid: jwtHMACKid}, map[string]interface{}{"role": "user"}, serverKey("jwt")), false},
+	}
+	for _, tt := range tests {
+		parsed, err := parseJWT(tt.token)
+		if err == nil {
+			err = verifyJWT(parsed)
+		}
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package endpoints
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Отключаемые ошибки подсистем стенда (проверка JWT, сессии, защита входа, второй фактор,
+// сброс пароля). У учащегося по умолчанию включены все; выключая их по одной, можно
+// проверить каждую атаку отдельно
+
+type flawSet struct {
+	name  string // Префикс ключа в данных учащегося
+	flaws []string
+}
+
+func (s flawSet) known(flaw string) bool {
+	for _, f := range s.flaws {
+		if f == flaw {
+			return true
+		}
+	}
+	return false
+}
+
+// Включить или выключить ошибку; false, если такой нет
+func (s flawSet) Set(d *domain, flaw string, enabled bool) bool {
+	if !s.known(flaw) {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.flawsOff == nil {
+		d.flawsOff = make(map[string]bool)
+	}
+	d.flawsOff[s.name+"/"+flaw] = !enabled
+	return true
+}
+
+// Включена ли ошибка у учащегося
+func (s flawSet) On(d *domain, flaw string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return !d.flawsOff[s.name+"/"+flaw]
+}
+
+// Эндпоинт настройки: GET - состояние ошибок и адреса задания, POST flaw=<имя>&enabled=true|false
+func (s flawSet) Handler(links map[string]interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d := learnerDomain(w, r)
+		if r.Method == "POST" {
+			if !s.Set(d, r.FormValue("flaw"), r.FormValue("enabled") == "true") {
+				sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+					"status":  "error",
+					"message": "flaw must be one of: " + strings.Join(s.flaws, ", "),
+				})
+				return
+			}
+		}
+		flaws := make(map[string]bool)
+		for _, f := range s.flaws {
+			flaws[f] = s.On(d, f)
+		}
+		response := map[string]interface{}{"flaws": flaws}
+		for k, v := range links {
+			response[k] = v
+		}
+		sendJSON(w, response)
+	}
+}
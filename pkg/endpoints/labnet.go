@@ -0,0 +1,444 @@
+package endpoints
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Сеть стенда: соединения, которые "сервер" открывает сам (внешние сущности XML, вебхуки,
+// загрузка по URL), доставляются сервисам внутри процесса через net.Pipe. Настоящая сеть
+// не нужна и не используется: имя, которого нет в labDNS, не резолвится, а адрес без сервиса
+// недоступен
+
+// Сервис в сети стенда: обслуживает одно соединение. learner - учащийся,
+// от имени которого сервер открыл соединение
+type labService func(conn net.Conn, learner string)
+
+// HTTP-обработчик сервиса стенда
+type labHandler func(w http.ResponseWriter, r *http.Request, learner string)
+
+// Адрес сервера стенда во внутренней сети
+const labServerAddr = "10.0.2.15"
+
+// Имена сети стенда
+var labDNS = map[string]string{
+	"localhost":                 "127.0.0.1",
+	"redis.internal":            "10.0.3.30",
+	oobHost:                     "203.0.113.10",
+	partnerHost:                 "198.51.100.20",
+	"self-signed.badssl.lab":    "198.51.100.30",
+	"expired.badssl.lab":        "198.51.100.30",
+	"wrong.host.badssl.lab":     "198.51.100.30",
+	"untrusted-root.badssl.lab": "198.51.100.30",
+}
+
+// Сервисы по адресу ip:port
+var labServices = map[string]labService{}
+
+// Ограничения запросов в сети стенда
+const (
+	labRequestTimeout = 5 * time.Second
+	labMaxResponse    = 64 << 10
+)
+
+// Сетевое окружение одного запроса учащегося: резолвер с состоянием (для DNS rebinding)
+// и журнал адресов, к которым сервер подключался
+type labNet struct {
+	learner string
+	source  string // Адрес, с которого идут подключения; пустой - сервер стенда
+
+	mu        sync.Mutex
+	lookups   map[string]int
+	dialed    []net.IP
+	localFile bool // Прочитан файл сервера (file://)
+}
+
+func newLabNet(w http.ResponseWriter, r *http.Request) *labNet {
+	return &labNet{learner: learnerID(w, r), lookups: make(map[string]int)}
+}
+
+// Разрешить имя хоста. IP-адрес в любой записи, которую принимает inet_aton (2130706433,
+// 0x7f000001, 0177.0.0.1, 127.1) или IPv6, возвращается как есть
+func (n *labNet) Lookup(host string) ([]net.IP, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if ip := parseLabIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	// <ip1 hex>.<ip2 hex>.rebind.lab: ответы по очереди - первый адрес, второй, снова первый
+	if name, ok := strings.CutSuffix(host, ".rebind.lab"); ok {
+		first, second, ok := strings.Cut(name, ".")
+		a, errA := hex.DecodeString(first)
+		b, errB := hex.DecodeString(second)
+		if ok && errA == nil && errB == nil && len(a) == 4 && len(b) == 4 {
+			n.mu.Lock()
+			i := n.lookups[host]
+			n.lookups[host]++
+			n.mu.Unlock()
+			if i%2 == 1 {
+				return []net.IP{net.IP(b)}, nil
+			}
+			return []net.IP{net.IP(a)}, nil
+		}
+	}
+	if addr, ok := labDNS[host]; ok {
+		return []net.IP{net.ParseIP(addr).To4()}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// IP-адрес в записи inet_aton или IPv6. 0.0.0.0 и ::1 ведут на локальный хост
+func parseLabIP(host string) net.IP {
+	var ip net.IP
+	if strings.Contains(host, ":") {
+		ip = net.ParseIP(host)
+		if ip == nil {
+			return nil
+		}
+		if ip.Equal(net.IPv6loopback) {
+			return net.IPv4(127, 0, 0, 1).To4()
+		}
+		if v4 := ip.To4(); v4 != nil {
+			ip = v4
+		}
+	} else {
+		ip = parseInetAton(host)
+		if ip == nil {
+			return nil
+		}
+	}
+	if ip.IsUnspecified() {
+		return net.IPv4(127, 0, 0, 1).To4()
+	}
+	return ip
+}
+
+// a, a.b, a.b.c, a.b.c.d; части десятичные, 0x - шестнадцатеричные, 0 - восьмеричные
+func parseInetAton(s string) net.IP {
+	parts := strings.Split(s, ".")
+	if len(parts) > 4 {
+		return nil
+	}
+	nums := make([]uint64, len(parts))
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 0, 32)
+		if err != nil || p == "" || strings.HasPrefix(p, "+") {
+			return nil
+		}
+		nums[i] = v
+	}
+	// Последняя часть заполняет оставшиеся байты
+	last := len(nums) - 1
+	if nums[last] >= 1<<(8*(4-last)) {
+		return nil
+	}
+	var v uint32
+	for i, num := range nums[:last] {
+		if num > 255 {
+			return nil
+		}
+		v |= uint32(num) << (24 - 8*i)
+	}
+	v |= uint32(nums[last])
+	return net.IPv4(byte(v>>24), byte(v>>16), byte(v>>8), byte(v)).To4()
+}
+
+// Открыть соединение с сервисом стенда. Имя резолвится при каждом подключении
+func (n *labNet) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := n.Lookup(host)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	return n.dialIP(ips[0], port)
+}
+
+func (n *labNet) dialIP(ip net.IP, port string) (net.Conn, error) {
+	n.mu.Lock()
+	n.dialed = append(n.dialed, ip)
+	n.mu.Unlock()
+	remote := &net.TCPAddr{IP: ip}
+	remote.Port, _ = strconv.Atoi(port)
+	service, ok := labServices[net.JoinHostPort(ip.String(), port)]
+	if !ok {
+		// Известный хост без сервиса на порту отказывает, до остальных нет маршрута
+		errno := syscall.EHOSTUNREACH
+		if labHostKnown(ip) {
+			errno = syscall.ECONNREFUSED
+		}
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Addr: remote, Err: os.NewSyscallError("connect", errno)}
+	}
+	from := net.ParseIP(labServerAddr)
+	if n.source != "" {
+		from = net.ParseIP(n.source)
+	}
+	if ip.IsLoopback() {
+		from = ip
+	}
+	local := &net.TCPAddr{IP: from, Port: 40000 + int(time.Now().UnixNano()%20000)}
+	client, server := net.Pipe()
+	go service(&labConn{Conn: server, local: remote, remote: local}, n.learner)
+	return &labConn{Conn: client, local: local, remote: remote}, nil
+}
+
+// На каком-то порту этого адреса есть сервис
+func labHostKnown(ip net.IP) bool {
+	prefix := ip.String() + ":"
+	for addr := range labServices {
+		if strings.HasPrefix(addr, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Сервер подключался к внутреннему адресу или читал свой файл
+func (n *labNet) ReachedInternal() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.localFile {
+		return true
+	}
+	for _, ip := range n.dialed {
+		if internalIP(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTP-клиент сервера стенда. Сертификаты HTTPS проверяются по корневому сертификату стенда
+func (n *labNet) Client() *http.Client {
+	return n.ClientTLS(&tls.Config{RootCAs: labRootCAs()})
+}
+
+// HTTP-клиент с заданными настройками TLS
+func (n *labNet) ClientTLS(tlsConfig *tls.Config) *http.Client {
+	return newLabHTTPClient(n.DialContext, tlsConfig)
+}
+
+func newLabHTTPClient(dial func(context.Context, string, string) (net.Conn, error), tlsConfig *tls.Config) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:       dial,
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: true,
+		},
+		Timeout: labRequestTimeout,
+	}
+}
+
+// Клиент для адресов от пользователя: подключения к внутренним адресам запрещены.
+// Проверяется адрес, к которому действительно идет подключение, поэтому ни перенаправление,
+// ни повторное разрешение имени проверку не обходят
+func (n *labNet) ExternalClient() *http.Client {
+	return newLabHTTPClient(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := n.Lookup(host)
+		if err != nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+		}
+		for _, ip := range ips {
+			if internalIP(ip) {
+				return nil, errInternalAddress
+			}
+		}
+		return n.dialIP(ips[0], port)
+	}, &tls.Config{RootCAs: labRootCAs()})
+}
+
+var errInternalAddress = errors.New("requests to internal addresses are not allowed")
+
+// HTTP-клиент сервера стенда для текущего учащегося
+func labClient(w http.ResponseWriter, r *http.Request) *http.Client {
+	return newLabNet(w, r).Client()
+}
+
+// Ответ сервиса стенда (Status - 0, если протокол не HTTP)
+type labResponse struct {
+	Status      int
+	ContentType string
+	Body        string
+	TLS         *tls.ConnectionState
+}
+
+// Выполнить запрос в сети стенда; тело ответа ограничено labMaxResponse
+func labFetch(ctx context.Context, client *http.Client, method, target string) (*labResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, labMaxResponse))
+	if err != nil {
+		return nil, err
+	}
+	return &labResponse{Status: resp.StatusCode, ContentType: resp.Header.Get("Content-Type"), Body: string(body), TLS: resp.TLS}, nil
+}
+
+// GET по адресу в сети стенда; ответ с ошибкой (4xx, 5xx) - ошибка
+func labGet(ctx context.Context, client *http.Client, target string) (string, error) {
+	resp, err := labFetch(ctx, client, "GET", target)
+	if err != nil {
+		return "", err
+	}
+	if resp.Status >= 400 {
+		return "", errors.New(http.StatusText(resp.Status))
+	}
+	return resp.Body, nil
+}
+
+// Запрос gopher://host:port/<тип><данные>: данные после типа отправляются как есть,
+// ответ читается, пока сервис отвечает. path - путь из URL (уже декодированный)
+func (n *labNet) Gopher(ctx context.Context, host, port, path string) (string, error) {
+	if port == "" {
+		port = "70"
+	}
+	conn, err := n.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	selector := strings.TrimPrefix(path, "/")
+	if selector != "" {
+		selector = selector[1:]
+	}
+	go func() {
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+		io.WriteString(conn, selector)
+	}()
+	var out strings.Builder
+	buf := make([]byte, 4096)
+	for out.Len() < labMaxResponse {
+		conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+		k, err := conn.Read(buf)
+		out.Write(buf[:k])
+		if err != nil {
+			break
+		}
+	}
+	return out.String(), nil
+}
+
+// Запрос по URL, как его выполняет curl: http(s), gopher и file
+func (n *labNet) Curl(w http.ResponseWriter, r *http.Request, rawURL string) (*labResponse, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return labFetch(r.Context(), n.Client(), "GET", rawURL)
+	case "gopher":
+		out, err := n.Gopher(r.Context(), u.Hostname(), u.Port(), u.Path)
+		if err != nil {
+			return nil, err
+		}
+		return &labResponse{Body: out}, nil
+	case "file":
+		content, err := readServerFile(w, r, u.Path)
+		if err != nil {
+			return nil, err
+		}
+		n.mu.Lock()
+		n.localFile = true
+		n.mu.Unlock()
+		return &labResponse{ContentType: "text/plain", Body: string(content)}, nil
+	}
+	return nil, fmt.Errorf("protocol %q not supported or disabled in libcurl", u.Scheme)
+}
+
+// Соединение через net.Pipe с адресами сети стенда
+type labConn struct {
+	net.Conn
+	local, remote net.Addr
+}
+
+func (c *labConn) LocalAddr() net.Addr  { return c.local }
+func (c *labConn) RemoteAddr() net.Addr { return c.remote }
+
+// HTTP-сервис: один запрос на соединение
+func httpService(h labHandler) labService {
+	return func(conn net.Conn, learner string) {
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(labRequestTimeout))
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			io.WriteString(conn, "HTTP/1.1 400 Bad Request\r\nConnection: close\r\n\r\n")
+			return
+		}
+		req.RemoteAddr = conn.RemoteAddr().String()
+		if tc, ok := conn.(*tls.Conn); ok {
+			state := tc.ConnectionState()
+			req.TLS = &state
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req, learner)
+		resp := rec.Result()
+		resp.ContentLength = int64(rec.Body.Len())
+		resp.Close = true
+		resp.Write(conn)
+	}
+}
+
+// HTTPS-сервис: TLS с сертификатом по имени из SNI
+func tlsService(certs func(name string) *tls.Certificate, inner labService) labService {
+	return func(conn net.Conn, learner string) {
+		tc := tls.Server(conn, &tls.Config{
+			GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+				if cert := certs(hello.ServerName); cert != nil {
+					return cert, nil
+				}
+				return nil, fmt.Errorf("no certificate for %q", hello.ServerName)
+			},
+		})
+		defer tc.Close()
+		tc.SetDeadline(time.Now().Add(labRequestTimeout))
+		if err := tc.Handshake(); err != nil {
+			return
+		}
+		inner(tc, learner)
+	}
+}
+
+// Проверить цепочку сертификата сервера по корневому сертификату стенда
+func verifyLabCertificate(state *tls.ConnectionState) error {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return errors.New("no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       state.ServerName,
+		Roots:         labRootCAs(),
+		Intermediates: intermediates,
+	})
+	return err
+}
@@ -0,0 +1,92 @@
+package endpoints
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// Сертификаты HTTPS-сервисов сети стенда. Корневой сертификат стенда - единственный,
+// которому доверяет сервер; сертификаты badssl.lab сломаны так же, как на badssl.com
+
+type labPKI struct {
+	roots *x509.CertPool
+	certs map[string]*tls.Certificate // По имени хоста
+}
+
+var labCerts = sync.OnceValue(func() *labPKI {
+	now := time.Now()
+	ca, caKey := newLabCert("Lab Root CA", nil, now.AddDate(-1, 0, 0), now.AddDate(10, 0, 0), nil, nil)
+	rogue, rogueKey := newLabCert("Totally Trusted Root CA", nil, now.AddDate(-1, 0, 0), now.AddDate(10, 0, 0), nil, nil)
+	pki := &labPKI{roots: x509.NewCertPool(), certs: make(map[string]*tls.Certificate)}
+	pki.roots.AddCert(ca)
+
+	issue := func(host string, names []string, notBefore, notAfter time.Time, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) {
+		cert, key := newLabCert(host, names, notBefore, notAfter, parent, parentKey)
+		chain := [][]byte{cert.Raw}
+		if parent != nil {
+			chain = append(chain, parent.Raw)
+		}
+		pki.certs[host] = &tls.Certificate{Certificate: chain, PrivateKey: key, Leaf: cert}
+	}
+	valid := now.AddDate(1, 0, 0)
+	issue(oobHost, []string{oobHost}, now.AddDate(0, -1, 0), valid, ca, caKey)
+	issue(partnerHost, []string{partnerHost}, now.AddDate(0, -1, 0), valid, ca, caKey)
+	issue("self-signed.badssl.lab", []string{"self-signed.badssl.lab"}, now.AddDate(0, -1, 0), valid, nil, nil)
+	issue("expired.badssl.lab", []string{"expired.badssl.lab"}, now.AddDate(-2, 0, 0), now.AddDate(-1, 0, 0), ca, caKey)
+	issue("wrong.host.badssl.lab", []string{"badssl.lab"}, now.AddDate(0, -1, 0), valid, ca, caKey)
+	issue("untrusted-root.badssl.lab", []string{"untrusted-root.badssl.lab"}, now.AddDate(0, -1, 0), valid, rogue, rogueKey)
+	return pki
+})
+
+// Корневые сертификаты, которым доверяет сервер стенда
+func labRootCAs() *x509.CertPool {
+	return labCerts().roots
+}
+
+// Сертификат хоста сети стенда
+func labCertificate(host string) *tls.Certificate {
+	return labCerts().certs[host]
+}
+
+// Сертификат с ключом ECDSA P-256. Без parent - самоподписанный (для корневых - с CA)
+func newLabCert(cn string, names []string, notBefore, notAfter time.Time, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"VulnWeb Lab"}},
+		DNSNames:     names,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent, parentKey
+	} else if names == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+		tmpl.ExtKeyUsage = nil
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	return cert, key
+}
@@ -0,0 +1,233 @@
+package endpoints
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Фильтры поиска LDAP (RFC 4515) для задания на LDAP Injection. Поддерживается:
+// & | ! (в том числе пустые (&) и (|) из RFC 4526), равенство, присутствие (attr=*)
+// и подстроки с *, экранирование \XX. Сравнение без учета регистра, как у uid и mail в OpenLDAP
+
+// Запись каталога: атрибут -> значения
+type ldapEntry map[string][]string
+
+type ldapFilter interface {
+	match(e ldapEntry) bool
+}
+
+type (
+	ldapAnd []ldapFilter
+	ldapOr  []ldapFilter
+	ldapNot struct{ f ldapFilter }
+
+	ldapPresent struct{ attr string }
+	ldapEqual   struct{ attr, value string }
+
+	// attr=initial*any*...*final
+	ldapSubstring struct {
+		attr           string
+		initial, final string
+		any            []string
+	}
+)
+
+const (
+	ldapMaxFilterLen = 4096
+	ldapMaxDepth     = 32
+)
+
+var errLDAPFilter = errors.New("bad search filter")
+
+// Разобрать фильтр; после закрывающей скобки ничего быть не должно
+func parseLDAPFilter(s string) (ldapFilter, error) {
+	if len(s) > ldapMaxFilterLen {
+		return nil, fmt.Errorf("%w: filter is too long", errLDAPFilter)
+	}
+	p := &ldapParser{s: s}
+	f, err := p.filter(0)
+	if err == nil && p.pos < len(p.s) {
+		err = fmt.Errorf("%w: unexpected %q at %d", errLDAPFilter, p.s[p.pos:], p.pos)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Записи каталога, подходящие под фильтр
+func ldapSearch(entries []ldapEntry, f ldapFilter) []ldapEntry {
+	var found []ldapEntry
+	for _, e := range entries {
+		if f.match(e) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+type ldapParser struct {
+	s   string
+	pos int
+}
+
+func (p *ldapParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s at %d", errLDAPFilter, fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *ldapParser) filter(depth int) (ldapFilter, error) {
+	if depth > ldapMaxDepth {
+		return nil, p.errorf("filter is nested too deeply")
+	}
+	if p.pos >= len(p.s) || p.s[p.pos] != '(' {
+		return nil, p.errorf("expected (")
+	}
+	p.pos++
+	if p.pos >= len(p.s) {
+		return nil, p.errorf("unexpected end of filter")
+	}
+
+	var f ldapFilter
+	var err error
+	switch p.s[p.pos] {
+	case '&', '|':
+		op := p.s[p.pos]
+		p.pos++
+		var list []ldapFilter
+		for err == nil && p.pos < len(p.s) && p.s[p.pos] == '(' {
+			var sub ldapFilter
+			if sub, err = p.filter(depth + 1); err == nil {
+				list = append(list, sub)
+			}
+		}
+		if op == '&' {
+			f = ldapAnd(list)
+		} else {
+			f = ldapOr(list)
+		}
+	case '!':
+		p.pos++
+		var sub ldapFilter
+		if sub, err = p.filter(depth + 1); err == nil {
+			f = ldapNot{sub}
+		}
+	default:
+		f, err = p.item()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.s) || p.s[p.pos] != ')' {
+		return nil, p.errorf("expected )")
+	}
+	p.pos++
+	return f, nil
+}
+
+// attr=value; значение заканчивается на ')'
+func (p *ldapParser) item() (ldapFilter, error) {
+	eq := strings.IndexByte(p.s[p.pos:], '=')
+	if eq <= 0 {
+		return nil, p.errorf("expected attribute=value")
+	}
+	attr := p.s[p.pos : p.pos+eq]
+	if strings.ContainsAny(attr, "()*\\&|!") {
+		return nil, p.errorf("bad attribute %q", attr)
+	}
+	p.pos += eq + 1
+
+	// Значение режем по неэкранированным *, ( в значении недопустима
+	var parts []string
+	var cur strings.Builder
+	for p.pos < len(p.s) && p.s[p.pos] != ')' {
+		c := p.s[p.pos]
+		switch c {
+		case '(':
+			return nil, p.errorf("unescaped ( in value")
+		case '*':
+			parts = append(parts, cur.String())
+			cur.Reset()
+			p.pos++
+		case '\\':
+			if p.pos+3 > len(p.s) {
+				return nil, p.errorf("bad escape")
+			}
+			b, err := strconv.ParseUint(p.s[p.pos+1:p.pos+3], 16, 8)
+			if err != nil {
+				return nil, p.errorf("bad escape")
+			}
+			cur.WriteByte(byte(b))
+			p.pos += 3
+		default:
+			cur.WriteByte(c)
+			p.pos++
+		}
+	}
+	parts = append(parts, cur.String())
+
+	attr = strings.ToLower(attr)
+	switch {
+	case len(parts) == 1:
+		return ldapEqual{attr, parts[0]}, nil
+	case len(parts) == 2 && parts[0] == "" && parts[1] == "":
+		return ldapPresent{attr}, nil
+	}
+	return ldapSubstring{attr: attr, initial: parts[0], any: parts[1 : len(parts)-1], final: parts[len(parts)-1]}, nil
+}
+
+func (f ldapAnd) match(e ldapEntry) bool {
+	for _, sub := range f {
+		if !sub.match(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f ldapOr) match(e ldapEntry) bool {
+	for _, sub := range f {
+		if sub.match(e) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f ldapNot) match(e ldapEntry) bool { return !f.f.match(e) }
+
+func (f ldapPresent) match(e ldapEntry) bool { return len(e[f.attr]) > 0 }
+
+func (f ldapEqual) match(e ldapEntry) bool {
+	for _, v := range e[f.attr] {
+		if strings.EqualFold(v, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f ldapSubstring) match(e ldapEntry) bool {
+	for _, v := range e[f.attr] {
+		v, initial := strings.ToLower(v), strings.ToLower(f.initial)
+		if !strings.HasPrefix(v, initial) {
+			continue
+		}
+		rest := v[len(initial):]
+		ok := true
+		for _, part := range f.any {
+			part = strings.ToLower(part)
+			i := strings.Index(rest, part)
+			if i < 0 {
+				ok = false
+				break
+			}
+			rest = rest[i+len(part):]
+		}
+		if ok && strings.HasSuffix(rest, strings.ToLower(f.final)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package endpoints
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func testLDAPDirectory() []ldapEntry {
+	return []ldapEntry{
+		{"objectclass": {"top", "person"}, "uid": {"john"}, "cn": {"John Doe"}, "mail": {"john.doe@company.com"}},
+		{"objectclass": {"top", "person"}, "uid": {"jane"}, "cn": {"Jane Smith"}, "mail": {"jane.smith@company.com"}},
+		{"objectclass": {"top", "person"}, "uid": {"admin"}, "cn": {"Admin User"}, "mail": {"admin@company.com"}},
+		{"objectclass": {"top", "device"}, "cn": {"printer"}},
+	}
+}
+
+func TestLDAPSearch(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   string // cn найденных записей через запятую
+	}{
+		{"(uid=john)", "John Doe"},
+		{"(UID=JOHN)", "John Doe"},
+		{"(uid=nobody)", ""},
+		{"(uid=*)", "John Doe,Jane Smith,Admin User"},
+		{"(uid=j*)", "John Doe,Jane Smith"},
+		{"(mail=*@company.com)", "John Doe,Jane Smith,Admin User"},
+		{"(cn=*Sm*th)", "Jane Smith"},
+		{"(cn=a*u*r)", "Admin User"},
+		{"(cn=a*r*u)", ""},
+		{"(!(objectClass=person))", "printer"},
+		{"(|(uid=john)(uid=jane))", "John Doe,Jane Smith"},
+		{"(&(objectClass=person)(uid=admin))", "Admin User"},
+		{"(&(objectClass=person)(uid=admin)(&))", "Admin User"},
+		{"(&(objectClass=person)(uid=admin)(|))", ""},
+		{"(&)", "John Doe,Jane Smith,Admin User,printer"},
+		{`(uid=\6a\6f\68\6e)`, "John Doe"},
+		{`(uid=\2a)`, ""},
+	}
+	for _, tt := range tests {
+		f, err := parseLDAPFilter(tt.filter)
+		if err != nil {
+			t.Errorf("%s: %v", tt.filter, err)
+			continue
+		}
+		var names []string
+		for _, e := range ldapSearch(testLDAPDirectory(), f) {
+			names = append(names, e["cn"][0])
+		}
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("%s: found %q, want %q", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestLDAPFilterErrors(t *testing.T) {
+	for _, filter := range []string{
+		"",
+		"uid=john",
+		"(uid=john",
+		"(uid=john))",
+		"(uid=john)(uid=jane)",
+		"(=john)",
+		"(uid)",
+		"(uid=jo(hn)",
+		`(uid=\4)`,
+		`(uid=\zz)`,
+		"(&(uid=john)",
+		"(!uid=john)",
+		"(u*d=john)",
+		strings.Repeat("(!", 40) + "(uid=john)" + strings.Repeat(")", 40),
+		fmt.Sprintf("(uid=%s)", strings.Repeat("a", ldapMaxFilterLen)),
+	} {
+		if _, err := parseLDAPFilter(filter); !errors.Is(err, errLDAPFilter) {
+			t.Errorf("%q: error %v", filter, err)
+		}
+	}
+}
+
+// Экранирование из исправленной версии не дает изменить структуру фильтра
+func TestLDAPEscaper(t *testing.T) {
+	for _, username := range []string{"admin)(&", "*", "admin)(|(uid=*", `\2a`, "a\x00b"} {
+		f, err := parseLDAPFilter(fmt.Sprintf(ldapUserFilter, ldapEscaper.Replace(username)))
+		if err != nil {
+			t.Errorf("%q: %v", username, err)
+			continue
+		}
+		if found := ldapSearch(testLDAPDirectory(), f); len(found) != 0 {
+			t.Errorf("%q: found %d entries", username, len(found))
+		}
+	}
+}
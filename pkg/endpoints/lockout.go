@@ -0,0 +1,292 @@
+package endpoints
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Защита входа от перебора паролей: счетчики неудачных попыток по IP и по учетной
+// записи, растущая задержка для IP и временная блокировка учетной записи. Исправленные
+// обработчики работают через secureLoginGuard, уязвимые - через labLoginGuard с типичными
+// ошибками; каждую можно выключить для учащегося через /api/lab/lockout
+
+const (
+	lockoutThreshold = 5                // Неудачи, после которых учетная запись блокируется
+	lockoutWindow    = 15 * time.Minute // Срок блокировки и хранения счетчиков
+	ipFreeAttempts   = 3                // Неудачи с одного IP без задержки
+	passwordHashCost = 50 * time.Millisecond
+)
+
+// Ошибки защиты входа в уязвимом режиме
+const (
+	lockoutFlawXFF   = "xff_trusted"      // IP клиента берется из X-Forwarded-For
+	lockoutFlawCase  = "case_sensitive"   // Счетчик учетной записи - по email как введен
+	lockoutFlawReset = "reset_on_success" // Успешный вход сбрасывает счетчик IP
+	lockoutFlawRace  = "race"             // Проверка и учет попытки не атомарны
+)
+
+var lockoutFlaws = flawSet{name: "lockout", flaws: []string{lockoutFlawXFF, lockoutFlawCase, lockoutFlawReset, lockoutFlawRace}}
+
+type attemptRecord struct {
+	failures int
+	last     time.Time
+}
+
+const (
+	attemptsPerLearner   = 1000        // Счетчиков у одного учащегося (разные IP из X-Forwarded-For, варианты email)
+	attemptSweepInterval = time.Minute // Как часто удалять устаревшие счетчики
+)
+
+// Счетчики неудачных попыток по учащимся. Ключ начинается с ID учащегося и "|"
+type attemptTable struct {
+	mu      sync.Mutex
+	records map[string]map[string]attemptRecord
+	swept   time.Time
+}
+
+var loginAttempts = &attemptTable{records: make(map[string]map[string]attemptRecord)}
+
+func attemptLearner(key string) string {
+	learner, _, _ := strings.Cut(key, "|")
+	return learner
+}
+
+// Счетчик без неудач дольше lockoutWindow обнуляется
+func (t *attemptTable) get(key string, now time.Time) attemptRecord {
+	rec := t.records[attemptLearner(key)][key]
+	if now.Sub(rec.last) > lockoutWindow {
+		return attemptRecord{}
+	}
+	return rec
+}
+
+// Удалить устаревшие счетчики; не чаще раза в attemptSweepInterval
+func (t *attemptTable) sweep(now time.Time) {
+	if now.Sub(t.swept) < attemptSweepInterval {
+		return
+	}
+	t.swept = now
+	for learner, records := range t.records {
+		for key, rec := range records {
+			if now.Sub(rec.last) > lockoutWindow {
+				delete(records, key)
+			}
+		}
+		if len(records) == 0 {
+			delete(t.records, learner)
+		}
+	}
+}
+
+// Сколько ждать, пока у учащегося освободится место для нового счетчика; 0 - место есть.
+// Старые счетчики не вытесняются: иначе перебором адресов можно было бы стереть блокировку
+func (t *attemptTable) full(key string, now time.Time) time.Duration {
+	records := t.records[attemptLearner(key)]
+	if _, ok := records[key]; ok || len(records) < attemptsPerLearner {
+		return 0
+	}
+	wait := lockoutWindow
+	for _, rec := range records {
+		wait = min(wait, rec.last.Add(lockoutWindow).Sub(now))
+	}
+	return max(wait, time.Second)
+}
+
+func (t *attemptTable) fail(key string, now time.Time) {
+	t.sweep(now)
+	if t.full(key, now) > 0 {
+		return
+	}
+	learner := attemptLearner(key)
+	records := t.records[learner]
+	if records == nil {
+		records = make(map[string]attemptRecord)
+		t.records[learner] = records
+	}
+	rec := t.get(key, now)
+	records[key] = attemptRecord{failures: rec.failures + 1, last: now}
+}
+
+// Сколько ждать до следующей попытки; 0 - попытка разрешена. Второе значение - причина
+// ограничения: блокировка учетной записи или задержка для IP
+func (t *attemptTable) wait(ipKey, accountKey string, now time.Time) (time.Duration, bool) {
+	if rec := t.get(accountKey, now); rec.failures >= lockoutThreshold {
+		return rec.last.Add(lockoutWindow).Sub(now), true
+	}
+	if wait := max(t.full(ipKey, now), t.full(accountKey, now)); wait > 0 {
+		return wait, false
+	}
+	rec := t.get(ipKey, now)
+	if rec.failures < ipFreeAttempts {
+		return 0, false
+	}
+	delay := lockoutWindow
+	if n := rec.failures - ipFreeAttempts; n < 10 {
+		delay = min(time.Second<<n, lockoutWindow)
+	}
+	if left := rec.last.Add(delay).Sub(now); left > 0 {
+		return left, false
+	}
+	return 0, false
+}
+
+// Проверить ограничения без учета попытки
+func (t *attemptTable) Check(ipKey, accountKey string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.wait(ipKey, accountKey, time.Now())
+}
+
+// Проверить ограничения и, если попытка разрешена, сразу учесть ее как неудачную.
+// Проверка и учет под одной блокировкой, поэтому параллельные запросы не проскакивают
+func (t *attemptTable) Begin(ipKey, accountKey string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if wait, account := t.wait(ipKey, accountKey, now); wait > 0 {
+		return wait, account
+	}
+	t.fail(ipKey, now)
+	t.fail(accountKey, now)
+	return 0, false
+}
+
+func (t *attemptTable) Fail(keys ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	for _, key := range keys {
+		t.fail(key, now)
+	}
+}
+
+// Снять одну неудачу, учтенную заранее
+func (t *attemptTable) Forgive(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	records := t.records[attemptLearner(key)]
+	if rec, ok := records[key]; ok && rec.failures > 0 {
+		rec.failures--
+		records[key] = rec
+	}
+}
+
+func (t *attemptTable) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.records[attemptLearner(key)], key)
+}
+
+func (t *attemptTable) Failures(key string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.get(key, time.Now()).failures
+}
+
+// Проверка пароля. Адрес email сравнивается без учета регистра; задержка имитирует
+// медленный хеш (bcrypt) и не зависит от того, существует ли пользователь
+func verifyPassword(d *domain, email, password string) (account, bool) {
+	time.Sleep(passwordHashCost)
+	user, ok := d.findUser(func(a *account) bool { return strings.EqualFold(a.Email, strings.TrimSpace(email)) })
+	if !ok || user.Password != password {
+		return account{}, false
+	}
+	return user, true
+}
+
+// Правила защиты входа
+type loginGuard struct {
+	weak bool // Действуют включенные у учащегося ошибки
+}
+
+var (
+	secureLoginGuard = loginGuard{}
+	labLoginGuard    = loginGuard{weak: true}
+)
+
+func (g loginGuard) flaw(d *domain, name string) bool {
+	return g.weak && lockoutFlaws.On(d, name)
+}
+
+// Вход по email и паролю. Если попытка отклонена, ответ уже отправлен и ok=false.
+// bypassed - пароль подошел после стольких неудач подряд, что учетная запись должна
+// была быть заблокирована
+func (g loginGuard) Login(w http.ResponseWriter, r *http.Request, email, password string) (user account, bypassed, ok bool) {
+	learner := learnerID(w, r)
+	d := domains.Get(learner)
+
+	ip := clientIP(r)
+	if g.flaw(d, lockoutFlawXFF) {
+		// УЯЗВИМОСТЬ: адрес из заголовка, который задает сам клиент
+		if xff := strings.TrimSpace(strings.Split(r.Header.Get("X-Forwarded-For"), ",")[0]); xff != "" {
+			ip = xff
+		}
+	}
+	name := strings.ToLower(strings.TrimSpace(email))
+	if g.flaw(d, lockoutFlawCase) {
+		// УЯЗВИМОСТЬ: вход не различает регистр email, а счетчик различает
+		name = email
+	}
+	ipKey := learner + "|ip|" + ip
+	accountKey := learner + "|account|" + name
+	// Настоящее число неудач подряд для учетной записи, без ошибок уязвимой версии
+	realKey := learner + "|real|" + strings.ToLower(strings.TrimSpace(email))
+
+	var wait time.Duration
+	var locked bool
+	race := g.flaw(d, lockoutFlawRace)
+	if race {
+		// УЯЗВИМОСТЬ: счетчик проверяется до проверки пароля, а растет после нее -
+		// параллельные запросы проходят проверку с одним и тем же значением
+		wait, locked = loginAttempts.Check(ipKey, accountKey)
+	} else {
+		wait, locked = loginAttempts.Begin(ipKey, accountKey)
+	}
+	if wait > 0 {
+		message := "Too many failed attempts from your address, slow down"
+		if locked {
+			message = "Account is temporarily locked due to too many failed attempts"
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		sendJSONStatus(w, http.StatusTooManyRequests, map[string]interface{}{
+			"status":  "error",
+			"message": message,
+		})
+		return account{}, false, false
+	}
+
+	user, ok = verifyPassword(d, email, password)
+	if !ok {
+		if race {
+			loginAttempts.Fail(ipKey, accountKey)
+		}
+		loginAttempts.Fail(realKey)
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":             "error",
+			"message":            "Invalid credentials",
+			"attempts_remaining": max(lockoutThreshold-loginAttempts.Failures(accountKey), 0),
+		})
+		return account{}, false, false
+	}
+
+	if !race {
+		loginAttempts.Forgive(ipKey)
+	}
+	if g.flaw(d, lockoutFlawReset) {
+		// УЯЗВИМОСТЬ: вход в любую учетную запись обнуляет неудачи этого IP
+		loginAttempts.Reset(ipKey)
+	}
+	loginAttempts.Reset(accountKey)
+	bypassed = loginAttempts.Failures(realKey) >= lockoutThreshold
+	loginAttempts.Reset(realKey)
+	return user, bypassed, true
+}
+
+// Ошибки защиты входа учащегося: GET - список, POST flaw=<имя>&enabled=true|false
+var apiLabLockout = lockoutFlaws.Handler(map[string]interface{}{
+	"login":    "/api/v1/auth/bruteforce",
+	"wordlist": "/api/lab/wordlists/passwords.txt",
+})
@@ -0,0 +1,123 @@
+package endpoints
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Почта стенда: письма не уходят на SMTP, а складываются в исходящие учащегося. Ящик
+// учащегося (labUser) читается на /api/lab/mail/<адрес>, чужие ящики закрыты. Остальные
+// сотрудники открывают ссылки из своих писем: запрос идет в сеть стенда с рабочей станции
+// получателя, так что ссылка на oob.lab попадает в журнал сервера учащегося
+
+const (
+	mailPath      = "/api/lab/mail"
+	mailFrom      = "no-reply@company.com"
+	mailMaxOutbox = 50
+	mailReaderIP  = "10.0.5.40" // Рабочие станции сотрудников
+)
+
+// Письмо. Link - ссылка в тексте, ее открывает получатель
+type mailMessage struct {
+	ID      int
+	Time    time.Time
+	To      string
+	Subject string
+	Text    string
+	Link    string
+}
+
+// Отправить письмо; старые письма вытесняются. Получатель, кроме учащегося, открывает ссылку
+func (d *domain) SendMail(learner string, msg mailMessage) {
+	d.mu.Lock()
+	d.nextMail++
+	msg.ID = d.nextMail
+	msg.Time = time.Now().UTC()
+	d.outbox = append(d.outbox, msg)
+	if len(d.outbox) > mailMaxOutbox {
+		d.outbox = d.outbox[len(d.outbox)-mailMaxOutbox:]
+	}
+	d.mu.Unlock()
+	if msg.Link != "" && !strings.EqualFold(msg.To, labUser.Email) {
+		go openMailLink(learner, msg.Link)
+	}
+}
+
+// Письма одного ящика, новые сначала
+func (d *domain) Mailbox(address string) []mailMessage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var messages []mailMessage
+	for i := len(d.outbox) - 1; i >= 0; i-- {
+		if strings.EqualFold(d.outbox[i].To, address) {
+			messages = append(messages, d.outbox[i])
+		}
+	}
+	return messages
+}
+
+// Сотрудник переходит по ссылке. Относительная ссылка ведет на сам стенд, туда бот не ходит
+func openMailLink(learner, link string) {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), labRequestTimeout)
+	defer cancel()
+	n := &labNet{learner: learner, source: mailReaderIP, lookups: make(map[string]int)}
+	labGet(ctx, n.Client(), link)
+}
+
+// Ящики: /api/lab/mail - список, /api/lab/mail/<адрес> - письма (только ящик учащегося)
+func apiLabMail(w http.ResponseWriter, r *http.Request) {
+	d := learnerDomain(w, r)
+	address := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, mailPath), "/")
+	if address == "" {
+		var boxes []map[string]interface{}
+		for _, u := range d.Users() {
+			boxes = append(boxes, map[string]interface{}{
+				"address":  u.Email,
+				"url":      mailPath + "/" + u.Email,
+				"readable": strings.EqualFold(u.Email, labUser.Email),
+			})
+		}
+		sendJSON(w, map[string]interface{}{"mailboxes": boxes})
+		return
+	}
+	if !strings.EqualFold(address, labUser.Email) {
+		sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": "You can only read your own mailbox (" + labUser.Email + ")",
+		})
+		return
+	}
+
+	var items strings.Builder
+	for _, m := range d.Mailbox(address) {
+		link := ""
+		if m.Link != "" {
+			link = fmt.Sprintf(`<p><a href="%s">%s</a></p>`, html.EscapeString(m.Link), html.EscapeString(m.Link))
+		}
+		fmt.Fprintf(&items, `
+		<div class="card">
+			<h3>%s</h3>
+			<p><small>From: %s, %s</small></p>
+			<p>%s</p>%s
+		</div>`, html.EscapeString(m.Subject), mailFrom, m.Time.Format(time.RFC1123),
+			strings.ReplaceAll(html.EscapeString(m.Text), "\n", "<br>"), link)
+	}
+	if items.Len() == 0 {
+		items.WriteString(`<div class="card"><p>No messages</p></div>`)
+	}
+	page := renderPage("Inbox", `
+		<div class="card">
+			<h2>Inbox: `+html.EscapeString(address)+`</h2>
+		</div>`+items.String())
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
@@ -0,0 +1,443 @@
+package endpoints
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Второй фактор TOTP (RFC 6238): подключение приложения-аутентификатора (секрет и
+// otpauth URI), вход в два шага и коды восстановления. У администратора второй фактор
+// подключен с начала, его секрета учащийся не знает. Исправленные обработчики работают
+// через secureMFA, уязвимые - через labMFA с типичными ошибками; каждую можно выключить
+// для учащегося через /api/lab/mfa
+
+const (
+	totpDigits        = 6
+	totpPeriod        = 30 // Секунд на один код
+	totpSkew          = 1  // Соседние интервалы: часы телефона и сервера расходятся
+	totpIssuer        = "VulnWeb"
+	mfaCookieName     = "mfa_token"
+	mfaChallengeTTL   = 5 * time.Minute
+	mfaMaxAttempts    = 5
+	recoveryCodeCount = 8
+)
+
+// Ошибки второго фактора в уязвимом режиме
+const (
+	mfaFlawReuse      = "otp_reuse"        // Использованный код принимается повторно
+	mfaFlawNoLimit    = "no_attempt_limit" // Код можно перебирать без ограничений
+	mfaFlawSkip       = "skip_step"        // Страница после входа не проверяет второй шаг
+	mfaFlawClientFlag = "client_flag"      // Результат второго шага хранится в cookie mfa_passed
+)
+
+var mfaFlaws = flawSet{name: "mfa", flaws: []string{mfaFlawReuse, mfaFlawNoLimit, mfaFlawSkip, mfaFlawClientFlag}}
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Второй фактор пользователя
+type mfaEnrollment struct {
+	Secret   []byte
+	Enabled  bool     // false - подключение начато, но не подтверждено кодом
+	LastStep int64    // Интервал последнего принятого кода
+	Recovery []string // Неиспользованные коды восстановления
+}
+
+// Вход, прошедший проверку пароля и ожидающий код
+type mfaChallenge struct {
+	ID       string
+	UserID   string
+	Created  time.Time
+	Failures int
+}
+
+// Администратор подключил второй фактор заранее
+func seedMFA() map[string]mfaEnrollment {
+	return map[string]mfaEnrollment{
+		"3": {Secret: newTOTPSecret(), Enabled: true, Recovery: newRecoveryCodes()},
+	}
+}
+
+func newTOTPSecret() []byte {
+	secret := make([]byte, 20)
+	rand.Read(secret)
+	return secret
+}
+
+func newRecoveryCodes() []string {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 5)
+		rand.Read(buf)
+		codes[i] = hex.EncodeToString(buf)
+	}
+	return codes
+}
+
+// HOTP (RFC 4226): HMAC-SHA1 от счетчика и динамическое усечение до 6 цифр
+func hotp(secret []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// Интервал, для которого код верен; false - код не подходит ни к одному из соседних
+func totpMatch(secret []byte, code string, now time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	step := now.Unix() / totpPeriod
+	for s := step - totpSkew; s <= step+totpSkew; s++ {
+		if subtle.ConstantTimeCompare([]byte(hotp(secret, uint64(s))), []byte(code)) == 1 {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// Адрес для QR-кода приложения-аутентификатора
+func otpauthURI(email string, secret []byte) string {
+	q := url.Values{}
+	q.Set("secret", totpEncoding.EncodeToString(secret))
+	q.Set("issuer", totpIssuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(totpIssuer+":"+email) + "?" + q.Encode()
+}
+
+func (d *domain) MFA(userID string) (mfaEnrollment, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.mfa[userID]
+	return e, ok
+}
+
+func (d *domain) PutMFA(userID string, e mfaEnrollment) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mfa[userID] = e
+}
+
+// Принять код интервала step. Повтор уже использованного кода (replay) принимается,
+// только если allowReuse
+func (d *domain) AcceptTOTPStep(userID string, step int64, allowReuse bool) (replay, ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e := d.mfa[userID]
+	replay = step <= e.LastStep
+	if replay && !allowReuse {
+		return true, false
+	}
+	e.LastStep = max(e.LastStep, step)
+	d.mfa[userID] = e
+	return replay, true
+}
+
+// Погасить код восстановления; каждый действует один раз
+func (d *domain) UseRecoveryCode(userID, code string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e := d.mfa[userID]
+	for i, c := range e.Recovery {
+		if subtle.ConstantTimeCompare([]byte(c), []byte(code)) == 1 {
+			e.Recovery = append(e.Recovery[:i:i], e.Recovery[i+1:]...)
+			d.mfa[userID] = e
+			return true
+		}
+	}
+	return false
+}
+
+func (d *domain) PutMFAChallenge(c mfaChallenge) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for id, old := range d.mfaChallenges {
+		if time.Since(old.Created) > mfaChallengeTTL {
+			delete(d.mfaChallenges, id)
+		}
+	}
+	d.mfaChallenges[c.ID] = c
+}
+
+func (d *domain) MFAChallenge(id string) (mfaChallenge, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c, ok := d.mfaChallenges[id]
+	if !ok || time.Since(c.Created) > mfaChallengeTTL {
+		return mfaChallenge{}, false
+	}
+	return c, true
+}
+
+func (d *domain) DeleteMFAChallenge(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.mfaChallenges, id)
+}
+
+// Учесть попытку ввода кода заранее, как неудачную. Возвращает число неудач до нее;
+// false - лимит исчерпан (limit 0 - без лимита)
+func (d *domain) BeginMFAAttempt(id string, limit int) (int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c, ok := d.mfaChallenges[id]
+	if !ok || limit > 0 && c.Failures >= limit {
+		return c.Failures, false
+	}
+	c.Failures++
+	d.mfaChallenges[id] = c
+	return c.Failures - 1, true
+}
+
+// Правила второго фактора и сессии, которые выдаются после входа
+type mfaPolicy struct {
+	weak     bool // Действуют включенные у учащегося ошибки
+	sessions sessionStore
+}
+
+var (
+	secureMFA = mfaPolicy{sessions: secureSessions}
+	labMFA    = mfaPolicy{weak: true, sessions: labSessions}
+)
+
+func (p mfaPolicy) flaw(d *domain, name string) bool {
+	return p.weak && mfaFlaws.On(d, name)
+}
+
+// Незавершенный вход из cookie
+func (p mfaPolicy) challenge(r *http.Request, d *domain) (mfaChallenge, bool) {
+	c, err := r.Cookie(mfaCookieName)
+	if err != nil {
+		return mfaChallenge{}, false
+	}
+	return d.MFAChallenge(c.Value)
+}
+
+func (p mfaPolicy) sessionUser(r *http.Request, d *domain) (account, bool) {
+	session, ok := p.sessions.FromRequest(r)
+	if !ok {
+		return account{}, false
+	}
+	return d.User(session.UserID)
+}
+
+// Шаг 1: пароль. Без второго фактора сразу выдается сессия, иначе - cookie незавершенного входа
+func (p mfaPolicy) Login(w http.ResponseWriter, r *http.Request) {
+	d := learnerDomain(w, r)
+	user, ok := verifyPassword(d, r.FormValue("email"), r.FormValue("password"))
+	if !ok {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid email or password",
+		})
+		return
+	}
+	if e, ok := d.MFA(user.ID); !ok || !e.Enabled {
+		if _, _, err := p.sessions.Login(w, r, user); err != nil {
+			sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{"status": "error", "message": "Internal server error"})
+			return
+		}
+		sendJSON(w, map[string]interface{}{
+			"status":       "success",
+			"mfa_required": false,
+			"user":         user.Username,
+		})
+		return
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{"status": "error", "message": "Internal server error"})
+		return
+	}
+	p.sessions.Destroy(w, r)
+	d.PutMFAChallenge(mfaChallenge{ID: id, UserID: user.ID, Created: time.Now()})
+	http.SetCookie(w, &http.Cookie{
+		Name:     mfaCookieName,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(mfaChallengeTTL.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	if p.flaw(d, mfaFlawClientFlag) {
+		// УЯЗВИМОСТЬ: состояние входа хранит клиент
+		http.SetCookie(w, &http.Cookie{Name: "mfa_passed", Value: "false", Path: "/"})
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":       "mfa_required",
+		"mfa_required": true,
+		"next":         "/api/v1/auth/mfa/verify",
+	})
+}
+
+// Шаг 2: код из приложения или код восстановления. bypassed - код принят там, где
+// правильная проверка отказала бы: повтор кода или подбор после исчерпания попыток
+func (p mfaPolicy) Verify(w http.ResponseWriter, r *http.Request) (user account, bypassed, ok bool) {
+	d := learnerDomain(w, r)
+	c, ok := p.challenge(r, d)
+	if !ok {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "No pending sign-in, enter your password first",
+		})
+		return account{}, false, false
+	}
+
+	limit := mfaMaxAttempts
+	if p.flaw(d, mfaFlawNoLimit) {
+		// УЯЗВИМОСТЬ: число попыток не ограничено - миллион кодов перебирается
+		limit = 0
+	}
+	failures, allowed := d.BeginMFAAttempt(c.ID, limit)
+	if !allowed {
+		d.DeleteMFAChallenge(c.ID)
+		sendJSONStatus(w, http.StatusTooManyRequests, map[string]interface{}{
+			"status":  "error",
+			"message": "Too many invalid codes, sign in again",
+		})
+		return account{}, false, false
+	}
+
+	code := strings.TrimSpace(r.FormValue("code"))
+	e, _ := d.MFA(c.UserID)
+	replay, matched := false, false
+	if step, ok := totpMatch(e.Secret, code, time.Now()); ok {
+		// УЯЗВИМОСТЬ (otp_reuse): без учета последнего интервала перехваченный код
+		// действует еще раз, пока не сменится
+		replay, matched = d.AcceptTOTPStep(c.UserID, step, p.flaw(d, mfaFlawReuse))
+	} else {
+		matched = d.UseRecoveryCode(c.UserID, code)
+	}
+	if !matched {
+		response := map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid code",
+		}
+		if limit > 0 {
+			response["attempts_remaining"] = limit - failures - 1
+		}
+		sendJSONStatus(w, http.StatusUnauthorized, response)
+		return account{}, false, false
+	}
+
+	d.DeleteMFAChallenge(c.ID)
+	http.SetCookie(w, &http.Cookie{Name: mfaCookieName, Path: "/", MaxAge: -1})
+	user, _ = d.User(c.UserID)
+	if _, _, err := p.sessions.Login(w, r, user); err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{"status": "error", "message": "Internal server error"})
+		return account{}, false, false
+	}
+	return user, replay || failures >= mfaMaxAttempts, true
+}
+
+// Страница после входа: пользователь по сессии. viaPassword - пускает незавершенный вход,
+// который прошел только пароль
+func (p mfaPolicy) Account(w http.ResponseWriter, r *http.Request) (user account, viaPassword, ok bool) {
+	d := learnerDomain(w, r)
+	if user, ok := p.sessionUser(r, d); ok {
+		return user, false, true
+	}
+	c, pending := p.challenge(r, d)
+	if pending {
+		user, _ = d.User(c.UserID)
+		if p.flaw(d, mfaFlawSkip) {
+			// УЯЗВИМОСТЬ: вход считается выполненным уже после пароля
+			return user, true, true
+		}
+		if flag, err := r.Cookie("mfa_passed"); err == nil && flag.Value == "true" && p.flaw(d, mfaFlawClientFlag) {
+			// УЯЗВИМОСТЬ: результат второго шага берется из cookie, которую задает клиент
+			return user, true, true
+		}
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":       "error",
+			"message":      "Second factor required",
+			"mfa_required": true,
+		})
+		return account{}, false, false
+	}
+	sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+		"status":  "error",
+		"message": "Not signed in",
+	})
+	return account{}, false, false
+}
+
+// Подключение второго фактора: POST без code - новый секрет, POST с code - подтверждение
+// и коды восстановления, GET - состояние
+func (p mfaPolicy) Enroll(w http.ResponseWriter, r *http.Request) {
+	d := learnerDomain(w, r)
+	user, ok := p.sessionUser(r, d)
+	if !ok {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "Sign in first: POST /api/v1/auth/login/no2fa",
+		})
+		return
+	}
+	e, enrolled := d.MFA(user.ID)
+	if r.Method != "POST" {
+		sendJSON(w, map[string]interface{}{
+			"user":        user.Username,
+			"mfa_enabled": enrolled && e.Enabled,
+		})
+		return
+	}
+	if enrolled && e.Enabled {
+		sendJSONStatus(w, http.StatusConflict, map[string]interface{}{
+			"status":  "error",
+			"message": "Two-factor authentication is already enabled",
+		})
+		return
+	}
+
+	code := strings.TrimSpace(r.FormValue("code"))
+	if code == "" {
+		e = mfaEnrollment{Secret: newTOTPSecret()}
+		d.PutMFA(user.ID, e)
+		sendJSON(w, map[string]interface{}{
+			"status":      "pending",
+			"secret":      totpEncoding.EncodeToString(e.Secret),
+			"otpauth_uri": otpauthURI(user.Email, e.Secret),
+			"message":     "Add the key to your authenticator app and confirm with a code",
+		})
+		return
+	}
+	step, ok := totpMatch(e.Secret, code, time.Now())
+	if !enrolled || !ok {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid code",
+		})
+		return
+	}
+	e.Enabled, e.LastStep, e.Recovery = true, step, newRecoveryCodes()
+	d.PutMFA(user.ID, e)
+	sendJSON(w, map[string]interface{}{
+		"status":         "enabled",
+		"recovery_codes": e.Recovery,
+	})
+}
+
+// Ошибки второго фактора учащегося: GET - список, POST flaw=<имя>&enabled=true|false
+var apiLabMFA = mfaFlaws.Handler(map[string]interface{}{
+	"login":   "/api/v1/auth/login/no2fa",
+	"verify":  "/api/v1/auth/mfa/verify",
+	"account": "/api/v1/a06/auth/verify",
+	"enroll":  "/api/v1/auth/mfa/enroll",
+})
@@ -0,0 +1,123 @@
+package endpoints
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Секрет из тестовых векторов RFC 4226 и RFC 6238 (SHA1)
+var rfcTOTPSecret = []byte("12345678901234567890")
+
+// RFC 4226, приложение D
+func TestHOTP(t *testing.T) {
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range want {
+		if got := hotp(rfcTOTPSecret, uint64(counter)); got != code {
+			t.Errorf("hotp(%d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+// RFC 6238, приложение B. Векторы даны для 8 цифр, код из 6 цифр - их последние 6
+func TestTOTPMatch(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		code := tt.code[len(tt.code)-totpDigits:]
+		step, ok := totpMatch(rfcTOTPSecret, code, time.Unix(tt.unix, 0))
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("T=%d: totpMatch(%s) = %d, %v, want %d", tt.unix, code, step, ok, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestTOTPMatchSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / totpPeriod
+	for offset := int64(-3); offset <= 3; offset++ {
+		code := hotp(rfcTOTPSecret, uint64(step+offset))
+		_, ok := totpMatch(rfcTOTPSecret, code, now)
+		if want := offset >= -totpSkew && offset <= totpSkew; ok != want {
+			t.Errorf("offset %d: ok %v, want %v", offset, ok, want)
+		}
+	}
+	for _, code := range []string{"", "05924", "0059240", "abcdef"} {
+		if _, ok := totpMatch(rfcTOTPSecret, code, now); ok {
+			t.Errorf("%q accepted", code)
+		}
+	}
+}
+
+func TestOTPAuthURI(t *testing.T) {
+	uri := otpauthURI("john.doe@company.com", rfcTOTPSecret)
+	for _, part := range []string{"otpauth://totp/VulnWeb:john.doe@company.com?", "secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("%s: no %s", uri, part)
+		}
+	}
+}
+
+func TestAcceptTOTPStep(t *testing.T) {
+	d := newDomain()
+	d.PutMFA("1", mfaEnrollment{Secret: rfcTOTPSecret, Enabled: true, LastStep: 10})
+	tests := []struct {
+		step          int64
+		allowReuse    bool
+		replay, valid bool
+	}{
+		{11, false, false, true},
+		{11, false, true, false},
+		{10, false, true, false},
+		{11, true, true, true},
+		{12, false, false, true},
+	}
+	for _, tt := range tests {
+		replay, ok := d.AcceptTOTPStep("1", tt.step, tt.allowReuse)
+		if replay != tt.replay || ok != tt.valid {
+			t.Errorf("step %d reuse %v: replay %v ok %v, want %v %v", tt.step, tt.allowReuse, replay, ok, tt.replay, tt.valid)
+		}
+	}
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	d := newDomain()
+	d.PutMFA("1", mfaEnrollment{Secret: rfcTOTPSecret, Enabled: true, Recovery: []string{"aaaa", "bbbb"}})
+	if !d.UseRecoveryCode("1", "bbbb") {
+		t.Fatal("code rejected")
+	}
+	if d.UseRecoveryCode("1", "bbbb") || d.UseRecoveryCode("1", "cccc") {
+		t.Error("used or unknown code accepted")
+	}
+	if e, _ := d.MFA("1"); len(e.Recovery) != 1 || e.Recovery[0] != "aaaa" {
+		t.Errorf("recovery codes %v", e.Recovery)
+	}
+}
+
+func TestBeginMFAAttempt(t *testing.T) {
+	d := newDomain()
+	d.PutMFAChallenge(mfaChallenge{ID: "c", UserID: "3", Created: time.Now()})
+	for i := 0; i < mfaMaxAttempts; i++ {
+		if failures, ok := d.BeginMFAAttempt("c", mfaMaxAttempts); !ok || failures != i {
+			t.Fatalf("attempt %d: failures %d, ok %v", i, failures, ok)
+		}
+	}
+	if _, ok := d.BeginMFAAttempt("c", mfaMaxAttempts); ok {
+		t.Error("attempt over the limit allowed")
+	}
+	if failures, ok := d.BeginMFAAttempt("c", 0); !ok || failures != mfaMaxAttempts {
+		t.Errorf("no limit: failures %d, ok %v", failures, ok)
+	}
+	if _, ok := d.BeginMFAAttempt("missing", 0); ok {
+		t.Error("unknown challenge allowed")
+	}
+}
@@ -0,0 +1,297 @@
+package endpoints
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Режим эндпоинтов: у каждого уязвимого обработчика есть исправленная версия (см. aXX_secure.go).
+// Режим переключается во время работы сервера - для всех эндпоинтов сразу или для отдельного
+// задания, чтобы учащийся мог повторить ту же атаку и увидеть, что исправление ее блокирует
+
+const (
+	modeVulnerable = "vulnerable"
+	modeSecure     = "secure"
+)
+
+// Токен преподавателя. Режим влияет на всех учащихся, поэтому меняется только с токеном;
+// если токен не задан в настройках, он генерируется при старте
+var trainerToken string
+
+// Взять токен преподавателя из настроек или сгенерировать и записать в журнал сервера
+func loadTrainerToken(configured string) error {
+	if configured != "" {
+		trainerToken = configured
+		return nil
+	}
+	token, err := randomHex(16)
+	if err != nil {
+		return fmt.Errorf("generate trainer token: %w", err)
+	}
+	trainerToken = token
+	log.Printf("trainer token for /mode (set trainer_token to choose your own): %s", token)
+	return nil
+}
+
+var (
+	errUnknownMode     = errors.New("mode must be vulnerable or secure")
+	errUnknownEndpoint = errors.New("unknown endpoint")
+	errTrainerToken    = errors.New("trainer token required")
+)
+
+type modeStore struct {
+	mu        sync.RWMutex
+	global    string
+	overrides map[string]string // Ключ задания -> режим, отличный от общего
+}
+
+var modes = newModeStore()
+
+func newModeStore() *modeStore {
+	return &modeStore{global: modeVulnerable, overrides: make(map[string]string)}
+}
+
+// Работает ли эндпоинт задания в исправленном режиме
+func (s *modeStore) Secure(key string) bool {
+	return s.Mode(key) == modeSecure
+}
+
+// Текущий режим эндпоинта задания
+func (s *modeStore) Mode(key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if mode, ok := s.overrides[key]; ok {
+		return mode
+	}
+	return s.global
+}
+
+// Задать общий режим; отдельные настройки эндпоинтов сбрасываются
+func (s *modeStore) SetGlobal(mode string) error {
+	if mode != modeVulnerable && mode != modeSecure {
+		return errUnknownMode
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.global = mode
+	s.overrides = make(map[string]string)
+	return nil
+}
+
+// Задать режим эндпоинта задания; пустой режим возвращает общий
+func (s *modeStore) SetEndpoint(key, mode string) error {
+	if _, ok := labRoutes[key]; !ok {
+		return errUnknownEndpoint
+	}
+	if mode != "" && mode != modeVulnerable && mode != modeSecure {
+		return errUnknownMode
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if mode == "" || mode == s.global {
+		delete(s.overrides, key)
+	} else {
+		s.overrides[key] = mode
+	}
+	return nil
+}
+
+// Состояние режимов для страницы и API
+type modeState struct {
+	Global    string            `json:"global"`
+	Overrides map[string]string `json:"overrides"`
+}
+
+func (s *modeStore) Snapshot() modeState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	overrides := make(map[string]string, len(s.overrides))
+	for key, mode := range s.overrides {
+		overrides[key] = mode
+	}
+	return modeState{Global: s.global, Overrides: overrides}
+}
+
+// Маршруты эндпоинтов заданий по ключу задания (заполняются при регистрации)
+var labRoutes = map[string]string{}
+
+// Зарегистрировать эндпоинт задания: уязвимая и исправленная версии
+func (e *endpoints) handleLab(route, key string, vulnerable, secure http.HandlerFunc) {
+	labRoutes[key] = route
+	e.r.HandleFunc(route, switchable(key, vulnerable, secure))
+}
+
+// Обработчик, выбирающий версию по текущему режиму эндпоинта
+func switchable(key string, vulnerable, secure http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mode := modes.Mode(key)
+		w.Header().Set("X-Lab-Mode", mode)
+		if mode == modeSecure {
+			secure(w, r)
+			return
+		}
+		vulnerable(w, r)
+	}
+}
+
+// У каждого задания каталога должен быть эндпоинт, и наоборот
+func checkLabRoutes(challenges map[string]Challenge) error {
+	var errs []error
+	for key := range challenges {
+		if _, ok := labRoutes[key]; !ok {
+			errs = append(errs, fmt.Errorf("challenge %s has no endpoint", key))
+		}
+	}
+	for key := range labRoutes {
+		if _, ok := challenges[key]; !ok {
+			errs = append(errs, fmt.Errorf("endpoint %s has no challenge", key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Применить изменение режима из формы или API
+func applyModeChange(r *http.Request) error {
+	sent := r.Header.Get("X-Trainer-Token")
+	if sent == "" {
+		sent = r.FormValue("token")
+	}
+	if trainerToken == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(trainerToken)) != 1 {
+		return errTrainerToken
+	}
+	if key := r.FormValue("key"); key != "" {
+		return modes.SetEndpoint(key, r.FormValue("mode"))
+	}
+	return modes.SetGlobal(r.FormValue("mode"))
+}
+
+// Страница переключения режимов
+func modePage(w http.ResponseWriter, r *http.Request) {
+	message := ""
+	if r.Method == "POST" {
+		err := applyModeChange(r)
+		if err == nil {
+			http.Redirect(w, r, "/mode", http.StatusSeeOther)
+			return
+		}
+		switch {
+		case errors.Is(err, errTrainerToken):
+			message = "Неверный токен преподавателя"
+		case errors.Is(err, errUnknownEndpoint):
+			message = "Неизвестный эндпоинт"
+		default:
+			message = "Неизвестный режим"
+		}
+		message = `<div class="response error">❌ ` + message + `</div>`
+	}
+
+	state := modes.Snapshot()
+	tokenField := `<div class="form-group"><label>Токен преподавателя</label><input type="password" name="token"></div>`
+
+	keys := make([]string, 0, len(labRoutes))
+	for key := range labRoutes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return challengeKeyLess(keys[i], keys[j]) })
+
+	rows := ""
+	for _, key := range keys {
+		mode := modes.Mode(key)
+		next := modeSecure
+		if mode == modeSecure {
+			next = modeVulnerable
+		}
+		category, vulnID, _ := strings.Cut(key, "_")
+		rows += fmt.Sprintf(`<tr><td><a href="/challenge/%s/%s">%s</a></td><td>%s</td><td><code>%s</code></td><td>%s</td><td>
+			<form method="POST" action="/mode" style="display: inline;">
+				<input type="hidden" name="key" value="%s"><input type="hidden" name="mode" value="%s">%s
+				<button type="submit" class="btn">%s</button>
+			</form></td></tr>`,
+			category, vulnID, key, html.EscapeString(catalog[key].Title), labRoutes[key], modeText[mode],
+			key, next, tokenInput(), modeButton[next])
+	}
+
+	page := renderPage("Режим эндпоинтов", fmt.Sprintf(`
+		<div class="card">
+			<h2>Общий режим: %s</h2>
+			<p>В исправленном режиме эндпоинты работают без уязвимостей: повторите атаку и убедитесь, что она больше не проходит. Флаги в этом режиме не выдаются.</p>
+			<form method="POST" action="/mode">
+				%s
+				<button type="submit" name="mode" value="vulnerable" class="btn btn-danger">Все уязвимые</button>
+				<button type="submit" name="mode" value="secure" class="btn">Все исправленные</button>
+			</form>
+			%s
+			<p>JSON: <a href="/api/mode" class="api-endpoint">/api/mode</a></p>
+		</div>
+
+		<div class="card">
+			<h2>Эндпоинты</h2>
+			<table style="width: 100%%; border-collapse: collapse;">
+				<tr style="text-align: left;"><th>Задание</th><th>Название</th><th>Маршрут</th><th>Режим</th><th></th></tr>
+				%s
+			</table>
+		</div>
+	`, modeText[state.Global], tokenField, message, rows))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
+
+// Поле токена в форме эндпоинта
+func tokenInput() string {
+	return `<input type="password" name="token" placeholder="Токен" style="width: 120px;">`
+}
+
+var modeText = map[string]string{
+	modeVulnerable: "уязвимый",
+	modeSecure:     "исправленный",
+}
+
+var modeBadge = map[string]string{
+	modeVulnerable: "badge-danger",
+	modeSecure:     "badge-info",
+}
+
+var modeButton = map[string]string{
+	modeVulnerable: "Сделать уязвимым",
+	modeSecure:     "Исправить",
+}
+
+// Режимы в JSON; POST с параметрами mode и key (необязательно) меняет режим
+func apiMode(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if r.Method == "POST" {
+		if err := applyModeChange(r); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, errTrainerToken) {
+				status = http.StatusForbidden
+			}
+			w.WriteHeader(status)
+			enc.Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	enc.Encode(modes.Snapshot())
+}
+
+// Порядок ключей заданий: a01_2 раньше a01_10
+func challengeKeyLess(a, b string) bool {
+	ac, an, _ := strings.Cut(a, "_")
+	bc, bn, _ := strings.Cut(b, "_")
+	if ac != bc {
+		return ac < bc
+	}
+	if len(an) != len(bn) {
+		return len(an) < len(bn)
+	}
+	return an < bn
+}
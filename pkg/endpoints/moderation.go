@@ -0,0 +1,406 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Бот-модератор для задания Stored XSS (a05_3). Раз в moderationInterval он "входит"
+// администратором и открывает очередь модерации каждого учащегося, у которого есть новые
+// комментарии. Страница разбирается токенизатором HTML (htmltoken.go): код выполняется
+// только там, где его выполнил бы браузер - в <script>, обработчике on* и ссылке javascript:.
+// Выполнение упрощенное: бот показывает alert и отправляет document.cookie по адресу
+// из строкового литерала. Запросы на /api/lab/collector/ попадают в журнал сборщика
+// учащегося, остальные идут в сеть стенда (oob.lab) с рабочей станции администратора
+
+const (
+	moderationInterval = 10 * time.Second
+	moderatorIP        = "10.0.5.23" // Рабочая станция администратора
+	moderationRoute    = "/api/v1/admin/comments"
+	collectorPath      = "/api/lab/collector"
+	collectorMaxLog    = 50
+	collectorMaxBody   = 4 << 10
+	reviewMaxLog       = 20
+)
+
+// Запрос, пришедший на сборщик учащегося
+type collectedRequest struct {
+	Time   time.Time
+	Method string
+	URL    string
+	Body   string
+	From   string
+}
+
+// Отчет бота об одной проверке очереди
+type botReview struct {
+	Time     time.Time
+	Comments []int
+	Executed []string // Где выполнился код
+	Blocked  string   // Почему код не выполнялся
+	Actions  []string // Что сделал код
+}
+
+// Запустить бота. Страница модерации открывается через тот же обработчик, что и у учащихся
+func startModerationBot(h http.Handler) {
+	go func() {
+		ticker := time.NewTicker(moderationInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			domains.Each(func(learner string, d *domain) {
+				reviewComments(h, learner, d)
+			})
+		}
+	}()
+}
+
+// Одна проверка очереди: открыть страницу, выполнить найденный код, одобрить комментарии
+func reviewComments(h http.Handler, learner string, d *domain) {
+	pending := d.CommentsByStatus(commentPending)
+	if len(pending) == 0 {
+		return
+	}
+	review := botReview{Time: time.Now().UTC()}
+	for _, c := range pending {
+		review.Comments = append(review.Comments, c.ID)
+	}
+
+	session, err := d.moderatorSession()
+	if err != nil {
+		return
+	}
+	cookie := moderatorCookie(session, modes.Mode("a05_3") == modeSecure)
+	req := httptest.NewRequest(http.MethodGet, moderationRoute, nil)
+	req.RemoteAddr = moderatorIP + ":52100"
+	req.AddCookie(&http.Cookie{Name: learnerCookieName, Value: learner})
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		review.Blocked = fmt.Sprintf("moderation page returned %d", rec.Code)
+	} else {
+		browser := &moderatorBrowser{
+			net:    &labNet{learner: learner, source: moderatorIP, lookups: make(map[string]int)},
+			domain: d,
+		}
+		// document.cookie видит только cookie без HttpOnly
+		if !cookie.HttpOnly {
+			browser.cookie = cookie.Name + "=" + cookie.Value
+		}
+		scripts := executableScripts(tokenizeHTML(rec.Body.String()))
+		csp := rec.Header().Get("Content-Security-Policy")
+		for _, s := range scripts {
+			review.Executed = append(review.Executed, s.Context)
+		}
+		if len(scripts) > 0 && cspBlocksScripts(csp) {
+			review.Executed = nil
+			review.Blocked = "Content-Security-Policy: " + csp
+		} else {
+			for _, s := range scripts {
+				review.Actions = append(review.Actions, browser.Run(s)...)
+			}
+		}
+	}
+
+	for _, c := range pending {
+		d.ModerateComment(c.ID, commentApproved)
+	}
+	d.LogReview(review)
+}
+
+// Сессия бота: создается при первом входе и живет sessionTTL, как обычная сессия
+func (d *domain) moderatorSession() (labSession, error) {
+	d.mu.Lock()
+	id := d.moderator
+	d.mu.Unlock()
+	if s, ok := d.Session(id); ok && time.Now().Before(s.Expires) {
+		return s, nil
+	}
+
+	id, err := randomHex(32)
+	if err != nil {
+		return labSession{}, err
+	}
+	now := time.Now().UTC()
+	s := labSession{ID: id, UserID: "3", Role: "admin", IP: moderatorIP, Created: now, Expires: now.Add(sessionTTL)}
+	d.PutSession(s)
+	d.mu.Lock()
+	d.moderator = id
+	d.mu.Unlock()
+	return s, nil
+}
+
+// Cookie, которую выдает вход администратора. В уязвимом режиме - без HttpOnly
+func moderatorCookie(s labSession, secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    s.ID,
+		Path:     "/",
+		Expires:  s.Expires,
+		HttpOnly: secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Код, который браузер выполнит при открытии страницы
+type botScript struct {
+	Context string
+	Code    string
+	Src     string // Адрес внешнего скрипта
+}
+
+// Найти исполняемые контексты. Бот ведет мышью по элементам и щелкает по ссылкам,
+// поэтому срабатывает любой обработчик on*, а не только onload и onerror
+func executableScripts(tokens []htmlToken) []botScript {
+	var scripts []botScript
+	for i, tok := range tokens {
+		if tok.Kind != htmlStartTag {
+			continue
+		}
+		if tok.Tag == "script" {
+			if src, ok := tok.Attr("src"); ok {
+				scripts = append(scripts, botScript{Context: fmt.Sprintf("<script src=%q>", src), Src: src})
+			} else if i+1 < len(tokens) && tokens[i+1].Kind == htmlText {
+				scripts = append(scripts, botScript{Context: "<script> element", Code: tokens[i+1].Text})
+			}
+		}
+		for _, a := range tok.Attrs {
+			if len(a.Name) > 2 && strings.HasPrefix(a.Name, "on") {
+				scripts = append(scripts, botScript{Context: fmt.Sprintf("%s handler of <%s>", a.Name, tok.Tag), Code: a.Value})
+				continue
+			}
+			if !javascriptURLAttr(tok.Tag, a.Name) {
+				continue
+			}
+			if code, ok := javascriptURL(a.Value); ok {
+				scripts = append(scripts, botScript{Context: fmt.Sprintf("javascript: URL in <%s %s>", tok.Tag, a.Name), Code: code})
+			}
+		}
+	}
+	return scripts
+}
+
+// Атрибуты, адрес javascript: в которых выполняется при переходе или загрузке
+func javascriptURLAttr(tag, attr string) bool {
+	switch attr {
+	case "href":
+		return tag == "a" || tag == "area"
+	case "src":
+		return tag == "iframe" || tag == "frame" || tag == "embed"
+	case "formaction":
+		return tag == "button" || tag == "input"
+	}
+	return false
+}
+
+// Код из адреса javascript:. Браузер отбрасывает пробелы и управляющие символы по краям
+// и табуляции и переводы строк внутри, поэтому "java&#9;script:" - тоже javascript:
+func javascriptURL(v string) (string, bool) {
+	v = strings.Trim(v, "\x00\x01\x02\x03\x04\x05\x06\x07\x08\t\n\x0b\x0c\r\x0e\x0f\x10\x11\x12\x13\x14\x15\x16\x17\x18\x19\x1a\x1b\x1c\x1d\x1e\x1f ")
+	v = strings.NewReplacer("\t", "", "\n", "", "\r", "").Replace(v)
+	if len(v) < len("javascript:") || !strings.EqualFold(v[:len("javascript:")], "javascript:") {
+		return "", false
+	}
+	code := v[len("javascript:"):]
+	if unescaped, err := url.PathUnescape(code); err == nil {
+		code = unescaped
+	}
+	return code, true
+}
+
+// CSP запрещает встроенные скрипты, если script-src (или default-src) задан без 'unsafe-inline'.
+// Внешние скрипты бот тоже считает запрещенными: источники стенда в политику не входят
+func cspBlocksScripts(csp string) bool {
+	directives := make(map[string]string)
+	for _, d := range strings.Split(csp, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(d), " ")
+		if name != "" {
+			directives[strings.ToLower(name)] = value
+		}
+	}
+	policy, ok := directives["script-src"]
+	if !ok {
+		policy, ok = directives["default-src"]
+	}
+	return ok && !strings.Contains(policy, "'unsafe-inline'")
+}
+
+// Браузер бота: cookie, видимая скриптам, и сеть для исходящих запросов
+type moderatorBrowser struct {
+	net    *labNet
+	domain *domain
+	cookie string // document.cookie
+}
+
+var (
+	dialogCall   = regexp.MustCompile(`\b(alert|prompt|confirm)\s*[(\x60]`)
+	urlLiteral   = regexp.MustCompile(`['"\x60]((?:https?:)?//[^'"\x60\s]*|/api/lab/collector/[^'"\x60\s]*)['"\x60]`)
+	cookieConcat = regexp.MustCompile(`^\s*\+\s*(?:(encodeURIComponent|escape|btoa)\s*\(\s*)?document\.cookie`)
+	cookieInline = regexp.MustCompile(`\$\{\s*(?:(encodeURIComponent|escape|btoa)\s*\(\s*)?document\.cookie\s*\)?\s*\}`)
+	postRequest  = regexp.MustCompile(`(?i)method\s*:\s*['"]post['"]|sendBeacon|\.send\s*\(\s*document\.cookie`)
+)
+
+// Выполнить скрипт; результат - список действий для отчета
+func (b *moderatorBrowser) Run(s botScript) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), labRequestTimeout)
+	defer cancel()
+
+	code := s.Code
+	if s.Src != "" {
+		body, err := labGet(ctx, b.net.Client(), s.Src)
+		if err != nil {
+			return []string{fmt.Sprintf("failed to load %s: %v", s.Src, err)}
+		}
+		code = body
+	}
+
+	var actions []string
+	if m := dialogCall.FindStringSubmatch(code); m != nil {
+		actions = append(actions, m[1]+"() dialog shown to the moderator")
+	}
+	loc := urlLiteral.FindStringSubmatchIndex(code)
+	if loc == nil {
+		if strings.Contains(code, "document.cookie") {
+			actions = append(actions, "document.cookie read, but not sent anywhere")
+		}
+		return actions
+	}
+
+	// Адрес из литерала; cookie дописывается конкатенацией или подстановкой ${...},
+	// иначе уходит в теле POST-запроса
+	target := code[loc[2]:loc[3]]
+	method, body := http.MethodGet, ""
+	if m := cookieConcat.FindStringSubmatch(code[loc[1]:]); m != nil {
+		target += encodeCookie(m[1], b.cookie)
+	} else if cookieInline.MatchString(target) {
+		target = cookieInline.ReplaceAllStringFunc(target, func(expr string) string {
+			return encodeCookie(cookieInline.FindStringSubmatch(expr)[1], b.cookie)
+		})
+	} else if strings.Contains(code, "document.cookie") && postRequest.MatchString(code) {
+		method, body = http.MethodPost, b.cookie
+	}
+	if strings.HasPrefix(target, "//") {
+		target = "http:" + target
+	}
+	return append(actions, b.send(ctx, method, target, body))
+}
+
+func encodeCookie(fn, cookie string) string {
+	switch fn {
+	case "encodeURIComponent", "escape":
+		return url.QueryEscape(cookie)
+	case "btoa":
+		return base64.StdEncoding.EncodeToString([]byte(cookie))
+	}
+	return cookie
+}
+
+// Отправить запрос из браузера бота
+func (b *moderatorBrowser) send(ctx context.Context, method, target, body string) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Sprintf("%s %s: %v", method, target, err)
+	}
+	if strings.HasPrefix(u.Path, collectorPath+"/") {
+		b.domain.Collect(collectedRequest{Time: time.Now().UTC(), Method: method, URL: u.RequestURI(), Body: body, From: moderatorIP})
+		return fmt.Sprintf("%s %s -> collector", method, u.RequestURI())
+	}
+	if u.Host == "" {
+		return fmt.Sprintf("%s %s: request to the application itself ignored", method, target)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), strings.NewReader(body))
+	if err != nil {
+		return fmt.Sprintf("%s %s: %v", method, target, err)
+	}
+	resp, err := b.net.Client().Do(req)
+	if err != nil {
+		return fmt.Sprintf("%s %s: %v", method, target, err)
+	}
+	resp.Body.Close()
+	return fmt.Sprintf("%s %s -> %d", method, u.String(), resp.StatusCode)
+}
+
+// Сохранить запрос на сборщике; старые записи вытесняются
+func (d *domain) Collect(req collectedRequest) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.collected = append(d.collected, req)
+	if len(d.collected) > collectorMaxLog {
+		d.collected = d.collected[len(d.collected)-collectorMaxLog:]
+	}
+}
+
+func (d *domain) Collected() []collectedRequest {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]collectedRequest(nil), d.collected...)
+}
+
+func (d *domain) LogReview(review botReview) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reviews = append(d.reviews, review)
+	if len(d.reviews) > reviewMaxLog {
+		d.reviews = d.reviews[len(d.reviews)-reviewMaxLog:]
+	}
+}
+
+func (d *domain) Reviews() []botReview {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]botReview(nil), d.reviews...)
+}
+
+// Сборщик учащегося: журнал запросов и отчеты бота-модератора
+func apiLabCollector(w http.ResponseWriter, r *http.Request) {
+	d := learnerDomain(w, r)
+	requests := make([]map[string]interface{}, 0)
+	for _, req := range d.Collected() {
+		requests = append(requests, map[string]interface{}{
+			"time":   req.Time.Format(time.RFC3339),
+			"method": req.Method,
+			"url":    req.URL,
+			"body":   req.Body,
+			"from":   req.From,
+		})
+	}
+	reviews := make([]map[string]interface{}, 0)
+	for _, rv := range d.Reviews() {
+		reviews = append(reviews, map[string]interface{}{
+			"time":     rv.Time.Format(time.RFC3339),
+			"comments": rv.Comments,
+			"executed": rv.Executed,
+			"blocked":  rv.Blocked,
+			"actions":  rv.Actions,
+		})
+	}
+	sendJSON(w, map[string]interface{}{
+		"collect_url": collectorPath + "/",
+		"requests":    requests,
+		"reviews":     reviews,
+	})
+}
+
+// Прием запросов на сборщик: любой метод, любой путь после /api/lab/collector/
+func apiLabCollect(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(io.LimitReader(r.Body, collectorMaxBody))
+	learnerDomain(w, r).Collect(collectedRequest{
+		Time:   time.Now().UTC(),
+		Method: r.Method,
+		URL:    r.URL.RequestURI(),
+		Body:   string(body),
+		From:   clientIP(r),
+	})
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,256 @@
+package endpoints
+
+import (
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Сброс пароля по ссылке из письма: токен, письмо в почту стенда (mail.go) и установка
+// нового пароля по токену. Исправленные обработчики работают через secureReset, уязвимые -
+// через labReset с типичными ошибками; каждую можно выключить для учащегося через
+// /api/lab/password-reset
+
+const (
+	resetTokenTTL    = 15 * time.Minute
+	firstResetNumber = 48213
+)
+
+// Ошибки сброса пароля в уязвимом режиме
+const (
+	resetFlawHost         = "host_header"       // Ссылка строится из Host и X-Forwarded-Host
+	resetFlawPredictable  = "predictable_token" // Токен - порядковый номер
+	resetFlawNoInvalidate = "no_invalidation"   // Токен действует после использования и смены пароля
+	resetFlawEnumeration  = "enumeration"       // Ответ зависит от того, есть ли учетная запись
+)
+
+var resetFlaws = flawSet{name: "password_reset", flaws: []string{resetFlawHost, resetFlawPredictable, resetFlawNoInvalidate, resetFlawEnumeration}}
+
+// Токен сброса. Used - по нему уже сменили пароль, Stale - пароль сменился после выдачи
+type resetToken struct {
+	Token   string
+	UserID  string
+	Created time.Time
+	Used    bool
+	Stale   bool
+}
+
+func (d *domain) PutResetToken(t resetToken) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for token, old := range d.resetTokens {
+		if time.Since(old.Created) > resetTokenTTL {
+			delete(d.resetTokens, token)
+		}
+	}
+	d.resetTokens[t.Token] = t
+}
+
+// Токен, срок которого не истек
+func (d *domain) ResetToken(token string) (resetToken, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t, ok := d.resetTokens[token]
+	if !ok || time.Since(t.Created) > resetTokenTTL {
+		return resetToken{}, false
+	}
+	return t, true
+}
+
+// Погасить токен. keep - токен остается и помечается использованным, иначе удаляются
+// все токены пользователя
+func (d *domain) UseResetToken(token string, keep bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t, ok := d.resetTokens[token]
+	if !ok {
+		return
+	}
+	if keep {
+		t.Used = true
+		d.resetTokens[token] = t
+		return
+	}
+	for id, other := range d.resetTokens {
+		if other.UserID == t.UserID {
+			delete(d.resetTokens, id)
+		}
+	}
+}
+
+func (d *domain) NextResetNumber() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextReset++
+	return d.nextReset
+}
+
+// Токен попал на сервер учащегося: получатель открыл ссылку на oob.lab
+func resetTokenLeaked(d *domain, token string) bool {
+	for _, req := range d.OOBRequests() {
+		if strings.Contains(req.URL, "token="+token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Правила сброса пароля
+type resetPolicy struct {
+	weak bool // Действуют включенные у учащегося ошибки
+}
+
+var (
+	secureReset = resetPolicy{}
+	labReset    = resetPolicy{weak: true}
+)
+
+func (p resetPolicy) flaw(d *domain, name string) bool {
+	return p.weak && resetFlaws.On(d, name)
+}
+
+func (p resetPolicy) newToken(d *domain) (string, error) {
+	if p.flaw(d, resetFlawPredictable) {
+		return strconv.Itoa(d.NextResetNumber()), nil
+	}
+	return randomHex(32)
+}
+
+// Ссылка из письма. Адрес сайта не берется из запроса: письмо читается на этом же сайте,
+// поэтому ссылка относительная
+func (p resetPolicy) link(r *http.Request, d *domain, token string) string {
+	link := r.URL.Path + "?token=" + token
+	if p.flaw(d, resetFlawHost) {
+		// УЯЗВИМОСТЬ: адрес сайта из заголовков, которые задает клиент
+		host := r.Host
+		if forwarded := strings.TrimSpace(strings.Split(r.Header.Get("X-Forwarded-Host"), ",")[0]); forwarded != "" {
+			host = forwarded
+		}
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		link = scheme + "://" + host + link
+	}
+	return link
+}
+
+// Запрос сброса: POST email. Письмо со ссылкой уходит на адрес из учетной записи
+func (p resetPolicy) Request(w http.ResponseWriter, r *http.Request) {
+	learner := learnerID(w, r)
+	d := domains.Get(learner)
+	email := strings.TrimSpace(r.FormValue("email"))
+	user, ok := d.findUser(func(a *account) bool { return strings.EqualFold(a.Email, email) })
+	if !ok {
+		if p.flaw(d, resetFlawEnumeration) {
+			// УЯЗВИМОСТЬ: по ответу видно, что такой учетной записи нет
+			sendJSONStatus(w, http.StatusNotFound, map[string]interface{}{
+				"status":  "error",
+				"message": "No account registered with this email",
+			})
+			return
+		}
+		sendJSON(w, map[string]interface{}{
+			"status":  "success",
+			"message": "If an account with this email exists, a reset link has been sent to it",
+		})
+		return
+	}
+
+	token, err := p.newToken(d)
+	if err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{"status": "error", "message": "Internal server error"})
+		return
+	}
+	d.PutResetToken(resetToken{Token: token, UserID: user.ID, Created: time.Now()})
+	d.SendMail(learner, mailMessage{
+		To:      user.Email,
+		Subject: "Password reset",
+		Text:    "Hello " + user.Username + ",\nsomeone requested a password reset for your account. The link is valid for 15 minutes:",
+		Link:    p.link(r, d, token),
+	})
+	message := "If an account with this email exists, a reset link has been sent to it"
+	if p.flaw(d, resetFlawEnumeration) {
+		message = "Reset link sent to " + user.Email
+	}
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": message,
+	})
+}
+
+// Новый пароль по токену: POST token и password. Если токен отклонен, ответ уже отправлен
+// и ok=false. reused - токен уже использован или выдан до смены пароля
+func (p resetPolicy) Confirm(w http.ResponseWriter, r *http.Request) (t resetToken, reused, ok bool) {
+	d := learnerDomain(w, r)
+	t, ok = d.ResetToken(r.FormValue("token"))
+	reused = t.Used || t.Stale
+	// УЯЗВИМОСТЬ (no_invalidation): использованный и устаревший токены принимаются
+	keep := p.flaw(d, resetFlawNoInvalidate)
+	if !ok || reused && !keep {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid or expired reset token",
+		})
+		return resetToken{}, false, false
+	}
+	password := r.FormValue("password")
+	if password == "" {
+		sendJSONStatus(w, http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "New password is required",
+		})
+		return resetToken{}, false, false
+	}
+	d.SetPassword(t.UserID, password)
+	d.UseResetToken(t.Token, keep)
+	return t, reused, true
+}
+
+// Ответ об успешной смене пароля
+func passwordResetDone(d *domain, t resetToken) map[string]interface{} {
+	user, _ := d.User(t.UserID)
+	return map[string]interface{}{
+		"status":  "success",
+		"message": "Password updated for " + user.Email,
+	}
+}
+
+// Страница: без token - форма запроса, с token - форма нового пароля
+func passwordResetPage(w http.ResponseWriter, r *http.Request, email string) {
+	form := `
+				<div class="form-group">
+					<label>Email</label>
+					<input type="email" name="email" value="` + email + `">
+				</div>
+				<button type="submit" class="btn">Reset</button>`
+	if token := r.URL.Query().Get("token"); token != "" {
+		form = `
+				<input type="hidden" name="token" value="` + html.EscapeString(token) + `">
+				<div class="form-group">
+					<label>New password</label>
+					<input type="password" name="password">
+				</div>
+				<button type="submit" class="btn">Set password</button>`
+	}
+	page := renderPage("Reset Password", `
+		<div class="card">
+			<h2>Reset Password</h2>
+			<form method="POST">`+form+`
+			</form>
+			<p>Reset emails for `+labUser.Email+` arrive at <a href="`+mailPath+`/`+labUser.Email+`">`+mailPath+`/`+labUser.Email+`</a></p>
+		</div>
+	`)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
+
+// Ошибки сброса пароля учащегося: GET - список, POST flaw=<имя>&enabled=true|false
+var apiLabPasswordReset = resetFlaws.Handler(map[string]interface{}{
+	"reset_a06": "/api/v1/a06/password/reset",
+	"reset_a07": "/api/v1/a07/password/reset",
+	"mailbox":   mailPath + "/" + labUser.Email,
+	"oob":       "/api/lab/oob",
+})
@@ -0,0 +1,275 @@
+package endpoints
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Прогресс учащихся: каждый посетитель получает cookie learner_id,
+// выполненные задания хранятся отдельно для каждого учащегося и сохраняются на диск
+
+const learnerCookieName = "learner_id"
+
+var errNameTaken = errors.New("name is already taken")
+
+// Прогресс одного учащегося
+type learnerProgress struct {
+	Name   string               `json:"name,omitempty"` // Имя в таблице результатов
+	Solved map[string]time.Time `json:"solved"`
+	// Время открытия подсказок по заданиям, в порядке подсказок
+	Hints map[string][]time.Time `json:"hints,omitempty"`
+}
+
+// Потокобезопасное хранилище прогресса всех учащихся
+type progressStore struct {
+	mu       sync.RWMutex
+	path     string
+	learners map[string]*learnerProgress
+}
+
+var progress = newProgressStore()
+
+func newProgressStore() *progressStore {
+	return &progressStore{learners: make(map[string]*learnerProgress)}
+}
+
+// Загрузить прогресс из файла; отсутствующий файл не считается ошибкой
+func (s *progressStore) Load(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read progress: %w", err)
+	}
+
+	learners := make(map[string]*learnerProgress)
+	if err := json.Unmarshal(data, &learners); err != nil {
+		return fmt.Errorf("parse progress %s: %w", path, err)
+	}
+	for _, p := range learners {
+		if p.Solved == nil {
+			p.Solved = make(map[string]time.Time)
+		}
+	}
+	s.learners = learners
+	return nil
+}
+
+// Отметить задание выполненным; возвращает true, если оно решено впервые
+func (s *progressStore) MarkSolved(learnerID, challengeKey string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.learners[learnerID]
+	if !ok {
+		p = &learnerProgress{Solved: make(map[string]time.Time)}
+		s.learners[learnerID] = p
+	}
+	if _, solved := p.Solved[challengeKey]; solved {
+		return false, nil
+	}
+	p.Solved[challengeKey] = time.Now().UTC()
+	return true, s.save()
+}
+
+// Проверить, выполнил ли учащийся задание
+func (s *progressStore) IsSolved(learnerID, challengeKey string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.learners[learnerID]
+	if !ok {
+		return false
+	}
+	_, solved := p.Solved[challengeKey]
+	return solved
+}
+
+// Есть ли у учащегося сохраненный прогресс
+func (s *progressStore) Has(learnerID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.learners[learnerID]
+	return ok
+}
+
+// Открыть первые n подсказок задания. Уже открытые подсказки не меняются,
+// поэтому повторная отправка формы не открывает лишнюю подсказку.
+// Учащийся без сохраненного прогресса создается, поэтому вызывающий код
+// должен сначала убедиться, что учащийся уже работал со стендом
+func (s *progressStore) UnlockHints(learnerID, challengeKey string, n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.learners[learnerID]
+	if !ok {
+		p = &learnerProgress{Solved: make(map[string]time.Time)}
+		s.learners[learnerID] = p
+	}
+	if len(p.Hints[challengeKey]) >= n {
+		return nil
+	}
+	if p.Hints == nil {
+		p.Hints = make(map[string][]time.Time)
+	}
+	now := time.Now().UTC()
+	for len(p.Hints[challengeKey]) < n {
+		p.Hints[challengeKey] = append(p.Hints[challengeKey], now)
+	}
+	return s.save()
+}
+
+// Задать имя учащегося для таблицы результатов; имена не должны повторяться
+func (s *progressStore) SetName(learnerID, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, p := range s.learners {
+		if id != learnerID && strings.EqualFold(p.Name, name) {
+			return errNameTaken
+		}
+	}
+	p, ok := s.learners[learnerID]
+	if !ok {
+		p = &learnerProgress{Solved: make(map[string]time.Time)}
+		s.learners[learnerID] = p
+	}
+	p.Name = name
+	return s.save()
+}
+
+// Имя учащегося (пустое, если не задано)
+func (s *progressStore) Name(learnerID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if p, ok := s.learners[learnerID]; ok {
+		return p.Name
+	}
+	return ""
+}
+
+// Открытые учащимся подсказки задания
+func (s *progressStore) Hints(learnerID, challengeKey string) []time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if p, ok := s.learners[learnerID]; ok {
+		return append([]time.Time(nil), p.Hints[challengeKey]...)
+	}
+	return nil
+}
+
+// Копия прогресса всех учащихся (для подсчета очков)
+func (s *progressStore) Snapshot() map[string]learnerProgress {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := make(map[string]learnerProgress, len(s.learners))
+	for id, p := range s.learners {
+		snapshot[id] = p.clone()
+	}
+	return snapshot
+}
+
+// Копия прогресса только указанных учащихся (например, участников команды)
+func (s *progressStore) SnapshotOf(ids []string) map[string]learnerProgress {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := make(map[string]learnerProgress, len(ids))
+	for _, id := range ids {
+		if p, ok := s.learners[id]; ok {
+			snapshot[id] = p.clone()
+		}
+	}
+	return snapshot
+}
+
+func (p *learnerProgress) clone() learnerProgress {
+	solved := make(map[string]time.Time, len(p.Solved))
+	for key, t := range p.Solved {
+		solved[key] = t
+	}
+	hints := make(map[string][]time.Time, len(p.Hints))
+	for key, times := range p.Hints {
+		hints[key] = append([]time.Time(nil), times...)
+	}
+	return learnerProgress{Name: p.Name, Solved: solved, Hints: hints}
+}
+
+// Сохранить прогресс на диск (вызывается под блокировкой)
+func (s *progressStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := writeJSONFile(s.path, s.learners); err != nil {
+		return fmt.Errorf("save progress: %w", err)
+	}
+	return nil
+}
+
+// Записать JSON в файл. Пишем во временный файл и переименовываем,
+// чтобы не оставить обрезанный JSON при падении
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create data dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replace: %w", err)
+	}
+	return nil
+}
+
+// Получить ID учащегося из cookie или выдать новый
+func learnerID(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(learnerCookieName); err == nil && validLearnerID(c.Value) {
+		return c.Value
+	}
+
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	id := hex.EncodeToString(buf)
+	http.SetCookie(w, &http.Cookie{
+		Name:     learnerCookieName,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int((90 * 24 * time.Hour).Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	// Запоминаем ID в запросе, чтобы повторный вызов в этом же запросе не выдал новый
+	r.AddCookie(&http.Cookie{Name: learnerCookieName, Value: id})
+	return id
+}
+
+func validLearnerID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
@@ -0,0 +1,311 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxNameLength = 32
+
+var errInvalidName = errors.New("name must be 1 to 32 printable characters")
+
+// Подписи состояний соревнования
+var eventStatusText = map[string]string{
+	"practice": "Режим тренировки: очки считаются за все решения",
+	"upcoming": "Соревнование еще не началось",
+	"running":  "Соревнование идет",
+	"frozen":   "Таблица заморожена: решения принимаются, но результаты появятся после окончания",
+	"finished": "Соревнование завершено",
+}
+
+// Публичная таблица результатов; POST задает имя учащегося
+func scoreboardPage(w http.ResponseWriter, r *http.Request) {
+	learner := learnerID(w, r)
+	now := time.Now()
+
+	message := ""
+	if r.Method == "POST" {
+		name, err := validName(r.FormValue("name"))
+		if err == nil {
+			err = progress.SetName(learner, name)
+		}
+		switch {
+		case errors.Is(err, errNameTaken):
+			message = `<div class="response error">❌ Это имя уже занято</div>`
+		case errors.Is(err, errInvalidName):
+			message = `<div class="response error">❌ Имя должно содержать от 1 до 32 печатных символов</div>`
+		case err != nil:
+			log.Printf("save progress: %v", err)
+			message = `<div class="response error">❌ Не удалось сохранить имя</div>`
+		default:
+			http.Redirect(w, r, "/scoreboard", http.StatusSeeOther)
+			return
+		}
+	}
+
+	board := currentScoreboard(r, now)
+
+	// Подсвечиваем строку учащегося или его команды
+	own := learner
+	if t, ok := teams.TeamOf(learner); ok && board.View == "teams" {
+		own = t.ID
+	}
+
+	rows := ""
+	for _, entry := range board.Entries {
+		style := ""
+		if entry.ID == own {
+			style = ` style="background: #fff3cd;"`
+		}
+		name := html.EscapeString(entry.Name)
+		if len(entry.Members) > 0 {
+			var members []string
+			for _, m := range entry.Members {
+				members = append(members, fmt.Sprintf("%s: %d", html.EscapeString(m.Name), m.Score))
+			}
+			name += `<br><small>` + strings.Join(members, ", ") + `</small>`
+		}
+		rows += fmt.Sprintf(`<tr%s><td>%d</td><td>%s</td><td>%d</td><td>%d</td><td>%d</td><td>%d</td><td>%s</td></tr>`,
+			style, entry.Rank, name, entry.Score, entry.Solved, entry.FirstBloods, entry.HintsUsed,
+			entry.LastSolve.Local().Format("02.01 15:04:05"))
+	}
+	if rows == "" {
+		rows = `<tr><td colspan="7">Пока никто не решил ни одного задания</td></tr>`
+	}
+
+	schedule := ""
+	if board.Start != nil {
+		schedule = fmt.Sprintf("<p><strong>Начало:</strong> %s<br><strong>Окончание:</strong> %s</p>",
+			board.Start.Local().Format("02.01.2006 15:04"), board.End.Local().Format("02.01.2006 15:04"))
+	}
+
+	points := ""
+	for _, d := range difficulties {
+		points += fmt.Sprintf("%s - %d, ", d, event.Points[d])
+	}
+	points += fmt.Sprintf("первое решение задания - +%d, подсказка, открытая до решения - −%d", *event.FirstBloodBonus, *event.HintCost)
+
+	page := renderPage("Таблица результатов: "+html.EscapeString(board.Event), fmt.Sprintf(`
+		<div class="card">
+			<h2>%s</h2>
+			%s
+			<p><strong>Очки:</strong> %s</p>
+		</div>
+
+		<div class="card">
+			<h2>Ваше имя</h2>
+			<form method="POST" action="/scoreboard">
+				<div class="form-group">
+					<label>Имя в таблице</label>
+					<input type="text" name="name" value="%s" maxlength="%d" required>
+				</div>
+				<button type="submit" class="btn">Сохранить</button>
+			</form>
+			%s
+		</div>
+
+		<div class="card">
+			<h2>Результаты</h2>
+			<p><a href="/scoreboard">Участники</a> | <a href="/scoreboard?view=teams">Команды</a></p>
+			<table style="width: 100%%; border-collapse: collapse;">
+				<tr style="text-align: left;"><th>#</th><th>Имя</th><th>Очки</th><th>Решено</th><th>First blood</th><th>Подсказки</th><th>Последнее решение</th></tr>
+				%s
+			</table>
+			<p>JSON: <a href="/api/scoreboard" class="api-endpoint">/api/scoreboard</a>, <a href="/api/scoreboard?view=teams" class="api-endpoint">/api/scoreboard?view=teams</a></p>
+		</div>
+	`,
+		eventStatusText[board.Status],
+		schedule,
+		points,
+		html.EscapeString(progress.Name(learner)),
+		maxNameLength,
+		message,
+		rows,
+	))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
+
+// Таблица результатов в JSON
+func apiScoreboard(w http.ResponseWriter, r *http.Request) {
+	board := currentScoreboard(r, time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(board)
+}
+
+// Таблица по учащимся или по командам (?view=teams)
+func currentScoreboard(r *http.Request, now time.Time) scoreboard {
+	learners := progress.Snapshot()
+	names := make(map[string]string, len(learners))
+	for id, p := range learners {
+		names[id] = displayName(id, p.Name)
+	}
+	if r.URL.Query().Get("view") == "teams" {
+		return event.Scoreboard("teams", teamCompetitors(teams.Snapshot(), learners), names, catalog, now)
+	}
+	return event.Scoreboard("learners", learnerCompetitors(learners), names, catalog, now)
+}
+
+// Проверить имя для таблицы результатов
+func validName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return "", errInvalidName
+	}
+	for _, r := range name {
+		if !unicode.IsPrint(r) {
+			return "", errInvalidName
+		}
+	}
+	return name, nil
+}
+
+// Страница команды: создание, вступление по коду, выход и прогресс команды
+func teamPage(w http.ResponseWriter, r *http.Request) {
+	learner := learnerID(w, r)
+
+	message := ""
+	if r.Method == "POST" {
+		var err error
+		switch r.FormValue("action") {
+		case "create":
+			var name string
+			if name, err = validName(r.FormValue("name")); err == nil {
+				_, err = teams.Create(learner, name)
+			}
+		case "join":
+			_, err = teams.Join(learner, r.FormValue("code"))
+		case "leave":
+			err = teams.Leave(learner)
+		default:
+			err = fmt.Errorf("unknown action %q", r.FormValue("action"))
+		}
+		switch {
+		case err == nil:
+			http.Redirect(w, r, "/team", http.StatusSeeOther)
+			return
+		case errors.Is(err, errInvalidName):
+			message = "Название должно содержать от 1 до 32 печатных символов"
+		case errors.Is(err, errTeamNameTaken):
+			message = "Команда с таким названием уже есть"
+		case errors.Is(err, errBadJoinCode):
+			message = "Неверный код приглашения"
+		case errors.Is(err, errAlreadyInTeam):
+			message = "Вы уже состоите в команде"
+		case errors.Is(err, errNotInTeam):
+			message = "Вы не состоите в команде"
+		default:
+			log.Printf("team %s: %v", r.FormValue("action"), err)
+			message = "Не удалось выполнить действие"
+		}
+		message = `<div class="response error">❌ ` + message + `</div>`
+	}
+
+	t, ok := teams.TeamOf(learner)
+	if !ok {
+		page := renderPage("Команда", `
+		<div class="card">
+			<h2>Создать команду</h2>
+			<form method="POST" action="/team">
+				<input type="hidden" name="action" value="create">
+				<div class="form-group">
+					<label>Название команды</label>
+					<input type="text" name="name" maxlength="32" required>
+				</div>
+				<button type="submit" class="btn">Создать</button>
+			</form>
+		</div>
+
+		<div class="card">
+			<h2>Вступить в команду</h2>
+			<form method="POST" action="/team">
+				<input type="hidden" name="action" value="join">
+				<div class="form-group">
+					<label>Код приглашения</label>
+					<input type="text" name="code" maxlength="8" required>
+				</div>
+				<button type="submit" class="btn">Вступить</button>
+			</form>
+		</div>
+		`+message)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+		return
+	}
+
+	learners := progress.Snapshot()
+	solves := t.Solves(learners)
+
+	members := ""
+	for _, id := range t.Members {
+		own := 0
+		for _, s := range solves {
+			if s.By == id {
+				own++
+			}
+		}
+		members += fmt.Sprintf("<li>%s - решено первым в команде: %d</li>", html.EscapeString(displayName(id, learners[id].Name)), own)
+	}
+
+	// Решенные командой задания в порядке решения
+	keys := make([]string, 0, len(solves))
+	for key := range solves {
+		if _, ok := catalog[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return solves[keys[i]].At.Before(solves[keys[j]].At) })
+	rows := ""
+	for _, key := range keys {
+		s := solves[key]
+		category, vulnID, _ := strings.Cut(key, "_")
+		rows += fmt.Sprintf(`<tr><td><a href="/challenge/%s/%s">%s</a></td><td>%s</td><td>%s</td><td>%s</td></tr>`,
+			category, vulnID, key, html.EscapeString(catalog[key].Title),
+			html.EscapeString(displayName(s.By, learners[s.By].Name)), s.At.Local().Format("02.01 15:04:05"))
+	}
+	if rows == "" {
+		rows = `<tr><td colspan="4">Команда пока не решила ни одного задания</td></tr>`
+	}
+
+	page := renderPage("Команда: "+html.EscapeString(t.Name), fmt.Sprintf(`
+		<div class="card">
+			<h2>%s</h2>
+			<p><strong>Код приглашения:</strong> <code>%s</code> - передайте его участникам команды</p>
+			<p><strong>Решено заданий:</strong> %d из %d</p>
+			<ul>%s</ul>
+			<form method="POST" action="/team">
+				<input type="hidden" name="action" value="leave">
+				<button type="submit" class="btn btn-danger">Выйти из команды</button>
+			</form>
+			%s
+		</div>
+
+		<div class="card">
+			<h2>Прогресс команды</h2>
+			<table style="width: 100%%; border-collapse: collapse;">
+				<tr style="text-align: left;"><th>Задание</th><th>Название</th><th>Кто решил</th><th>Время</th></tr>
+				%s
+			</table>
+		</div>
+	`,
+		html.EscapeString(t.Name),
+		t.JoinCode,
+		len(keys), len(catalog),
+		members,
+		message,
+		rows,
+	))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
@@ -0,0 +1,49 @@
+package endpoints
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// Общее для исправленных обработчиков (aXX_secure.go): текущий пользователь,
+// ключи сервера и журнал аудита
+
+// Текущий пользователь стенда. Исправленные обработчики проверяют права по нему,
+// а не по параметрам и заголовкам запроса
+var labUser = struct {
+	ID    string
+	Email string
+	Role  string
+}{ID: "1", Email: "john.doe@company.com", Role: "user"}
+
+// Ключ сервера для конкретной цели (подписи, шифрование). Выводится из секрета флагов,
+// поэтому не хранится отдельно и не совпадает между установками
+func serverKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, flagSecret)
+	mac.Write([]byte("key:" + purpose))
+	return mac.Sum(nil)
+}
+
+// Ключ подписи поставщика обновлений и пакетов: у сервера есть только открытый ключ для проверки
+func vendorPublicKey() ed25519.PublicKey {
+	return ed25519.NewKeyFromSeed(serverKey("vendor")).Public().(ed25519.PublicKey)
+}
+
+// Запрос пришел со страницы того же сайта
+func sameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host == r.Host
+}
+
+// IP клиента без порта. X-Forwarded-For не учитываем: его задает сам клиент
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
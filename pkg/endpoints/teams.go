@@ -0,0 +1,255 @@
+package endpoints
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Команды: учащийся создает команду и получает код приглашения, остальные вступают по коду.
+// Решения участников общие для команды, но каждое решение по-прежнему записано на того,
+// кто его сделал (прогресс хранится по учащимся, см. progress.go)
+
+// Символы кода приглашения (без похожих друг на друга 0/O и 1/I)
+const joinCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const joinCodeLength = 8
+
+var (
+	errAlreadyInTeam = errors.New("learner is already in a team")
+	errNotInTeam     = errors.New("learner is not in a team")
+	errTeamNameTaken = errors.New("team name is already taken")
+	errBadJoinCode   = errors.New("unknown join code")
+)
+
+// Команда
+type team struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	JoinCode string    `json:"join_code"`
+	Members  []string  `json:"members"` // ID учащихся в порядке вступления
+	Created  time.Time `json:"created"`
+}
+
+type teamStore struct {
+	mu    sync.RWMutex
+	path  string
+	teams map[string]*team
+}
+
+var teams = newTeamStore()
+
+func newTeamStore() *teamStore {
+	return &teamStore{teams: make(map[string]*team)}
+}
+
+// Загрузить команды из файла; отсутствующий файл не считается ошибкой
+func (s *teamStore) Load(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read teams: %w", err)
+	}
+
+	loaded := make(map[string]*team)
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("parse teams %s: %w", path, err)
+	}
+	s.teams = loaded
+	return nil
+}
+
+// Создать команду; создатель становится ее первым участником
+func (s *teamStore) Create(learnerID, name string) (team, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.teamOf(learnerID) != nil {
+		return team{}, errAlreadyInTeam
+	}
+	for _, t := range s.teams {
+		if strings.EqualFold(t.Name, name) {
+			return team{}, errTeamNameTaken
+		}
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return team{}, err
+	}
+	code, err := s.newJoinCode()
+	if err != nil {
+		return team{}, err
+	}
+	t := &team{ID: id, Name: name, JoinCode: code, Members: []string{learnerID}, Created: time.Now().UTC()}
+	s.teams[id] = t
+	return t.copy(), s.save()
+}
+
+// Вступить в команду по коду приглашения
+func (s *teamStore) Join(learnerID, code string) (team, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.teamOf(learnerID) != nil {
+		return team{}, errAlreadyInTeam
+	}
+	code = strings.ToUpper(strings.TrimSpace(code))
+	for _, t := range s.teams {
+		if t.JoinCode == code {
+			t.Members = append(t.Members, learnerID)
+			return t.copy(), s.save()
+		}
+	}
+	return team{}, errBadJoinCode
+}
+
+// Выйти из команды; команда без участников удаляется
+func (s *teamStore) Leave(learnerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.teamOf(learnerID)
+	if t == nil {
+		return errNotInTeam
+	}
+	members := t.Members[:0]
+	for _, id := range t.Members {
+		if id != learnerID {
+			members = append(members, id)
+		}
+	}
+	t.Members = members
+	if len(t.Members) == 0 {
+		delete(s.teams, t.ID)
+	}
+	return s.save()
+}
+
+// Команда учащегося
+func (s *teamStore) TeamOf(learnerID string) (team, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if t := s.teamOf(learnerID); t != nil {
+		return t.copy(), true
+	}
+	return team{}, false
+}
+
+// Копия всех команд, отсортированная по имени
+func (s *teamStore) Snapshot() []team {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]team, 0, len(s.teams))
+	for _, t := range s.teams {
+		list = append(list, t.copy())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Вызывается под блокировкой
+func (s *teamStore) teamOf(learnerID string) *team {
+	for _, t := range s.teams {
+		for _, id := range t.Members {
+			if id == learnerID {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// Вызывается под блокировкой
+func (s *teamStore) newJoinCode() (string, error) {
+	buf := make([]byte, joinCodeLength)
+	for {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("generate join code: %w", err)
+		}
+		code := make([]byte, joinCodeLength)
+		for i, b := range buf {
+			code[i] = joinCodeAlphabet[int(b)%len(joinCodeAlphabet)]
+		}
+		unique := true
+		for _, t := range s.teams {
+			unique = unique && t.JoinCode != string(code)
+		}
+		if unique {
+			return string(code), nil
+		}
+	}
+}
+
+// Сохранить команды на диск (вызывается под блокировкой)
+func (s *teamStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := writeJSONFile(s.path, s.teams); err != nil {
+		return fmt.Errorf("save teams: %w", err)
+	}
+	return nil
+}
+
+func (t *team) copy() team {
+	c := *t
+	c.Members = append([]string(nil), t.Members...)
+	return c
+}
+
+// Решения команды: по каждому заданию - самое раннее решение среди участников
+func (t team) Solves(learners map[string]learnerProgress) map[string]solveRecord {
+	solves := make(map[string]solveRecord)
+	for _, id := range t.Members {
+		for key, at := range learners[id].Solved {
+			if first, ok := solves[key]; !ok || at.Before(first.At) {
+				solves[key] = solveRecord{At: at, By: id}
+			}
+		}
+	}
+	return solves
+}
+
+// Подсказки команды: подсказка открыта, если ее открыл любой участник,
+// временем открытия считается самое раннее
+func (t team) Hints(learners map[string]learnerProgress) map[string][]time.Time {
+	hints := make(map[string][]time.Time)
+	for _, id := range t.Members {
+		for key, times := range learners[id].Hints {
+			merged := hints[key]
+			for i, at := range times {
+				if i == len(merged) {
+					merged = append(merged, at)
+				} else if at.Before(merged[i]) {
+					merged[i] = at
+				}
+			}
+			hints[key] = merged
+		}
+	}
+	return hints
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
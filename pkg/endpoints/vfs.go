@@ -0,0 +1,157 @@
+package endpoints
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// Виртуальная файловая система сервера для эндпоинтов, читающих файлы. Дерево vfs/ встраивается
+// в бинарник и отображается в корень "/": веб-каталог приложения, конфигурация, бэкапы, /etc.
+// Реальная ФС хоста этим эндпоинтам недоступна, поэтому path traversal можно эксплуатировать по-настоящему.
+//
+// В файлах можно писать {{flag:<ключ задания>}} - при чтении подставляется флаг учащегося.
+
+//go:embed all:vfs
+var vfsEmbed embed.FS
+
+var serverFS = func() fs.FS {
+	sub, err := fs.Sub(vfsEmbed, "vfs")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}()
+
+// Каталоги эндпоинтов в виртуальной ФС
+const (
+	webFilesDir   = "/var/www/app/public/files"
+	webBackupsDir = "/var/www/app/public/backups"
+	uploadsDir    = "/var/www/app/uploads"
+	appDataDir    = "/var/www/app/data"
+)
+
+// Пути, которые процесс приложения (www-data) прочитать не может
+var vfsRestricted = []string{"/etc/shadow", "/root"}
+
+var vfsFlagRe = regexp.MustCompile(`\{\{flag:(a\d{2}_\d+)\}\}`)
+
+// Путь в виртуальной ФС -> имя для fs.FS
+func vfsName(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// Прочитать файл по абсолютному пути. Ошибки - как у os.ReadFile: *fs.PathError с полным путем и errno
+func readServerFile(w http.ResponseWriter, r *http.Request, name string) ([]byte, error) {
+	name = path.Clean("/" + name)
+	for _, restricted := range vfsRestricted {
+		if name == restricted || strings.HasPrefix(name, restricted+"/") {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EACCES}
+		}
+	}
+	info, err := fs.Stat(serverFS, vfsName(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.ENOENT}
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: syscall.EISDIR}
+	}
+	data, err := fs.ReadFile(serverFS, vfsName(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return vfsFlagRe.ReplaceAllFunc(data, func(m []byte) []byte {
+		return []byte(labFlag(w, r, string(vfsFlagRe.FindSubmatch(m)[1])))
+	}), nil
+}
+
+// Имена в каталоге виртуальной ФС (подкаталоги с / на конце)
+func listServerDir(name string) ([]string, error) {
+	entries, err := fs.ReadDir(serverFS, vfsName(name))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name()+"/")
+		} else {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Код ответа для ошибки чтения файла
+func fileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return http.StatusForbidden
+	case errors.Is(err, fs.ErrNotExist):
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// Разрешение путей с типичными ошибками. Каждая функция возвращает абсолютный путь
+// в виртуальной ФС или false, если проверка отклонила имя
+
+// УЯЗВИМОСТЬ: ".." запрещено, но абсолютный путь заменяет каталог целиком (как os.path.join в Python)
+func resolveAbsoluteJoin(base, name string) (string, bool) {
+	if strings.Contains(name, "..") {
+		return "", false
+	}
+	if strings.HasPrefix(name, "/") {
+		return path.Clean(name), true
+	}
+	return path.Join(base, name), true
+}
+
+// УЯЗВИМОСТЬ: "../" вырезается один раз, без повтора: из "....//" снова получается "../"
+func resolveStripOnce(base, name string) (string, bool) {
+	name = strings.ReplaceAll(name, "../", "")
+	name = strings.ReplaceAll(name, `..\`, "")
+	return path.Join(base, strings.TrimPrefix(name, "/")), true
+}
+
+// УЯЗВИМОСТЬ: проверяется только префикс строки: /backups-archive тоже начинается с /backups
+func resolvePrefixCheck(base, name string) (string, bool) {
+	full := path.Join(base, name)
+	if !strings.HasPrefix(full, base) {
+		return "", false
+	}
+	return full, true
+}
+
+// УЯЗВИМОСТЬ: ".." ищется до декодирования, а имя декодируется повторно: %252e%252e%252f проходит
+func resolveDecodeAfterCheck(base, name string) (string, bool) {
+	if strings.Contains(name, "..") {
+		return "", false
+	}
+	decoded, err := url.PathUnescape(name)
+	if err != nil {
+		return "", false
+	}
+	return path.Join(base, decoded), true
+}
+
+// ИСПРАВЛЕНИЕ: имя - локальный путь внутри каталога (fs.ValidPath: без "..", без "/" в начале,
+// без пустых элементов), повторно не декодируется
+func resolveSafe(base, name string) (string, bool) {
+	if !fs.ValidPath(name) || name == "." || strings.ContainsAny(name, `\%`) {
+		return "", false
+	}
+	return path.Join(base, name), true
+}
@@ -0,0 +1,5 @@
+-- Database backup 2024-06-30
+-- Contains user passwords, API keys, etc.
+INSERT INTO users VALUES (1, 'admin', 'password123', 'admin@company.com');
+INSERT INTO users VALUES (2, 'john', 'qwerty', 'john@company.com');
+INSERT INTO secrets VALUES ('lab_flag', '{{flag:a02_8}}');
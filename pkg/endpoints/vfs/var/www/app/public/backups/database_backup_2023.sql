@@ -0,0 +1,3 @@
+-- Database backup 2023-12-31
+-- NOTE: backups are rotated; newer dumps are moved to ../backups-archive/
+INSERT INTO users VALUES (1, 'admin', '$2y$10$oldhasholdhasholdhashold', 'admin@company.com');
@@ -0,0 +1,49 @@
+package endpoints
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Словари, которые стенд раздает учащимся для офлайн-перебора (секреты JWT, пароли).
+// Сервер берет слабые секреты из тех же файлов, поэтому перебор по словарю всегда успешен
+
+//go:embed wordlists
+var wordlistsFS embed.FS
+
+// Слова из словаря без пустых строк
+func wordlist(name string) []string {
+	data, err := wordlistsFS.ReadFile(path.Join("wordlists", name))
+	if err != nil {
+		panic(err)
+	}
+	var words []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			words = append(words, line)
+		}
+	}
+	return words
+}
+
+// Есть ли слово в словаре
+func inWordlist(name, word string) bool {
+	for _, w := range wordlist(name) {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+// Раздача словарей: /api/lab/wordlists/<имя>
+func apiLabWordlists() http.Handler {
+	sub, err := fs.Sub(wordlistsFS, "wordlists")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/api/lab/wordlists/", http.FileServer(http.FS(sub)))
+}
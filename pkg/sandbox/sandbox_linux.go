@@ -0,0 +1,321 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// argv[0] процесса, который готовит песочницу: [initArg0, корень, рабочий каталог, имя хоста, команда...]
+const initArg0 = "vulnweb-sandbox-init"
+
+// Каталоги хоста, которые видны в песочнице только для чтения (если есть на хосте).
+// Символические ссылки (например, /bin -> usr/bin) воссоздаются как есть
+var hostDirs = []string{"bin", "sbin", "lib", "lib32", "lib64", "libx32", "usr", "etc/alternatives"}
+
+// Отдельные файлы хоста, нужные загрузчику и программам
+var hostFiles = []string{"etc/ld.so.cache"}
+
+// Устройства, доступные в песочнице
+var devices = []string{"null", "zero", "random", "urandom"}
+
+// Ограничения ресурсов процесса команды
+var rlimits = []struct {
+	resource int
+	value    uint64
+}{
+	{syscall.RLIMIT_CPU, 5},         // Секунд процессорного времени
+	{syscall.RLIMIT_AS, 256 << 20},  // Адресное пространство
+	{syscall.RLIMIT_FSIZE, 1 << 20}, // Размер создаваемого файла
+	{syscall.RLIMIT_NOFILE, 64},     // Открытых файлов
+	{syscall.RLIMIT_CORE, 0},        // Без дампов памяти
+	{rlimitNProc, 64},               // Процессов (защита от fork-бомбы)
+}
+
+const rlimitNProc = 6 // RLIMIT_NPROC, в syscall не объявлена
+
+// Выполнить команду argv (поиск по PATH внутри песочницы)
+func Run(ctx context.Context, cfg Config, argv ...string) (Result, error) {
+	if len(argv) == 0 {
+		return Result{}, errors.New("sandbox: empty command")
+	}
+	cfg = cfg.withDefaults()
+	if err := acquire(ctx); err != nil {
+		return Result{}, err
+	}
+	defer release()
+
+	root, err := os.MkdirTemp("", "vulnweb-sandbox-")
+	if err != nil {
+		return Result{}, fmt.Errorf("sandbox: %w", err)
+	}
+	defer os.RemoveAll(root)
+	if err := prepareRoot(root, cfg); err != nil {
+		return Result{}, fmt.Errorf("sandbox: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	out := &cappedBuffer{limit: cfg.MaxOutput}
+	cmd := exec.CommandContext(ctx, "/proc/self/exe")
+	cmd.Args = append([]string{initArg0, root, cfg.Dir, cfg.Hostname}, argv...)
+	cmd.Env = cfg.Env
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.WaitDelay = time.Second
+	uid, gid := os.Getuid(), os.Getgid()
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNET | syscall.CLONE_NEWUTS | syscall.CLONE_NEWIPC,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: uid, Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: gid, Size: 1}},
+		GidMappingsEnableSetgroups: false,
+		Pdeathsig:                  syscall.SIGKILL,
+	}
+
+	if err := cmd.Start(); err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	err = cmd.Wait()
+	res := Result{Output: out.buf.Bytes(), Truncated: out.truncated, ExitCode: cmd.ProcessState.ExitCode()}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		res.TimedOut = true
+		return res, nil
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return res, fmt.Errorf("sandbox: %w", err)
+	}
+	// Процесс подготовки сообщает о своей ошибке кодом initFailed
+	if res.ExitCode == initFailed {
+		return res, fmt.Errorf("%w: %s", ErrUnavailable, strings.TrimSpace(string(res.Output)))
+	}
+	return res, nil
+}
+
+// Код выхода процесса подготовки, если песочницу создать не удалось
+const initFailed = 125
+
+// Подготовить каталог корневой ФС на хосте: файлы Config и точки монтирования
+func prepareRoot(root string, cfg Config) error {
+	for _, dir := range hostDirs {
+		info, err := os.Lstat("/" + dir)
+		if err != nil {
+			continue
+		}
+		target := filepath.Join(root, dir)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink("/" + dir)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			return err
+		}
+	}
+	for _, file := range hostFiles {
+		if err := touch(root, file); err != nil {
+			return err
+		}
+	}
+	for _, dev := range devices {
+		if err := touch(root, "dev/"+dev); err != nil {
+			return err
+		}
+	}
+	for _, dir := range []string{"proc", "tmp", ".oldroot"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			return err
+		}
+	}
+	for name, content := range cfg.Files {
+		target := filepath.Join(root, filepath.Clean("/"+name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		mode := os.FileMode(0o644)
+		if strings.HasPrefix(content, "#!") {
+			mode = 0o755
+		}
+		if err := os.WriteFile(target, []byte(content), mode); err != nil {
+			return err
+		}
+	}
+	return os.MkdirAll(filepath.Join(root, filepath.Clean("/"+cfg.Dir)), 0o755)
+}
+
+func touch(root, name string) error {
+	target := filepath.Join(root, name)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, nil, 0o644)
+}
+
+// Точка входа процесса песочницы. В обычном запуске сервера сразу возвращает управление
+func Init() {
+	if len(os.Args) < 5 || os.Args[0] != initArg0 {
+		return
+	}
+	root, dir, hostname, argv := os.Args[1], os.Args[2], os.Args[3], os.Args[4:]
+	if err := enter(root, dir, hostname); err != nil {
+		fmt.Fprintln(os.Stderr, "sandbox:", err)
+		os.Exit(initFailed)
+	}
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sh:", err)
+		os.Exit(127)
+	}
+	// Ограничения - в последний момент: под ними не работал бы сам Go runtime
+	if err := restrict(); err != nil {
+		fmt.Fprintln(os.Stderr, "sandbox:", err)
+		os.Exit(initFailed)
+	}
+	err = syscall.Exec(path, argv, os.Environ())
+	fmt.Fprintln(os.Stderr, "sandbox:", err)
+	os.Exit(126)
+}
+
+// Смонтировать корневую ФС и сменить корень
+func enter(root, dir, hostname string) error {
+	// Изменения монтирования не должны уходить в пространство имен хоста
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+	if err := syscall.Mount(root, root, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind root: %w", err)
+	}
+	for _, dir := range hostDirs {
+		if info, err := os.Lstat("/" + dir); err == nil && info.IsDir() {
+			if err := bindReadOnly("/"+dir, filepath.Join(root, dir)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, file := range hostFiles {
+		if _, err := os.Stat("/" + file); err == nil {
+			if err := bindReadOnly("/"+file, filepath.Join(root, file)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, dev := range devices {
+		if err := syscall.Mount("/dev/"+dev, filepath.Join(root, "dev", dev), "", syscall.MS_BIND, ""); err != nil {
+			return fmt.Errorf("bind /dev/%s: %w", dev, err)
+		}
+	}
+	// /proc нового пространства PID: видны только процессы песочницы.
+	// В некоторых контейнерах монтировать proc нельзя - тогда песочница работает без него
+	syscall.Mount("proc", filepath.Join(root, "proc"), "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "")
+	if err := syscall.Mount("tmpfs", filepath.Join(root, "tmp"), "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "size=16m,mode=1777"); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+	if err := syscall.Sethostname([]byte(hostname)); err != nil {
+		return fmt.Errorf("set hostname: %w", err)
+	}
+
+	oldRoot := filepath.Join(root, ".oldroot")
+	if err := syscall.PivotRoot(root, oldRoot); err != nil {
+		return fmt.Errorf("pivot root: %w", err)
+	}
+	if err := syscall.Chdir("/"); err != nil {
+		return err
+	}
+	if err := syscall.Unmount("/.oldroot", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach old root: %w", err)
+	}
+	os.Remove("/.oldroot")
+	// Корень тоже только для чтения: писать можно только в /tmp (ограничен по размеру)
+	if err := remountReadOnly("/"); err != nil {
+		return err
+	}
+	return syscall.Chdir(dir)
+}
+
+// Ограничить ресурсы и сбросить привилегии перед запуском команды
+func restrict() error {
+	for _, l := range rlimits {
+		if err := syscall.Setrlimit(l.resource, &syscall.Rlimit{Cur: l.value, Max: l.value}); err != nil {
+			return fmt.Errorf("set rlimit %d: %w", l.resource, err)
+		}
+	}
+	return dropPrivileges()
+}
+
+// Привязать путь хоста только для чтения
+func bindReadOnly(source, target string) error {
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %w", source, err)
+	}
+	return remountReadOnly(target)
+}
+
+// Перемонтировать привязку только для чтения. Флаги исходного монтирования (nodev, noexec и т.п.)
+// в пространстве имен пользователя снять нельзя, поэтому они сохраняются
+func remountReadOnly(target string) error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(target, &st); err != nil {
+		return fmt.Errorf("statfs %s: %w", target, err)
+	}
+	flags := uintptr(syscall.MS_REMOUNT | syscall.MS_BIND | syscall.MS_RDONLY | syscall.MS_NOSUID)
+	for stFlag, msFlag := range map[uint64]uintptr{
+		stNoDev:      syscall.MS_NODEV,
+		stNoExec:     syscall.MS_NOEXEC,
+		stNoAtime:    syscall.MS_NOATIME,
+		stNoDirAtime: syscall.MS_NODIRATIME,
+		stRelatime:   syscall.MS_RELATIME,
+	} {
+		if uint64(st.Flags)&stFlag != 0 {
+			flags |= msFlag
+		}
+	}
+	if err := syscall.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("remount %s read-only: %w", target, err)
+	}
+	return nil
+}
+
+// Флаги statfs (ST_*), которых нет в syscall
+const (
+	stNoDev      = 4
+	stNoExec     = 8
+	stNoAtime    = 1024
+	stNoDirAtime = 2048
+	stRelatime   = 4096
+)
+
+// Сбросить все возможности (capabilities): root внутри песочницы не сможет
+// перемонтировать ФС или выйти из нее. no_new_privs запрещает повышение через setuid
+func dropPrivileges() error {
+	const (
+		prSetNoNewPrivs = 38
+		prCapBSetDrop   = 24
+	)
+	for c := uintptr(0); c < 64; c++ {
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prCapBSetDrop, c, 0); errno == syscall.EINVAL {
+			break
+		} else if errno != 0 {
+			return fmt.Errorf("drop capability %d: %w", c, errno)
+		}
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return fmt.Errorf("set no_new_privs: %w", errno)
+	}
+	return nil
+}
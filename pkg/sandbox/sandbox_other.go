@@ -0,0 +1,12 @@
+//go:build !linux
+
+package sandbox
+
+import "context"
+
+// Песочница использует пространства имен Linux; на других ОС команды не выполняются
+func Run(ctx context.Context, cfg Config, argv ...string) (Result, error) {
+	return Result{}, ErrUnavailable
+}
+
+func Init() {}
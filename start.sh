@@ -1,19 +1,28 @@
 #!/bin/bash
 # Скрипт для запуска сервера
+#
+# Аргументы передаются серверу, например: ./start.sh -addr localhost:8080 -data-dir data2
+# Для нескольких экземпляров задайте каждому свой PID-файл:
+#   VULNWEB_PID_FILE=lab2.pid ./start.sh -addr localhost:8080 -data-dir data2
 
-echo "Запуск сервера..."
+cd "$(dirname "$0")" || exit 1
 
-# Проверить, не занят ли порт
-if lsof -ti:9999 >/dev/null 2>&1; then
-    echo "⚠️  Порт 9999 уже занят!"
+PID_FILE="${VULNWEB_PID_FILE:-vulnweb.pid}"
+
+# Проверить, не запущен ли уже этот экземпляр
+if [ -f "$PID_FILE" ] && kill -0 "$(cat "$PID_FILE")" 2>/dev/null; then
+    echo "⚠️  Сервер уже запущен (PID: $(cat "$PID_FILE"))"
     echo "Остановите текущий сервер: ./stop.sh"
     exit 1
 fi
 
-cd "$(dirname "$0")/cmd" || exit 1
+echo "Сборка сервера..."
+go build -o bin/vulnweb ./cmd || exit 1
 
-echo "Сервер запускается на http://localhost:9999"
+echo "Запуск сервера (адрес и другие настройки - в README)"
 echo "Для остановки нажмите Ctrl+C или выполните: ./stop.sh"
 echo ""
 
-go run main.go
\ No newline at end of file
+# exec сохраняет PID скрипта, поэтому в PID-файле окажется PID сервера
+echo $$ > "$PID_FILE"
+exec bin/vulnweb "$@"
@@ -1,28 +1,36 @@
 #!/bin/bash
 # Скрипт для остановки сервера
+#
+# Останавливает экземпляр из PID-файла (по умолчанию vulnweb.pid, см. start.sh)
 
-echo "Остановка сервера..."
+cd "$(dirname "$0")" || exit 1
 
-# Найти и остановить процессы Go
-pkill -f "go run main.go" 2>/dev/null
-pkill -f "/tmp/go-build.*/exe/main" 2>/dev/null
+PID_FILE="${VULNWEB_PID_FILE:-vulnweb.pid}"
 
-# Найти процесс на порту 9999 и остановить его
-PORT_PID=$(lsof -ti:9999 2>/dev/null)
-if [ ! -z "$PORT_PID" ]; then
-    echo "Остановка процесса на порту 9999 (PID: $PORT_PID)"
-    kill $PORT_PID 2>/dev/null
-    sleep 1
-    # Если процесс не остановился, принудительно
-    kill -9 $PORT_PID 2>/dev/null
+if [ ! -f "$PID_FILE" ]; then
+    echo "PID-файл $PID_FILE не найден, сервер не запущен"
+    exit 0
 fi
 
-sleep 1
-
-# Проверить, что порт свободен
-if lsof -ti:9999 >/dev/null 2>&1; then
-    echo "⚠️  Порт 9999 все еще занят. Попробуйте: kill -9 \$(lsof -ti:9999)"
-else
-    echo "✅ Сервер успешно остановлен"
+PID=$(cat "$PID_FILE")
+if ! kill -0 "$PID" 2>/dev/null; then
+    echo "Сервер (PID: $PID) уже остановлен"
+    rm -f "$PID_FILE"
+    exit 0
 fi
 
+echo "Остановка сервера (PID: $PID)..."
+# SIGTERM: сервер дорабатывает начатые запросы и завершается сам
+kill -TERM "$PID"
+for _ in $(seq 1 15); do
+    if ! kill -0 "$PID" 2>/dev/null; then
+        rm -f "$PID_FILE"
+        echo "✅ Сервер успешно остановлен"
+        exit 0
+    fi
+    sleep 1
+done
+
+echo "⚠️  Сервер не остановился за 15 секунд, принудительная остановка"
+kill -KILL "$PID" 2>/dev/null
+rm -f "$PID_FILE"